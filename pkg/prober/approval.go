@@ -0,0 +1,69 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// proposal encodes the desired annotations as the value of the proposed
+// annotation. json.Marshal sorts the keys, so equal proposals compare equal.
+func proposal(desired map[string]string) string {
+	b, _ := json.Marshal(desired)
+	return string(b)
+}
+
+// approved reports whether an operator approved exactly the desired change.
+func (r *Runner) approved(annotations, desired map[string]string) bool {
+	return annotations[r.proberKey(approveAnnotationName)] == "true" &&
+		annotations[r.proberKey(proposedAnnotationName)] == proposal(desired)
+}
+
+// approvalKeys returns the approval annotations present on an object.
+func (r *Runner) approvalKeys(annotations map[string]string) []string {
+	var keys []string
+	for _, k := range []string{r.proberKey(proposedAnnotationName), r.proberKey(approveAnnotationName)} {
+		if _, ok := annotations[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// planProposal writes the desired annotations to the proposed annotation
+// instead of applying them. A pending approval is dropped when the proposal
+// changes, since it was given for a different value. It reports false when
+// the proposal is already in place.
+func (r *Runner) planProposal(ctx context.Context, obj client.Object, desired map[string]string) (targetUpdate, bool) {
+	annotations := obj.GetAnnotations()
+	proposedKey, approveKey := r.proberKey(proposedAnnotationName), r.proberKey(approveAnnotationName)
+	value := proposal(desired)
+	if annotations[proposedKey] == value {
+		return targetUpdate{}, false
+	}
+
+	// keep the current values in the set of applied keys, so server-side
+	// apply doesn't drop them while the change awaits approval
+	proposed := map[string]string{proposedKey: value}
+	for k := range desired {
+		if v, ok := annotations[k]; ok {
+			proposed[k] = v
+		}
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	before := maps.Clone(annotations)
+	annotations[proposedKey] = value
+	var stale []string
+	if _, ok := annotations[approveKey]; ok {
+		delete(annotations, approveKey)
+		stale = append(stale, approveKey)
+	}
+	if r.requireCurrentValue != "" || r.patchStrategy == PatchStrategyApply {
+		annotations[r.proberKey(managedAnnotationName)] = "true"
+	}
+	log.FromContext(ctx).Info("proposed annotation change; awaiting approval", "object", client.ObjectKeyFromObject(obj).String(), "proposed", value)
+	return targetUpdate{obj: obj, patch: patch, desired: proposed, stale: stale, changes: annotationChanges(before, annotations)}, true
+}
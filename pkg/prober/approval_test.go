@@ -0,0 +1,125 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_RequireApproval(t *testing.T) {
+	var up2 atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" && !up2.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	const (
+		key         = "new.example.com/target"
+		proposedKey = "ingress-target-prober/proposed"
+		approveKey  = "ingress-target-prober/approve"
+	)
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:          k8s,
+		AnnotationKey:   key,
+		IPs:             []string{"10.0.0.1", "10.0.0.2"},
+		HTTPClient:      newRoutedHTTPClient(server),
+		RequireApproval: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	get := func() *networkingv1.Ingress {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	annotate := func(annotations map[string]string) {
+		t.Helper()
+		ing := get()
+		patch := client.MergeFrom(ing.DeepCopy())
+		for k, v := range annotations {
+			ing.Annotations[k] = v
+		}
+		if err := k8s.Patch(context.Background(), ing, patch); err != nil {
+			t.Fatalf("failed to annotate Ingress: %v", err)
+		}
+	}
+
+	// the change is proposed, not applied
+	tick()
+	ing := get()
+	if _, ok := ing.Annotations[key]; ok {
+		t.Fatalf("Expected the target annotation to wait for approval, got %v", ing.Annotations)
+	}
+	const first = `{"new.example.com/target":"10.0.0.1"}`
+	if got := ing.Annotations[proposedKey]; got != first {
+		t.Fatalf("Expected proposal %s, got %q", first, got)
+	}
+
+	// unrelated values do not approve
+	annotate(map[string]string{approveKey: "yes"})
+	tick()
+	if _, ok := get().Annotations[key]; ok {
+		t.Fatal("Expected only \"true\" to approve the proposal")
+	}
+
+	// approval promotes the proposal and clears the approval annotations
+	annotate(map[string]string{approveKey: "true"})
+	tick()
+	ing = get()
+	if got := ing.Annotations[key]; got != "10.0.0.1" {
+		t.Errorf("Expected the approved value to be applied, got %q", got)
+	}
+	if _, ok := ing.Annotations[proposedKey]; ok {
+		t.Errorf("Expected the proposal to be cleared after promotion, got %v", ing.Annotations)
+	}
+	if _, ok := ing.Annotations[approveKey]; ok {
+		t.Errorf("Expected the approval to be cleared after promotion, got %v", ing.Annotations)
+	}
+
+	// an approval given for an outdated proposal is dropped
+	up2.Store(true)
+	annotate(map[string]string{proposedKey: first, approveKey: "true"})
+	tick()
+	ing = get()
+	if got := ing.Annotations[key]; got != "10.0.0.1" {
+		t.Errorf("Expected the applied value to stay until the new proposal is approved, got %q", got)
+	}
+	const second = `{"new.example.com/target":"10.0.0.1,10.0.0.2"}`
+	if got := ing.Annotations[proposedKey]; got != second {
+		t.Errorf("Expected proposal %s, got %q", second, got)
+	}
+	if _, ok := ing.Annotations[approveKey]; ok {
+		t.Errorf("Expected the outdated approval to be dropped, got %v", ing.Annotations)
+	}
+
+	annotate(map[string]string{approveKey: "true"})
+	tick()
+	if got := get().Annotations[key]; got != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected the second proposal to be applied once approved, got %q", got)
+	}
+}
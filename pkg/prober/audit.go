@@ -0,0 +1,128 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AuditRecord is one line of the audit log: a single annotation change made
+// by a successful patch. Removed keys have an empty New and Removed set.
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Actor is the field manager the patch was sent as.
+	Actor     string `json:"actor"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+	Removed   bool   `json:"removed,omitempty"`
+}
+
+// annotationChange is a change planned for one annotation key.
+type annotationChange struct {
+	key, old, new string
+	removed       bool
+}
+
+// annotationChanges lists the keys whose value differs between before and
+// after, sorted by key.
+func annotationChanges(before, after map[string]string) []annotationChange {
+	var changes []annotationChange
+	for k, v := range after {
+		if old, ok := before[k]; !ok || old != v {
+			changes = append(changes, annotationChange{key: k, old: old, new: v})
+		}
+	}
+	for k, old := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, annotationChange{key: k, old: old, removed: true})
+		}
+	}
+	slices.SortFunc(changes, func(a, b annotationChange) int { return strings.Compare(a.key, b.key) })
+	return changes
+}
+
+// auditLog appends AuditRecords as JSON lines to a file. The file is opened
+// in append mode for every write, so it can be rotated by renaming it.
+type auditLog struct {
+	path string
+	now  func() time.Time
+	mu   sync.Mutex
+}
+
+// newAuditLog returns an audit log writing to path, checking that the file
+// can be opened. It returns nil when path is empty.
+func newAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	_ = f.Close()
+	return &auditLog{path: path, now: time.Now}, nil
+}
+
+// record appends one line per change of obj. It is a no-op on a nil log.
+func (a *auditLog) record(actor, resource string, obj types.NamespacedName, changes []annotationChange) error {
+	if a == nil || len(changes) == 0 {
+		return nil
+	}
+	now := a.now().UTC()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, c := range changes {
+		rec := AuditRecord{
+			Timestamp: now,
+			Actor:     actor,
+			Resource:  resource,
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Key:       c.key,
+			Old:       c.old,
+			New:       c.new,
+			Removed:   c.removed,
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	// a single write keeps an object's lines together
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditChanges records the changes of a successful patch, logging rather
+// than failing when the audit log can't be written.
+func (r *Runner) auditChanges(ctx context.Context, key types.NamespacedName, changes []annotationChange) {
+	resource := r.targetResource
+	if resource == "" {
+		resource = TargetResourceIngress
+	}
+	if err := r.audit.record(r.fieldManager, resource, key, changes); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write audit log", "object", key.String(), "path", r.audit.path)
+	}
+}
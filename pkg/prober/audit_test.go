@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	var records []AuditRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid audit line %q: %v", sc.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestRunner_Tick_AuditLog(t *testing.T) {
+	down := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); down[host] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{
+			"kubernetes.io/ingress.class": "public-nginx",
+			"new.example.com/target":      "10.0.0.9",
+		}),
+	).Build()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	audit.now = func() time.Time { return now }
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		requireCurrentValue:       "10.0.0.9",
+		fieldManager:              DefaultFieldManager,
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		audit:                     audit,
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tick()
+	// an unchanged tick adds nothing
+	tick()
+	down["10.0.0.2"] = true
+	now = now.Add(time.Minute)
+	tick()
+
+	record := func(at time.Time, key, old, new string) AuditRecord {
+		return AuditRecord{Timestamp: at, Actor: DefaultFieldManager, Resource: TargetResourceIngress, Namespace: "default", Name: "web", Key: key, Old: old, New: new}
+	}
+	start := now.Add(-time.Minute)
+	expected := []AuditRecord{
+		record(start, ManagedAnnotationKey, "", "true"),
+		record(start, "new.example.com/target", "10.0.0.9", "10.0.0.1,10.0.0.2"),
+		record(now, "new.example.com/target", "10.0.0.1,10.0.0.2", "10.0.0.1"),
+	}
+	if got := readAuditLog(t, path); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected audit records %+v, got %+v", expected, got)
+	}
+
+	// a rotated log is recreated on the next change
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	delete(down, "10.0.0.2")
+	tick()
+	if got := readAuditLog(t, path); len(got) != 1 || got[0].New != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected one record in the rotated log, got %+v", got)
+	}
+}
+
+func TestAnnotationChanges_Removed(t *testing.T) {
+	got := annotationChanges(
+		map[string]string{"a": "1", "b": "2", "c": "3"},
+		map[string]string{"a": "1", "c": "4"},
+	)
+	expected := []annotationChange{
+		{key: "b", old: "2", removed: true},
+		{key: "c", old: "3", new: "4"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestNewAuditLog_Unwritable(t *testing.T) {
+	if _, err := New(Options{IPs: []string{"10.0.0.1"}, AuditLogFile: filepath.Join(t.TempDir(), "missing", "audit.log")}); err == nil {
+		t.Error("Expected an error for an audit log in a missing directory")
+	}
+}
@@ -0,0 +1,33 @@
+package prober
+
+import "time"
+
+// nextInterval records the outcome of a tick and returns the delay until the
+// next one. Failures double the interval (with jitter) up to maxInterval; the
+// first success resets it to the base interval.
+func (r *Runner) nextInterval(tickErr error) time.Duration {
+	if tickErr == nil {
+		r.consecutiveFailures = 0
+		return r.interval
+	}
+	r.consecutiveFailures++
+	if r.maxInterval <= r.interval {
+		return r.interval
+	}
+
+	backoff := r.interval
+	for i := 0; i < r.consecutiveFailures && backoff < r.maxInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxInterval {
+		backoff = r.maxInterval
+	}
+
+	// equal jitter: keep half, randomize the other half, never drop below the base interval
+	half := int64(backoff / 2)
+	jittered := time.Duration(half + r.randInt63n(half+1))
+	if jittered < r.interval {
+		jittered = r.interval
+	}
+	return jittered
+}
@@ -0,0 +1,81 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner_NextInterval_BacksOffAndResets(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:         []string{"10.0.0.1"},
+		httpClient:  newRoutedHTTPClient(server),
+		urlScheme:   "http",
+		httpPath:    "/",
+		interval:    time.Second,
+		maxInterval: 10 * time.Second,
+		timeout:     time.Second,
+		// always pick the top of the jitter range so growth is deterministic
+		randInt63n: func(n int64) int64 { return n - 1 },
+	}
+
+	ctx := context.Background()
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, want := range expected {
+		got := runner.nextInterval(runner.tick(ctx))
+		if got != want {
+			t.Errorf("failing tick %d: expected interval %s, got %s", i+1, want, got)
+		}
+	}
+	if runner.consecutiveFailures != len(expected) {
+		t.Errorf("Expected %d consecutive failures, got %d", len(expected), runner.consecutiveFailures)
+	}
+
+	status.Store(http.StatusOK)
+	if got := runner.nextInterval(runner.tick(ctx)); got != time.Second {
+		t.Errorf("Expected interval to reset to %s after success, got %s", time.Second, got)
+	}
+	if runner.consecutiveFailures != 0 {
+		t.Errorf("Expected consecutive failures to reset, got %d", runner.consecutiveFailures)
+	}
+}
+
+func TestRunner_NextInterval_Jitter(t *testing.T) {
+	runner := &Runner{
+		interval:    time.Second,
+		maxInterval: time.Minute,
+		randInt63n:  func(n int64) int64 { return 0 },
+	}
+
+	// bottom of the jitter range is half the backoff, but never below the base interval
+	if got := runner.nextInterval(errNoHealthyIP); got != time.Second {
+		t.Errorf("Expected %s, got %s", time.Second, got)
+	}
+	if got := runner.nextInterval(errNoHealthyIP); got != 2*time.Second {
+		t.Errorf("Expected %s, got %s", 2*time.Second, got)
+	}
+}
+
+func TestRunner_NextInterval_Disabled(t *testing.T) {
+	runner := &Runner{
+		interval:    time.Second,
+		maxInterval: time.Second,
+		randInt63n:  func(n int64) int64 { return n - 1 },
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := runner.nextInterval(errNoHealthyIP); got != time.Second {
+			t.Errorf("Expected interval to stay at %s when backoff is disabled, got %s", time.Second, got)
+		}
+	}
+}
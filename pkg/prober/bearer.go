@@ -0,0 +1,66 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// bearerToken holds the token sent as "Authorization: Bearer" with HTTP
+// probes. It is re-read from its file at the start of every tick so a
+// rotated Secret takes effect without a restart. The token is never logged.
+// A nil *bearerToken sends no token.
+type bearerToken struct {
+	path  string
+	token atomic.Pointer[string]
+}
+
+// newBearerToken reads the token from path. It returns nil when path is empty.
+func newBearerToken(path string) (*bearerToken, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b := &bearerToken{path: path}
+	if err := b.reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// reload re-reads the token file; on error the previous token is kept.
+func (b *bearerToken) reload() error {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return fmt.Errorf("reading bearer token file: %w", err)
+	}
+	// secrets mounted from files commonly end in a newline
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return errors.New("bearer token file is empty")
+	}
+	b.token.Store(&token)
+	return nil
+}
+
+// get returns the current token, "" for a nil *bearerToken.
+func (b *bearerToken) get() string {
+	if b == nil {
+		return ""
+	}
+	return *b.token.Load()
+}
+
+// reloadBearerToken picks up a rotated token before a tick probes.
+func (r *Runner) reloadBearerToken(ctx context.Context) {
+	if r.bearerToken == nil {
+		return
+	}
+	if err := r.bearerToken.reload(); err != nil {
+		log.FromContext(ctx).Error(err, "failed to reload bearer token; keeping the current one", "file", r.bearerToken.path)
+	}
+}
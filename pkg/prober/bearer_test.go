@@ -0,0 +1,96 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestRunner_Tick_BearerTokenRotation(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken := func(token string) {
+		t.Helper()
+		if err := os.WriteFile(tokenFile, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeToken("first-token\n")
+
+	runner, err := New(Options{
+		IPs:                  []string{"10.0.0.1"},
+		ProbeBearerTokenFile: tokenFile,
+		HTTPClient:           newRoutedHTTPClient(server),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logs := &logCapture{}
+	ctx := log.IntoContext(context.Background(), logs.logger())
+	tick := func() string {
+		t.Helper()
+		if err := runner.tick(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return seen[len(seen)-1]
+	}
+
+	if got := tick(); got != "Bearer first-token" {
+		t.Errorf("Expected the initial token, got %q", got)
+	}
+	writeToken("second-token\n")
+	if got := tick(); got != "Bearer second-token" {
+		t.Errorf("Expected the rotated token on the next tick, got %q", got)
+	}
+	// a token file caught mid-rotation keeps the last good token
+	writeToken("")
+	if got := tick(); got != "Bearer second-token" {
+		t.Errorf("Expected the previous token to be kept, got %q", got)
+	}
+	if logs.contains("first-token") || logs.contains("second-token") {
+		t.Error("Expected the token to stay out of the logs")
+	}
+}
+
+func TestNewBearerToken(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newBearerToken(empty); err == nil {
+		t.Error("Expected an empty token file to be rejected")
+	}
+	if _, err := newBearerToken(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing token file to be rejected")
+	}
+	if b, err := newBearerToken(""); b != nil || err != nil {
+		t.Errorf("Expected no token without a file, got %v, %v", b, err)
+	}
+}
+
+func TestOptions_ValidateBearerToken(t *testing.T) {
+	opts := Options{IPs: []string{"10.0.0.1"}, ProbeBearerTokenFile: "token", ProbeBasicAuthUser: "prober"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected basic auth and a bearer token to be rejected together")
+	}
+}
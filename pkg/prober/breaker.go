@@ -0,0 +1,121 @@
+package prober
+
+import (
+	"sync"
+	"time"
+)
+
+// Patch breaker states, also exported as the patch_breaker_state gauge value.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+var breakerStateValues = map[string]float64{BreakerClosed: 0, BreakerOpen: 1, BreakerHalfOpen: 2}
+
+// patchBreaker stops patch attempts after threshold consecutive failed
+// patches. Once open it rejects patches for cooldown, then half-opens and
+// lets a single trial patch through: success closes it, failure reopens it.
+// A nil *patchBreaker always allows patches.
+type patchBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func newPatchBreaker(threshold int, cooldown time.Duration) *patchBreaker {
+	b := &patchBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+	b.setState(BreakerClosed)
+	return b
+}
+
+// setState records a state transition. Callers must hold b.mu.
+func (b *patchBreaker) setState(s string) {
+	b.state = s
+	patchBreakerState.Set(breakerStateValues[s])
+}
+
+// open reports whether patches are rejected outright, i.e. the breaker is
+// open and its cooldown has not yet passed.
+func (b *patchBreaker) open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == BreakerOpen && b.now().Sub(b.openedAt) < b.cooldown
+}
+
+// allow reports whether a patch may be sent now. After the cooldown the
+// breaker half-opens and admits one trial patch until its result is recorded.
+func (b *patchBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.setState(BreakerHalfOpen)
+	}
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// record feeds a patch result into the breaker and reports whether it opened.
+func (b *patchBreaker) record(err error) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return false
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+		return true
+	}
+	return false
+}
+
+// release ends a trial that produced no patch result, leaving the state as is.
+func (b *patchBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// State returns the current breaker state, or "" for a nil breaker.
+func (b *patchBreaker) State() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
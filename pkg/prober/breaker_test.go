@@ -0,0 +1,123 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestPatchBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newPatchBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	fail := errors.New("rejected")
+
+	if !b.allow() || b.record(fail) {
+		t.Fatal("Expected the breaker to stay closed after one failure")
+	}
+	if !b.allow() || !b.record(fail) {
+		t.Fatal("Expected the breaker to open at the threshold")
+	}
+	if b.State() != BreakerOpen || b.allow() {
+		t.Fatalf("Expected an open breaker to reject patches, state %q", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() || b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a trial patch after the cooldown, state %q", b.State())
+	}
+	if b.allow() {
+		t.Error("Expected only one trial patch while half-open")
+	}
+	if !b.record(fail) || b.State() != BreakerOpen {
+		t.Fatalf("Expected a failed trial to reopen the breaker, state %q", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("Expected a trial patch after the second cooldown")
+	}
+	b.record(nil)
+	if b.State() != BreakerClosed || !b.allow() || !b.allow() {
+		t.Errorf("Expected a successful trial to close the breaker, state %q", b.State())
+	}
+}
+
+func TestRunner_Tick_PatchBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	failing := true
+	var patches int
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			if failing {
+				return apierrors.NewServiceUnavailable("api server unavailable")
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	now := time.Unix(0, 0)
+	breaker := newPatchBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		breaker:                   breaker,
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tick()
+	tick()
+	if patches != 2 || runner.Status().PatchBreaker != BreakerOpen {
+		t.Fatalf("Expected the breaker to open after 2 failed patches, got %d patches and state %q", patches, runner.Status().PatchBreaker)
+	}
+	if got := testutil.ToFloat64(patchBreakerState); got != 1 {
+		t.Errorf("Expected patch_breaker_state 1, got %v", got)
+	}
+
+	tick()
+	if patches != 2 {
+		t.Errorf("Expected no patch attempts while open, got %d", patches)
+	}
+	if len(runner.Status().Healthy) != 1 {
+		t.Error("Expected probing to continue while the breaker is open")
+	}
+
+	failing = false
+	now = now.Add(time.Minute)
+	tick()
+	if patches != 3 || runner.Status().PatchBreaker != BreakerClosed {
+		t.Errorf("Expected a successful trial patch to close the breaker, got %d patches and state %q", patches, runner.Status().PatchBreaker)
+	}
+	if got := testutil.ToFloat64(patchBreakerState); got != 0 {
+		t.Errorf("Expected patch_breaker_state 0, got %v", got)
+	}
+}
@@ -0,0 +1,16 @@
+package prober
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// WatchTargets registers the informer for the target resource (Ingresses,
+// Services, Gateways or HTTPRoutes) with the manager's cache so it is started
+// and synced with the cache instead of lazily on the first tick. The manager
+// client then serves every tick's List from that informer.
+func WatchTargets(ctx context.Context, informers cache.Informers, resource string) error {
+	_, err := informers.GetInformer(ctx, newTargetObject(resource), cache.BlockUntilSynced(false))
+	return err
+}
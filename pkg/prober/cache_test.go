@@ -0,0 +1,105 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWatchTargets(t *testing.T) {
+	tests := []struct {
+		resource string
+		gvk      schema.GroupVersionKind
+	}{
+		{resource: TargetResourceIngress, gvk: networkingv1.SchemeGroupVersion.WithKind("Ingress")},
+		{resource: TargetResourceService, gvk: corev1.SchemeGroupVersion.WithKind("Service")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			informers := &informertest.FakeInformers{Scheme: testScheme}
+			if err := WatchTargets(context.Background(), informers, tt.resource); err != nil {
+				t.Fatalf("WatchTargets failed: %v", err)
+			}
+			if _, ok := informers.InformersByGVK[tt.gvk]; !ok || len(informers.InformersByGVK) != 1 {
+				t.Errorf("Expected only a %s informer to be registered, got %v", tt.gvk.Kind, informers.InformersByGVK)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_ListsIngressesFromCache(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer backend.Close()
+
+	var apiRequests atomic.Int32
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiRequests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer apiServer.Close()
+
+	// stands in for the synced informer cache behind the manager client
+	var cacheLists atomic.Int32
+	reader := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("other", map[string]string{"kubernetes.io/ingress.class": "internal"}),
+	).Build()
+	cached := &countingReader{Reader: reader, lists: &cacheLists}
+
+	k8s, err := client.New(&rest.Config{Host: apiServer.URL}, client.Options{
+		Scheme: testScheme,
+		Mapper: meta.NewDefaultRESTMapper(nil),
+		Cache:  &client.CacheOptions{Reader: cached},
+	})
+	if err != nil {
+		t.Fatalf("failed to build client: %v", err)
+	}
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(backend),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "external-dns.alpha.kubernetes.io/target",
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("tick failed: %v", err)
+		}
+	}
+	if got := cacheLists.Load(); got != 3 {
+		t.Errorf("Expected 3 Lists served from the cache, got %d", got)
+	}
+	if got := apiRequests.Load(); got != 0 {
+		t.Errorf("Expected no API server requests, got %d", got)
+	}
+}
+
+// countingReader counts List calls reaching the wrapped cache reader.
+type countingReader struct {
+	client.Reader
+	lists *atomic.Int32
+}
+
+func (c *countingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.lists.Add(1)
+	return c.Reader.List(ctx, list, opts...)
+}
@@ -0,0 +1,42 @@
+package prober
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errCertPinMismatch is returned from the TLS handshake when the peer's leaf
+// certificate does not match the configured fingerprint.
+var errCertPinMismatch = errors.New("certificate fingerprint mismatch")
+
+// parseCertFingerprint decodes a hex SHA-256 fingerprint, accepting the
+// colon-separated form printed by openssl.
+func parseCertFingerprint(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(b) != sha256.Size {
+		return nil, fmt.Errorf("invalid certificate SHA-256 fingerprint %q", s)
+	}
+	return b, nil
+}
+
+// verifyCertPin returns a tls.Config.VerifyConnection callback that fails the
+// handshake unless the leaf certificate's SHA-256 equals want. It runs after
+// and independently of CA verification, so it also applies with
+// InsecureSkipVerify.
+func verifyCertPin(want []byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("%w: no peer certificate", errCertPinMismatch)
+		}
+		got := sha256.Sum256(cs.PeerCertificates[0].Raw)
+		if !bytes.Equal(got[:], want) {
+			return fmt.Errorf("%w: got %s", errCertPinMismatch, hex.EncodeToString(got[:]))
+		}
+		return nil
+	}
+}
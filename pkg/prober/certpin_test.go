@@ -0,0 +1,89 @@
+package prober
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_Probe_CertPin(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	match := hex.EncodeToString(sum[:])
+	other := sha256.Sum256([]byte("another certificate"))
+
+	tests := []struct {
+		name        string
+		pin         string
+		expectError string
+	}{
+		{name: "matching", pin: match},
+		{name: "matching colon form", pin: strings.ToUpper(colonHex(sum[:]))},
+		{name: "mismatching", pin: hex.EncodeToString(other[:]), expectError: ErrorTypeTLSPin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{
+				Scheme:             "https",
+				Timeout:            time.Second,
+				InsecureSkipVerify: true,
+				ExpectCertSHA256:   tt.pin,
+			}
+			opts.IPs = []string{"10.0.0.1"}
+			if err := opts.validate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			client := opts.httpClient()
+			d := &net.Dialer{}
+			client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, network, server.Listener.Addr().String())
+			}
+			runner := &Runner{ips: []string{"10.0.0.1"}, httpClient: client, urlScheme: "https", httpPath: "/"}
+
+			healthy, failures := runner.probeAll(context.Background())
+			if tt.expectError == "" {
+				if len(healthy) != 1 {
+					t.Errorf("Expected the IP to be healthy, got %v", failures)
+				}
+				return
+			}
+			if got := classifyProbeError(failures["10.0.0.1"]); got != tt.expectError {
+				t.Errorf("Expected error type %q, got %q (%v)", tt.expectError, got, failures["10.0.0.1"])
+			}
+		})
+	}
+}
+
+func TestParseCertFingerprint(t *testing.T) {
+	sum := sha256.Sum256([]byte("x"))
+	for _, s := range []string{hex.EncodeToString(sum[:]), colonHex(sum[:])} {
+		if _, err := parseCertFingerprint(s); err != nil {
+			t.Errorf("Unexpected error for %q: %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "abcd", "zz" + hex.EncodeToString(sum[1:])} {
+		if _, err := parseCertFingerprint(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+// colonHex formats b like openssl's fingerprint output (AB:CD:...).
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = hex.EncodeToString([]byte{c})
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,72 @@
+package prober
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// CIDR modes for targets outside AllowedCIDRs.
+const (
+	// CIDRModeReject fails configuration when a target is outside the allowlist.
+	CIDRModeReject = "reject"
+	// CIDRModeSkip drops such targets with a warning.
+	CIDRModeSkip = "skip"
+)
+
+// parseCIDRs parses an allowlist of prefixes.
+func parseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed CIDR %q: %w", c, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// allowedIP reports whether ip falls inside one of prefixes. The zone of an
+// IPv6 address is ignored, since prefixes never match zoned addresses.
+func allowedIP(ip string, prefixes []netip.Prefix) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap().WithZone("")
+	for _, p := range prefixes {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTargets applies the CIDR allowlist to ts. In CIDRModeReject any
+// target outside prefixes is an error; in CIDRModeSkip those targets are
+// dropped and returned. An empty allowlist allows everything.
+func filterTargets(ts targetSet, prefixes []netip.Prefix, mode string) (targetSet, []string, error) {
+	if len(prefixes) == 0 {
+		return ts, nil, nil
+	}
+	var outside []string
+	for _, ip := range ts.ips {
+		if !allowedIP(ip, prefixes) {
+			outside = append(outside, ip)
+		}
+	}
+	if len(outside) == 0 {
+		return ts, nil, nil
+	}
+	if mode != CIDRModeSkip {
+		return targetSet{}, nil, fmt.Errorf("targets outside the allowed CIDRs: %s", strings.Join(outside, ","))
+	}
+	out := targetSet{labels: ts.labels, probeAddrs: ts.probeAddrs}
+	for _, ip := range ts.ips {
+		if allowedIP(ip, prefixes) {
+			out.ips = append(out.ips, ip)
+		}
+	}
+	return out, outside, nil
+}
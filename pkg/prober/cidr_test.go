@@ -0,0 +1,55 @@
+package prober
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNew_AllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name        string
+		ips         []string
+		mode        string
+		expected    []string
+		expectError bool
+	}{
+		{name: "all inside", ips: []string{"10.0.0.1", "192.168.1.20"}, expected: []string{"10.0.0.1", "192.168.1.20"}},
+		{name: "reject outside", ips: []string{"10.0.0.1", "172.16.0.1"}, expectError: true},
+		{name: "reject is the default mode", ips: []string{"172.16.0.1"}, mode: "", expectError: true},
+		{name: "skip outside", ips: []string{"10.0.0.1", "172.16.0.1", "192.168.1.20"}, mode: CIDRModeSkip, expected: []string{"10.0.0.1", "192.168.1.20"}},
+		{name: "skip leaving nothing", ips: []string{"172.16.0.1"}, mode: CIDRModeSkip, expectError: true},
+		{name: "mapped form inside", ips: []string{"::ffff:10.0.0.9"}, expected: []string{"10.0.0.9"}},
+		{name: "labelled target", ips: []string{"10.0.0.1;region=eu", "8.8.8.8"}, mode: CIDRModeSkip, expected: []string{"10.0.0.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := New(Options{
+				IPs:          tt.ips,
+				AllowedCIDRs: []string{"10.0.0.0/8", "192.168.1.0/24"},
+				CIDRMode:     tt.mode,
+			})
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got targets %v", runner.currentIPs())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := runner.currentIPs(); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected targets %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateCIDRs(t *testing.T) {
+	if _, err := New(Options{IPs: []string{"10.0.0.1"}, AllowedCIDRs: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("Expected error for an invalid CIDR")
+	}
+	if _, err := New(Options{IPs: []string{"10.0.0.1"}, CIDRMode: "warn"}); err == nil {
+		t.Error("Expected error for an unsupported CIDR mode")
+	}
+}
@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const cleanupTimeout = 10 * time.Second
+
+// markManaged records an object as managed during this session so it can be
+// reverted on shutdown.
+func (r *Runner) markManaged(key types.NamespacedName) {
+	if !r.cleanupOnShutdown {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.managed == nil {
+		r.managed = map[types.NamespacedName]struct{}{}
+	}
+	r.managed[key] = struct{}{}
+}
+
+// cleanup removes the managed annotation from every object managed during
+// this session. It runs with its own deadline since ctx is usually already
+// cancelled at shutdown.
+func (r *Runner) cleanup(ctx context.Context) {
+	if r.k8s == nil {
+		return
+	}
+	logger := log.FromContext(ctx)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+
+	r.mu.Lock()
+	keys := make([]types.NamespacedName, 0, len(r.managed))
+	for k := range r.managed {
+		keys = append(keys, k)
+	}
+	r.mu.Unlock()
+
+	for _, key := range keys {
+		obj := r.newTarget()
+		if err := r.k8s.Get(ctx, key, obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logger.Error(err, "failed to get object for cleanup", "object", key.String())
+			}
+			continue
+		}
+		annotations := obj.GetAnnotations()
+		valueKeys := r.valueKeys()
+		hasValue := slices.ContainsFunc(valueKeys, func(k string) bool {
+			_, ok := annotations[k]
+			return ok
+		})
+		_, hasMarker := annotations[r.proberKey(managedAnnotationName)]
+		_, hasRecordType := annotations[RecordTypeAnnotationKey]
+		_, hasCount := annotations[r.proberKey(countAnnotationName)]
+		if !hasValue && !hasMarker && !(r.writesRecordType() && hasRecordType) && !(r.writesCount() && hasCount) {
+			continue
+		}
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		for _, k := range valueKeys {
+			delete(annotations, k)
+		}
+		delete(annotations, r.proberKey(managedAnnotationName))
+		if r.writesRecordType() {
+			delete(annotations, RecordTypeAnnotationKey)
+		}
+		if r.writesCount() {
+			delete(annotations, r.proberKey(countAnnotationName))
+		}
+		if err := r.k8sOp(ctx, "patch", func(ctx context.Context) error { return r.k8s.Patch(ctx, obj, patch) }); err != nil {
+			logger.Error(err, "failed to remove annotation on shutdown", "object", key.String(), "keys", valueKeys)
+			continue
+		}
+		logger.Info("removed annotation on shutdown", "object", key.String(), "keys", valueKeys)
+	}
+}
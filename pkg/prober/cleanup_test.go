@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Start_CleanupOnShutdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	const key = "external-dns.alpha.kubernetes.io/target"
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("managed", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+		newIngress("other-class", map[string]string{"kubernetes.io/ingress.class": "private-nginx", key: "1.2.3.4"}),
+	).Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             key,
+		cleanupOnShutdown:         true,
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		interval:                  time.Hour,
+		maxInterval:               time.Hour,
+		timeout:                   time.Second,
+	}
+
+	get := func(name string) *networkingv1.Ingress {
+		t.Helper()
+		ing := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, ing); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return ing
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runner.Start(ctx) }()
+
+	// wait for the startup tick to annotate the managed Ingress
+	deadline := time.Now().Add(5 * time.Second)
+	for get("managed").Annotations[key] != "10.0.0.1" {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected startup tick to set the annotation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Start did not return after cancellation")
+	}
+
+	if v, ok := get("managed").Annotations[key]; ok {
+		t.Errorf("Expected annotation to be removed on shutdown, got %q", v)
+	}
+	if v := get("other-class").Annotations[key]; v != "1.2.3.4" {
+		t.Errorf("Expected unmanaged Ingress to keep its annotation, got %q", v)
+	}
+}
+
+func TestRunner_Start_NoCleanupByDefault(t *testing.T) {
+	const key = "external-dns.alpha.kubernetes.io/target"
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("managed", map[string]string{"kubernetes.io/ingress.class": "public-nginx", key: "10.0.0.1"}),
+	).Build()
+	runner := &Runner{k8s: k8s, annotationKey: key}
+	runner.markManaged(types.NamespacedName{Namespace: "default", Name: "managed"})
+
+	runner.cleanup(context.Background())
+
+	ing := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "managed"}, ing); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	if ing.Annotations[key] != "10.0.0.1" {
+		t.Errorf("Expected annotation to be kept when cleanup is disabled, got %v", ing.Annotations)
+	}
+}
@@ -0,0 +1,61 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// configMapRef points at a single key of a ConfigMap.
+type configMapRef struct {
+	types.NamespacedName
+	Key string
+}
+
+func (c configMapRef) String() string { return c.NamespacedName.String() + "/" + c.Key }
+
+// parseConfigMapRef parses "namespace/name/key".
+func parseConfigMapRef(s string) (configMapRef, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return configMapRef{}, fmt.Errorf("invalid ConfigMap reference %q (want namespace/name/key)", s)
+	}
+	return configMapRef{NamespacedName: types.NamespacedName{Namespace: parts[0], Name: parts[1]}, Key: parts[2]}, nil
+}
+
+// reloadIPsConfigMap re-reads the target list from the configured ConfigMap
+// and swaps it in when it changed. The manager's client serves the Get from
+// its watch-backed cache, so this is cheap enough to run on every tick. An
+// unreadable or invalid ConfigMap leaves the current list in place.
+func (r *Runner) reloadIPsConfigMap(ctx context.Context) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.k8s.Get(ctx, r.ipsConfigMap.NamespacedName, cm); err != nil {
+		return err
+	}
+	value, ok := cm.Data[r.ipsConfigMap.Key]
+	if !ok {
+		return fmt.Errorf("ConfigMap %s has no key %q", r.ipsConfigMap.NamespacedName, r.ipsConfigMap.Key)
+	}
+	entries := parseIPList(value)
+	if len(entries) == 0 {
+		return fmt.Errorf("ConfigMap %s contains no IPs", r.ipsConfigMap)
+	}
+	ts, err := parseTargets(entries)
+	if err != nil {
+		return fmt.Errorf("ConfigMap %s: %w", r.ipsConfigMap, err)
+	}
+	if ts, err = r.prepareTargets(ctx, ts); err != nil {
+		return err
+	}
+
+	old := r.setTargets(ts)
+	if !slices.Equal(old, ts.ips) {
+		log.FromContext(ctx).Info("reloaded target IPs", "configmap", r.ipsConfigMap.String(), "old", strings.Join(old, ","), "new", strings.Join(ts.ips, ","))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_ReloadsIPsFromConfigMap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prober", Name: "targets"},
+		Data:       map[string]string{"ips": "10.0.0.1,10.0.0.2"},
+	}
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(cm).Build()
+	runner, err := New(Options{
+		Client:       k8s,
+		IPs:          []string{"10.9.9.9"},
+		IPsConfigMap: "prober/targets/ips",
+		HTTPClient:   newRoutedHTTPClient(server),
+		Timeout:      time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		data     map[string]string
+		expected []string
+	}{
+		{name: "initial load", expected: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "updated", data: map[string]string{"ips": "10.0.0.3\n# drained: 10.0.0.1\n10.0.0.2"}, expected: []string{"10.0.0.3", "10.0.0.2"}},
+		{name: "invalid keeps current", data: map[string]string{"ips": "10.0.0.4;bad-label"}, expected: []string{"10.0.0.3", "10.0.0.2"}},
+		{name: "missing key keeps current", data: map[string]string{"other": "10.0.0.5"}, expected: []string{"10.0.0.3", "10.0.0.2"}},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.data != nil {
+				cm.Data = step.data
+				if err := k8s.Update(context.Background(), cm); err != nil {
+					t.Fatalf("failed to update ConfigMap: %v", err)
+				}
+			}
+			_ = runner.tick(context.Background())
+			if got := runner.currentIPs(); !slices.Equal(got, step.expected) {
+				t.Errorf("Expected IPs %v, got %v", step.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseConfigMapRef(t *testing.T) {
+	ref, err := parseConfigMapRef("prober/targets/ips")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ref.Namespace != "prober" || ref.Name != "targets" || ref.Key != "ips" {
+		t.Errorf("Unexpected reference %+v", ref)
+	}
+	for _, s := range []string{"", "targets/ips", "prober//ips", "a/b/c/d"} {
+		if _, err := parseConfigMapRef(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestOptions_IPsConfigMapRequiresClient(t *testing.T) {
+	opts := Options{IPsConfigMap: "prober/targets/ips"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected error without a client")
+	}
+}
@@ -0,0 +1,56 @@
+package prober
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// patchCooldown rate-limits annotation updates per object: once an object
+// was patched, it is not patched again until period has passed, even when
+// the desired value changes. An object never patched is not held back.
+// A nil *patchCooldown never holds back.
+type patchCooldown struct {
+	period time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	last map[types.NamespacedName]time.Time
+}
+
+// newPatchCooldown returns a cooldown of period, or nil when period is zero.
+func newPatchCooldown(period time.Duration) *patchCooldown {
+	if period <= 0 {
+		return nil
+	}
+	return &patchCooldown{period: period, now: time.Now, last: map[types.NamespacedName]time.Time{}}
+}
+
+// remaining returns how long key has to wait before it may be patched again;
+// zero means it may be patched now.
+func (c *patchCooldown) remaining(key types.NamespacedName) time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last, ok := c.last[key]
+	if !ok {
+		return 0
+	}
+	if wait := c.period - c.now().Sub(last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// record marks key as patched now.
+func (c *patchCooldown) record(key types.NamespacedName) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last[key] = c.now()
+}
@@ -0,0 +1,137 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestPatchCooldown(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newPatchCooldown(time.Minute)
+	c.now = func() time.Time { return now }
+	a := types.NamespacedName{Namespace: "default", Name: "a"}
+	b := types.NamespacedName{Namespace: "default", Name: "b"}
+
+	if wait := c.remaining(a); wait != 0 {
+		t.Errorf("Expected no wait before the first patch, got %v", wait)
+	}
+	c.record(a)
+	now = now.Add(20 * time.Second)
+	if wait := c.remaining(a); wait != 40*time.Second {
+		t.Errorf("Expected 40s wait, got %v", wait)
+	}
+	if wait := c.remaining(b); wait != 0 {
+		t.Errorf("Expected another object not to be held back, got %v", wait)
+	}
+	now = now.Add(40 * time.Second)
+	if wait := c.remaining(a); wait != 0 {
+		t.Errorf("Expected no wait once the cooldown passed, got %v", wait)
+	}
+
+	if newPatchCooldown(0) != nil {
+		t.Error("Expected a zero period to disable the cooldown")
+	}
+	var disabled *patchCooldown
+	disabled.record(a)
+	if wait := disabled.remaining(a); wait != 0 {
+		t.Errorf("Expected a nil cooldown never to hold back, got %v", wait)
+	}
+}
+
+func TestRunner_Tick_AnnotationCooldown(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		if host == "10.0.0.2" && down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	class := func(ann map[string]string) map[string]string {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		return ann
+	}
+	patches := map[string]int{}
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("a", class(map[string]string{})),
+		newIngress("b", class(map[string]string{})),
+		// already current on the first tick, so first patched later
+		newIngress("c", class(map[string]string{"new.example.com/target": "10.0.0.1,10.0.0.2"})),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches[obj.GetName()]++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	now := time.Unix(0, 0)
+	cooldown := newPatchCooldown(time.Minute)
+	cooldown.now = func() time.Time { return now }
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		cooldown:                  cooldown,
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	annotation := func(name string) string {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations["new.example.com/target"]
+	}
+
+	tick()
+	if patches["a"] != 1 || patches["b"] != 1 || patches["c"] != 0 {
+		t.Fatalf("Expected the first patches to go through, got %v", patches)
+	}
+
+	down.Store(true)
+	now = now.Add(30 * time.Second)
+	tick()
+	if patches["a"] != 1 || patches["b"] != 1 {
+		t.Errorf("Expected a and b to be held back within the cooldown, got %v", patches)
+	}
+	if got := annotation("a"); got != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected a to keep its value within the cooldown, got %q", got)
+	}
+	if patches["c"] != 1 || annotation("c") != "10.0.0.1" {
+		t.Errorf("Expected c, never patched before, to be patched at once, got %d patches and %q", patches["c"], annotation("c"))
+	}
+
+	now = now.Add(30 * time.Second)
+	tick()
+	if patches["a"] != 2 || patches["b"] != 2 || patches["c"] != 1 {
+		t.Errorf("Expected a and b to be patched once the cooldown passed, got %v", patches)
+	}
+	if got := annotation("b"); got != "10.0.0.1" {
+		t.Errorf("Expected b to be updated after the cooldown, got %q", got)
+	}
+}
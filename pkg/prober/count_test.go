@@ -0,0 +1,107 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunner_Tick_WriteCountAnnotation(t *testing.T) {
+	var mu sync.Mutex
+	unhealthy := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		down := unhealthy[host]
+		mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	const (
+		key      = "new.example.com/target"
+		countKey = "ingress-target-prober/healthy-count"
+	)
+	var patches int
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		// the target value is already current, only the count is missing
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx", key: "10.0.0.3,10.0.0.2,10.0.0.1"}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             key,
+		compareAsSet:              true,
+		writeCount:                true,
+		ips:                       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	get := func() map[string]string {
+		t.Helper()
+		ing := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, ing); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return ing.Annotations
+	}
+	tick := func() {
+		t.Helper()
+		patches = 0
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tick()
+	if got := get()[countKey]; got != "3" {
+		t.Errorf("Expected count 3, got %q", got)
+	}
+	if patches != 1 {
+		t.Errorf("Expected the missing count to be patched, got %d patches", patches)
+	}
+
+	tick()
+	if patches != 0 {
+		t.Errorf("Expected no patch while the healthy set is unchanged, got %d", patches)
+	}
+
+	mu.Lock()
+	unhealthy["10.0.0.3"] = true
+	mu.Unlock()
+	tick()
+	got := get()
+	if got[countKey] != "2" || got[key] != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected count 2 for 10.0.0.1,10.0.0.2, got %q for %q", got[countKey], got[key])
+	}
+
+	mu.Lock()
+	delete(unhealthy, "10.0.0.3")
+	mu.Unlock()
+	tick()
+	if got := get()[countKey]; got != "3" {
+		t.Errorf("Expected the count to follow the healthy set back to 3, got %q", got)
+	}
+}
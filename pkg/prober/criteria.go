@@ -0,0 +1,106 @@
+package prober
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusCodes is a set of accepted HTTP status codes as inclusive ranges.
+// An empty set accepts any 2xx status.
+type statusCodes [][2]int
+
+// parseStatusCodes parses a comma-separated list of status codes and ranges
+// such as "200,204,300-399".
+func parseStatusCodes(s string) (statusCodes, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var codes statusCodes
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || from < 100 || to > 599 || from > to {
+			return nil, fmt.Errorf("invalid expected status %q (want codes or ranges such as 200,300-399)", part)
+		}
+		codes = append(codes, [2]int{from, to})
+	}
+	return codes, nil
+}
+
+func (c statusCodes) match(code int) bool {
+	if len(c) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, r := range c {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCriteria checks ExpectStatus, ExpectBody and MaxLatency.
+func (o *Options) validateCriteria() error {
+	if _, err := parseStatusCodes(o.ExpectStatus); err != nil {
+		return err
+	}
+	if o.ExpectStatus != "" && o.HealthExpr != "" {
+		return fmt.Errorf("expected status codes and a health expression are mutually exclusive")
+	}
+	if o.MaxLatency < 0 {
+		return fmt.Errorf("max latency must not be negative")
+	}
+	if (o.ExpectStatus != "" || o.ExpectBody != "" || o.MaxLatency > 0) && o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+		return fmt.Errorf("expected status codes, an expected body and a max latency require the http probe mode")
+	}
+	return nil
+}
+
+// checkResponse applies every configured success criterion to an HTTP probe
+// response and returns the error type and error of the first that fails, in
+// this order: status (or the health expression), latency, headers, trailers,
+// body substring and JSON body. body and bodyErr are the result of reading
+// the body, when it was read.
+func (r *Runner) checkResponse(resp *http.Response, latency time.Duration, body []byte, bodyErr error) (string, error) {
+	if r.healthExpr != nil {
+		if bodyErr != nil {
+			return classifyError(bodyErr), bodyErr
+		}
+		// the expression replaces the status rule
+		if err := r.healthExpr.eval(resp, latency, body); err != nil {
+			return ErrorTypeHealthExpr, err
+		}
+	} else if !r.expectStatus.match(resp.StatusCode) {
+		return ErrorTypeHTTPStatus, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if r.maxLatency > 0 && latency > r.maxLatency {
+		return ErrorTypeLatency, fmt.Errorf("response took %s, over the %s limit", latency.Round(time.Millisecond), r.maxLatency)
+	}
+	if err := checkHeaders(resp.Header, r.expectHeaders, "header"); err != nil {
+		return ErrorTypeHeaderMismatch, err
+	}
+	if bodyErr != nil {
+		return classifyError(bodyErr), bodyErr
+	}
+	if err := checkHeaders(resp.Trailer, r.expectTrailers, "trailer"); err != nil {
+		return ErrorTypeHeaderMismatch, err
+	}
+	if r.expectBody != "" && !bytes.Contains(body, []byte(r.expectBody)) {
+		return ErrorTypeBodyMismatch, fmt.Errorf("response body does not contain %q", r.expectBody)
+	}
+	if r.expectJSON != nil {
+		if err := r.expectJSON.check(body); err != nil {
+			return ErrorTypeBodyMismatch, err
+		}
+	}
+	return "", nil
+}
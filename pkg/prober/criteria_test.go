@@ -0,0 +1,118 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_ProbeOnce_CombinedCriteria(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		status, header, body := http.StatusOK, "yes", "status: ok"
+		switch host {
+		case "10.0.0.2":
+			status = http.StatusAccepted
+		case "10.0.0.3":
+			time.Sleep(200 * time.Millisecond)
+		case "10.0.0.4":
+			header = ""
+		case "10.0.0.5":
+			body = "status: degraded"
+		case "10.0.0.6":
+			// several criteria fail; the status is reported
+			status, header, body = http.StatusInternalServerError, "", ""
+		}
+		if header != "" {
+			w.Header().Set("X-Ready", header)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	runner, err := New(Options{
+		IPs:           []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"},
+		HTTPClient:    newRoutedHTTPClient(server),
+		ExpectStatus:  "200,204",
+		MaxLatency:    100 * time.Millisecond,
+		ExpectHeaders: []string{"X-Ready=yes"},
+		ExpectBody:    "status: ok",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"10.0.0.1": "",
+		"10.0.0.2": ErrorTypeHTTPStatus,
+		"10.0.0.3": ErrorTypeLatency,
+		"10.0.0.4": ErrorTypeHeaderMismatch,
+		"10.0.0.5": ErrorTypeBodyMismatch,
+		"10.0.0.6": ErrorTypeHTTPStatus,
+	}
+	results := runner.ProbeOnce(context.Background())
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for _, res := range results {
+		if res.ErrorType != want[res.IP] || res.Healthy != (want[res.IP] == "") {
+			t.Errorf("%s: expected error type %q, got healthy=%v %q (%s)", res.IP, want[res.IP], res.Healthy, res.ErrorType, res.Error)
+		}
+	}
+
+	healthy, _, err := runner.HealthyIPs(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Join(healthy, ","); got != "10.0.0.1" {
+		t.Errorf("Expected only the IP passing every criterion, got %q", got)
+	}
+}
+
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := parseStatusCodes("200, 204,300-399")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for code, want := range map[int]bool{200: true, 201: false, 204: true, 300: true, 399: true, 400: false} {
+		if got := codes.match(code); got != want {
+			t.Errorf("match(%d) = %v, want %v", code, got, want)
+		}
+	}
+	if !(statusCodes(nil)).match(299) || (statusCodes(nil)).match(301) {
+		t.Error("Expected an empty set to accept exactly the 2xx statuses")
+	}
+	for _, s := range []string{"ok", "200-", "399-300", "99", "600", "200,,204"} {
+		if _, err := parseStatusCodes(s); err == nil {
+			t.Errorf("parseStatusCodes(%q) expected an error", s)
+		}
+	}
+}
+
+func TestOptions_ValidateCriteria(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "all criteria", opts: Options{ExpectStatus: "200-299", ExpectBody: "ok", MaxLatency: time.Second}},
+		{name: "invalid status", opts: Options{ExpectStatus: "2xx"}, wantErr: true},
+		{name: "status with health expression", opts: Options{ExpectStatus: "200", HealthExpr: "status == 200"}, wantErr: true},
+		{name: "negative max latency", opts: Options{MaxLatency: -time.Second}, wantErr: true},
+		{name: "body in tcp mode", opts: Options{ExpectBody: "ok", ProbeMode: ProbeModeTCP}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package prober
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultDeadAfter is the number of consecutive failed probes after which an
+// IP is paused for DeadCooldown.
+const DefaultDeadAfter = 3
+
+// ErrorTypeDead classifies an IP skipped while its dead cooldown runs.
+const ErrorTypeDead = "dead"
+
+var errDeadCooldown = errors.New("not probed during dead cooldown")
+
+// deadIPs pauses probing of IPs that failed threshold consecutive probes.
+// Such an IP is skipped for cooldown and then probed again: a success
+// clears it, another failure pauses it for a further cooldown.
+// A nil *deadIPs never pauses an IP.
+type deadIPs struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]int
+	until    map[string]time.Time
+}
+
+// newDeadIPs returns a tracker pausing IPs for cooldown after threshold
+// consecutive failures, or nil when cooldown is zero.
+func newDeadIPs(threshold int, cooldown time.Duration) *deadIPs {
+	if cooldown <= 0 {
+		return nil
+	}
+	if threshold <= 0 {
+		threshold = DefaultDeadAfter
+	}
+	return &deadIPs{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		failures:  map[string]int{},
+		until:     map[string]time.Time{},
+	}
+}
+
+// paused reports whether ip is in its cooldown and, if so, until when.
+func (d *deadIPs) paused(ip string) (time.Time, bool) {
+	if d == nil {
+		return time.Time{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	until, ok := d.until[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !d.now().Before(until) {
+		delete(d.until, ip)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// record counts a probe result for ip and reports whether it starts a
+// cooldown.
+func (d *deadIPs) record(ip string, healthy bool) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if healthy {
+		delete(d.failures, ip)
+		delete(d.until, ip)
+		return false
+	}
+	d.failures[ip]++
+	if d.failures[ip] < d.threshold {
+		return false
+	}
+	d.until[ip] = d.now().Add(d.cooldown)
+	return true
+}
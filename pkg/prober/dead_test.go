@@ -0,0 +1,149 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeadIPs(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := newDeadIPs(2, time.Minute)
+	d.now = func() time.Time { return now }
+
+	if d.record("10.0.0.1", false) {
+		t.Fatal("Expected a single failure not to pause the IP")
+	}
+	if !d.record("10.0.0.1", false) {
+		t.Fatal("Expected the second consecutive failure to pause the IP")
+	}
+	if _, ok := d.paused("10.0.0.1"); !ok {
+		t.Error("Expected the IP to be paused")
+	}
+	if _, ok := d.paused("10.0.0.2"); ok {
+		t.Error("Expected another IP not to be paused")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := d.paused("10.0.0.1"); ok {
+		t.Error("Expected the IP to be probed again after the cooldown")
+	}
+	if !d.record("10.0.0.1", false) {
+		t.Error("Expected a failed retest to pause the IP again")
+	}
+	d.record("10.0.0.1", true)
+	if _, ok := d.paused("10.0.0.1"); ok {
+		t.Error("Expected a success to clear the dead state")
+	}
+	if d.record("10.0.0.1", false) {
+		t.Error("Expected a success to reset the failure count")
+	}
+
+	if newDeadIPs(3, 0) != nil {
+		t.Error("Expected a zero cooldown to disable dead tracking")
+	}
+	var disabled *deadIPs
+	if disabled.record("10.0.0.1", false) {
+		t.Error("Expected a nil tracker never to pause")
+	}
+	if _, ok := disabled.paused("10.0.0.1"); ok {
+		t.Error("Expected a nil tracker never to pause")
+	}
+}
+
+func TestRunner_Tick_DeadCooldown(t *testing.T) {
+	for _, concurrency := range []int{1, 2} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var (
+				down atomic.Bool
+				mu   sync.Mutex
+				hits = map[string]int{}
+			)
+			down.Store(true)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				host, _, _ := net.SplitHostPort(r.Host)
+				mu.Lock()
+				hits[host]++
+				mu.Unlock()
+				if host == "10.0.0.2" && down.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			hitsOf := func(host string) int {
+				mu.Lock()
+				defer mu.Unlock()
+				return hits[host]
+			}
+
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).Build()
+			runner, err := New(Options{
+				Client:           k8s,
+				AnnotationKey:    "new.example.com/target",
+				IPs:              []string{"10.0.0.1", "10.0.0.2"},
+				HTTPClient:       newRoutedHTTPClient(server),
+				DeadAfter:        2,
+				DeadCooldown:     time.Minute,
+				ProbeConcurrency: concurrency,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			now := time.Unix(0, 0)
+			runner.dead.now = func() time.Time { return now }
+			tick := func() {
+				t.Helper()
+				if err := runner.tick(context.Background()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			annotation := func() string {
+				t.Helper()
+				got := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+					t.Fatalf("failed to get Ingress: %v", err)
+				}
+				return got.Annotations["new.example.com/target"]
+			}
+
+			tick()
+			tick()
+			tick()
+			if got := hitsOf("10.0.0.2"); got != 2 {
+				t.Errorf("Expected the dead IP to be skipped during its cooldown after 2 probes, got %d probes", got)
+			}
+			if got := hitsOf("10.0.0.1"); got != 3 {
+				t.Errorf("Expected the healthy IP to be probed every tick, got %d probes", got)
+			}
+			if got := runner.Status().Errors["10.0.0.2"]; got != ErrorTypeDead {
+				t.Errorf("Expected the skipped IP to be reported as %q, got %q", ErrorTypeDead, got)
+			}
+			if v := annotation(); v != "10.0.0.1" {
+				t.Errorf("Expected the dead IP to stay out of the annotation, got %q", v)
+			}
+
+			down.Store(false)
+			now = now.Add(time.Minute)
+			tick()
+			if got := hitsOf("10.0.0.2"); got != 3 {
+				t.Errorf("Expected the dead IP to be retested after the cooldown, got %d probes", got)
+			}
+			if v := annotation(); v != "10.0.0.1,10.0.0.2" {
+				t.Errorf("Expected the recovered IP back in the annotation, got %q", v)
+			}
+		})
+	}
+}
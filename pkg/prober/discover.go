@@ -0,0 +1,96 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Discovery write modes: what is written once discovered endpoints were probed.
+const (
+	// DiscoverWriteIPs writes the configured IPs (e.g. the load balancer
+	// addresses) while at least one discovered endpoint is healthy.
+	DiscoverWriteIPs = "ips"
+	// DiscoverWriteDiscovered writes the healthy discovered pod IPs.
+	DiscoverWriteDiscovered = "discovered"
+)
+
+// validateDiscovery checks the endpoint discovery settings.
+func (o *Options) validateDiscovery() error {
+	if o.DiscoverSelector == "" {
+		return nil
+	}
+	if o.Client == nil {
+		return fmt.Errorf("endpoint discovery requires a Kubernetes client")
+	}
+	if _, err := labels.Parse(o.DiscoverSelector); err != nil {
+		return fmt.Errorf("invalid discovery selector %q: %w", o.DiscoverSelector, err)
+	}
+	if o.IPsFile != "" || o.IPsConfigMap != "" {
+		return fmt.Errorf("endpoint discovery does not support an IPs file or ConfigMap")
+	}
+	switch o.DiscoverWrite {
+	case "", DiscoverWriteIPs:
+		if len(o.IPs) == 0 {
+			return fmt.Errorf("discovery write mode %q requires IPs to write", DiscoverWriteIPs)
+		}
+	case DiscoverWriteDiscovered:
+		if len(o.IPs) > 0 {
+			return fmt.Errorf("IPs are only written with discovery write mode %q", DiscoverWriteIPs)
+		}
+	default:
+		return fmt.Errorf("unsupported discovery write mode %q (want %s or %s)", o.DiscoverWrite, DiscoverWriteIPs, DiscoverWriteDiscovered)
+	}
+	return nil
+}
+
+// discoverEndpoints returns the IPs of running Pods matching the discovery
+// selector, sorted so the target order is stable between ticks.
+func (r *Runner) discoverEndpoints(ctx context.Context) ([]string, error) {
+	pods := &corev1.PodList{}
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: r.discoverSelector}}
+	if r.discoverNamespace != "" {
+		opts = append(opts, client.InNamespace(r.discoverNamespace))
+	}
+	if err := r.k8s.List(ctx, pods, opts...); err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, p := range pods.Items {
+		if p.Status.Phase != corev1.PodRunning || p.Status.PodIP == "" || p.DeletionTimestamp != nil {
+			continue
+		}
+		ips = append(ips, p.Status.PodIP)
+	}
+	slices.SortFunc(ips, compareIPs)
+	return slices.Compact(ips), nil
+}
+
+// refreshDiscoveredTargets replaces the probe targets with the currently
+// discovered endpoints. On failure the previous targets stay in place.
+func (r *Runner) refreshDiscoveredTargets(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	ips, err := r.discoverEndpoints(ctx)
+	if err != nil {
+		logger.Error(err, "failed to discover endpoints; keeping current targets", "selector", r.discoverSelector.String())
+		return
+	}
+	ts := targetSet{ips: ips}
+	if r.discoverWrite == DiscoverWriteDiscovered && len(ips) > 0 {
+		// discovered IPs end up in annotations, so the usual target checks apply
+		if ts, err = r.prepareTargets(ctx, ts); err != nil {
+			logger.Error(err, "discovered endpoints rejected; keeping current targets")
+			return
+		}
+	}
+	old := r.setTargets(ts)
+	if !slices.Equal(old, ts.ips) {
+		logger.Info("discovered endpoints", "selector", r.discoverSelector.String(), "old", strings.Join(old, ","), "new", strings.Join(ts.ips, ","))
+	}
+}
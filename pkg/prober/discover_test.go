@@ -0,0 +1,150 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newPod(namespace, name, ip string, phase corev1.PodPhase, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Status:     corev1.PodStatus{Phase: phase, PodIP: ip},
+	}
+}
+
+func controllerPods() []client.Object {
+	sel := map[string]string{"app": "ingress-nginx"}
+	return []client.Object{
+		newPod("ingress", "nginx-b", "10.1.0.3", corev1.PodRunning, sel),
+		newPod("ingress", "nginx-a", "10.1.0.2", corev1.PodRunning, sel),
+		newPod("ingress", "nginx-c", "10.1.0.10", corev1.PodRunning, sel),
+		newPod("ingress", "nginx-pending", "10.1.0.4", corev1.PodPending, sel),
+		newPod("ingress", "nginx-no-ip", "", corev1.PodRunning, sel),
+		newPod("ingress", "other", "10.1.0.5", corev1.PodRunning, map[string]string{"app": "other"}),
+		newPod("staging", "nginx-staging", "10.2.0.2", corev1.PodRunning, sel),
+	}
+}
+
+func TestRunner_DiscoverEndpoints(t *testing.T) {
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(controllerPods()...).Build()
+
+	tests := []struct {
+		name      string
+		namespace string
+		expected  []string
+	}{
+		{name: "namespaced", namespace: "ingress", expected: []string{"10.1.0.2", "10.1.0.3", "10.1.0.10"}},
+		{name: "all namespaces", expected: []string{"10.1.0.2", "10.1.0.3", "10.1.0.10", "10.2.0.2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := New(Options{
+				Client:            k8s,
+				IPs:               []string{"203.0.113.10"},
+				DiscoverSelector:  "app=ingress-nginx",
+				DiscoverNamespace: tt.namespace,
+			})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			got, err := runner.discoverEndpoints(context.Background())
+			if err != nil {
+				t.Fatalf("discoverEndpoints failed: %v", err)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_DiscoveredEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		write     string
+		ips       []string
+		unhealthy []string
+		expected  string
+	}{
+		{name: "write configured IPs", write: DiscoverWriteIPs, ips: []string{"203.0.113.10", "203.0.113.11"}, unhealthy: []string{"10.1.0.2"}, expected: "203.0.113.10,203.0.113.11"},
+		{name: "configured IPs untouched when no pod is healthy", write: DiscoverWriteIPs, ips: []string{"203.0.113.10"}, unhealthy: []string{"10.1.0.2", "10.1.0.3", "10.1.0.10"}, expected: ""},
+		{name: "write discovered IPs", write: DiscoverWriteDiscovered, unhealthy: []string{"10.1.0.3"}, expected: "10.1.0.2,10.1.0.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				host, _, _ := net.SplitHostPort(r.Host)
+				if slices.Contains(tt.unhealthy, host) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			objs := append(controllerPods(), newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}))
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objs...).Build()
+			runner, err := New(Options{
+				Client:            k8s,
+				IPs:               tt.ips,
+				DiscoverSelector:  "app=ingress-nginx",
+				DiscoverNamespace: "ingress",
+				DiscoverWrite:     tt.write,
+				AnnotationKey:     "new.example.com/target",
+				HTTPClient:        newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			_ = runner.tick(context.Background())
+
+			if want := []string{"10.1.0.2", "10.1.0.3", "10.1.0.10"}; !slices.Equal(runner.currentIPs(), want) {
+				t.Errorf("Expected discovered targets %v, got %v", want, runner.currentIPs())
+			}
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if v := got.Annotations["new.example.com/target"]; v != tt.expected {
+				t.Errorf("Expected annotation %q, got %q", tt.expected, v)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateDiscovery(t *testing.T) {
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "write ips", opts: Options{Client: k8s, IPs: []string{"203.0.113.10"}, DiscoverSelector: "app=nginx"}},
+		{name: "write discovered", opts: Options{Client: k8s, DiscoverSelector: "app=nginx", DiscoverWrite: DiscoverWriteDiscovered}},
+		{name: "write ips without ips", opts: Options{Client: k8s, DiscoverSelector: "app=nginx"}, expectError: true},
+		{name: "write discovered with ips", opts: Options{Client: k8s, IPs: []string{"203.0.113.10"}, DiscoverSelector: "app=nginx", DiscoverWrite: DiscoverWriteDiscovered}, expectError: true},
+		{name: "no client", opts: Options{IPs: []string{"203.0.113.10"}, DiscoverSelector: "app=nginx"}, expectError: true},
+		{name: "bad selector", opts: Options{Client: k8s, IPs: []string{"203.0.113.10"}, DiscoverSelector: "app in (nginx"}, expectError: true},
+		{name: "ips file", opts: Options{Client: k8s, IPsFile: "ips.txt", DiscoverSelector: "app=nginx"}, expectError: true},
+		{name: "bad write mode", opts: Options{Client: k8s, IPs: []string{"203.0.113.10"}, DiscoverSelector: "app=nginx", DiscoverWrite: "both"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
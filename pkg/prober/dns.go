@@ -0,0 +1,105 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	ProbeModeHTTP = "http"
+	ProbeModeDNS  = "dns"
+
+	DefaultDNSRecordType = "A"
+	DefaultDNSPort       = "53"
+)
+
+// dnsLookup is the subset of *net.Resolver used by DNS probes.
+type dnsLookup interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// newServerResolver returns a resolver that sends every query to server.
+func newServerResolver(server string) dnsLookup {
+	d := &net.Dialer{}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func validateDNSRecordType(t string) error {
+	switch strings.ToUpper(t) {
+	case "A", "AAAA", "TXT":
+		return nil
+	}
+	return fmt.Errorf("unsupported DNS record type %q (want A, AAAA or TXT)", t)
+}
+
+// probeDNS queries ip as a DNS server; it is healthy when the answer for
+// dnsQueryName contains dnsExpect. A mismatching answer is a body-mismatch.
+func (r *Runner) probeDNS(ctx context.Context, logger logr.Logger, ip string) error {
+	server := r.probeAddress(ip, r.dnsPort)
+	release, err := r.hostLimit.acquire(ctx, server)
+	if err != nil {
+		return newProbeError(classifyError(err), err)
+	}
+	defer release()
+	resolver := r.newResolver(server)
+	logger.Info("probing IP", "ip", ip, "mode", ProbeModeDNS, "name", r.dnsQueryName, "type", r.dnsRecordType)
+
+	var answers []string
+	switch strings.ToUpper(r.dnsRecordType) {
+	case "TXT":
+		txt, err := resolver.LookupTXT(ctx, r.dnsQueryName)
+		if err != nil {
+			logger.Info("DNS query failed", "ip", ip, "error", err.Error(), "error_type", ErrorTypeDNS)
+			return newProbeError(ErrorTypeDNS, err)
+		}
+		answers = txt
+	default:
+		network := "ip4"
+		if strings.ToUpper(r.dnsRecordType) == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, r.dnsQueryName)
+		if err != nil {
+			logger.Info("DNS query failed", "ip", ip, "error", err.Error(), "error_type", ErrorTypeDNS)
+			return newProbeError(ErrorTypeDNS, err)
+		}
+		for _, a := range ips {
+			answers = append(answers, a.String())
+		}
+	}
+
+	if dnsAnswerMatches(r.dnsRecordType, answers, r.dnsExpect) {
+		logger.Info("IP marked as healthy", "ip", ip, "answers", answers)
+		return nil
+	}
+	logger.Info("IP marked as unhealthy due to DNS answer mismatch", "ip", ip, "answers", answers, "expected", r.dnsExpect, "error_type", ErrorTypeBodyMismatch)
+	return newProbeError(ErrorTypeBodyMismatch, fmt.Errorf("DNS answer %v does not contain %q", answers, r.dnsExpect))
+}
+
+// dnsAnswerMatches reports whether expect is among answers. Address records
+// are compared as IPs so equivalent textual forms match.
+func dnsAnswerMatches(recordType string, answers []string, expect string) bool {
+	want := net.ParseIP(expect)
+	for _, a := range answers {
+		if strings.ToUpper(recordType) != "TXT" && want != nil {
+			if got := net.ParseIP(a); got != nil && got.Equal(want) {
+				return true
+			}
+			continue
+		}
+		if a == expect {
+			return true
+		}
+	}
+	return false
+}
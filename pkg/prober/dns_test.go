@@ -0,0 +1,110 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+// stubResolver answers queries from fixed tables keyed by DNS server address.
+type stubResolver struct {
+	server string
+	ips    map[string][]net.IP
+	txt    map[string][]string
+}
+
+func (s *stubResolver) LookupIP(_ context.Context, network, _ string) ([]net.IP, error) {
+	ips, ok := s.ips[s.server]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	var out []net.IP
+	for _, ip := range ips {
+		if (network == "ip4") == (ip.To4() != nil) {
+			out = append(out, ip)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubResolver) LookupTXT(_ context.Context, _ string) ([]string, error) {
+	txt, ok := s.txt[s.server]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return txt, nil
+}
+
+func TestRunner_HealthyIPs_DNSMode(t *testing.T) {
+	ips := map[string][]net.IP{
+		"10.0.0.1:53": {net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")},
+		"10.0.0.2:53": {net.ParseIP("192.0.2.99")},
+		"10.0.0.3:53": {net.ParseIP("2001:db8::10")},
+	}
+	txt := map[string][]string{
+		"10.0.0.1:53": {"v=ok", "other"},
+		"10.0.0.2:53": {"v=stale"},
+	}
+
+	tests := []struct {
+		name            string
+		recordType      string
+		expect          string
+		expectedHealthy []string
+	}{
+		{name: "A match", recordType: "A", expect: "192.0.2.10", expectedHealthy: []string{"10.0.0.1"}},
+		{name: "AAAA match", recordType: "AAAA", expect: "2001:db8:0::10", expectedHealthy: []string{"10.0.0.1", "10.0.0.3"}},
+		{name: "TXT match", recordType: "txt", expect: "v=ok", expectedHealthy: []string{"10.0.0.1"}},
+		{name: "no match", recordType: "A", expect: "192.0.2.1", expectedHealthy: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{
+				ips:           []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+				probeMode:     ProbeModeDNS,
+				dnsQueryName:  "health.example.com",
+				dnsRecordType: tt.recordType,
+				dnsExpect:     tt.expect,
+				dnsPort:       "53",
+				newResolver: func(server string) dnsLookup {
+					return &stubResolver{server: server, ips: ips, txt: txt}
+				},
+			}
+
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if !slices.Equal(healthy, tt.expectedHealthy) {
+				t.Errorf("Expected healthy %v, got %v", tt.expectedHealthy, healthy)
+			}
+			if (tt.expectedHealthy == nil) != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateDNSMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "valid", opts: Options{ProbeMode: ProbeModeDNS, DNSQueryName: "a.example.com", DNSExpect: "192.0.2.1", DNSRecordType: "A"}},
+		{name: "missing name", opts: Options{ProbeMode: ProbeModeDNS, DNSExpect: "192.0.2.1", DNSRecordType: "A"}, expectError: true},
+		{name: "missing expect", opts: Options{ProbeMode: ProbeModeDNS, DNSQueryName: "a.example.com", DNSRecordType: "A"}, expectError: true},
+		{name: "bad record type", opts: Options{ProbeMode: ProbeModeDNS, DNSQueryName: "a.example.com", DNSExpect: "x", DNSRecordType: "MX"}, expectError: true},
+		{name: "unknown mode", opts: Options{ProbeMode: "icmp"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package prober
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner_HealthyIPs_DrainBodyReusesConnections(t *testing.T) {
+	tests := []struct {
+		name      string
+		drain     bool
+		wantConns int64
+	}{
+		{name: "drained", drain: true, wantConns: 1},
+		{name: "closed unread", drain: false, wantConns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conns atomic.Int64
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				// large enough that the transport does not finish reading it on close
+				_, _ = io.WriteString(w, strings.Repeat("x", 512<<10))
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			runner, err := New(Options{
+				IPs:        []string{"10.0.0.1"},
+				DrainBody:  tt.drain,
+				HTTPClient: newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i := 0; i < 3; i++ {
+				if _, _, err := runner.HealthyIPs(context.Background()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if got := conns.Load(); got != tt.wantConns {
+				t.Errorf("Expected %d connections for 3 probes, got %d", tt.wantConns, got)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_BodyReadWithinTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// stall the body until the test ends
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// a client without its own timeout leaves the bound to the probe context
+	client := newRoutedHTTPClient(server)
+	client.Timeout = 0
+	runner, err := New(Options{
+		IPs:        []string{"10.0.0.1"},
+		DrainBody:  true,
+		Timeout:    100 * time.Millisecond,
+		HTTPClient: client,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = runner.HealthyIPs(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the stalled body read to be cut off by the probe timeout")
+	}
+}
@@ -0,0 +1,126 @@
+package prober
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// validateDualStack checks the IPv6 annotation key against the options it
+// interacts with.
+func (o *Options) validateDualStack() error {
+	if o.AnnotationKeyV6 == "" {
+		return nil
+	}
+	if o.AnnotationKeyV6 == o.AnnotationKey {
+		return fmt.Errorf("the IPv6 annotation key must differ from the annotation key")
+	}
+	if o.RecordType != "" {
+		return fmt.Errorf("a record type cannot be combined with a separate IPv6 annotation key")
+	}
+	return nil
+}
+
+// splitFamilies splits ips into IPv4 and IPv6 addresses, keeping their
+// order. IPv4-mapped IPv6 addresses count as IPv4; entries that do not
+// parse are dropped.
+func splitFamilies(ips []string) (v4, v6 []string) {
+	for _, ip := range ips {
+		a, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		if a.Unmap().Is4() {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	return v4, v6
+}
+
+// addFamilyAnnotations sets the annotation key to the healthy IPv4 and the
+// IPv6 annotation key to the healthy IPv6 addresses. A family without healthy
+// addresses gets the unhealthy mode applied to its key alone once it has been
+// down for the clear grace period: the keep mode leaves the key untouched,
+// the remove mode deletes it and the fallback mode writes the family's
+// fallback targets, deleting the key when there are none. It returns the keys
+// to delete.
+func (r *Runner) addFamilyAnnotations(desired map[string]string, healthyIPs []string, obj client.Object) ([]string, error) {
+	v4, v6 := splitFamilies(healthyIPs)
+	fallback4, fallback6 := splitFamilies(r.fallbackTargets)
+	families := []struct {
+		key           string
+		ips, fallback []string
+	}{
+		{key: r.annotationKey, ips: v4, fallback: fallback4},
+		{key: r.annotationKeyV6, ips: v6, fallback: fallback6},
+	}
+	var remove []string
+	for _, f := range families {
+		ips := f.ips
+		if len(ips) == 0 {
+			if !r.familyCleared(f.key) {
+				continue
+			}
+			if r.unhealthyMode == UnhealthyModeFallback {
+				ips = f.fallback
+			}
+			if len(ips) == 0 {
+				remove = append(remove, f.key)
+				continue
+			}
+		}
+		value, err := r.renderValue(ips, obj)
+		if err != nil {
+			return nil, err
+		}
+		desired[f.key] = value
+	}
+	return remove, nil
+}
+
+// trackFamilies records since when each address family has had no healthy
+// IP, for the per-family clear grace period. Callers hold tickMu.
+func (r *Runner) trackFamilies(logger logr.Logger, healthyIPs []string) {
+	if r.annotationKeyV6 == "" {
+		return
+	}
+	if r.familyDownSince == nil {
+		r.familyDownSince = map[string]time.Time{}
+	}
+	v4, v6 := splitFamilies(healthyIPs)
+	for _, f := range []struct {
+		key string
+		ips []string
+	}{{r.annotationKey, v4}, {r.annotationKeyV6, v6}} {
+		_, down := r.familyDownSince[f.key]
+		switch {
+		case len(f.ips) > 0 && down:
+			delete(r.familyDownSince, f.key)
+			logger.Info("healthy IPs available again in address family", "key", f.key)
+		case len(f.ips) == 0 && !down:
+			r.familyDownSince[f.key] = time.Now()
+			logger.Info("no healthy IP in address family", "key", f.key, "mode", r.unhealthyMode)
+		}
+	}
+}
+
+// familyCleared reports whether the unhealthy mode applies to the family
+// written to key: the mode removes or replaces targets and the family has
+// been down for the clear grace period. A family the tick has not seen down,
+// such as one emptied by an object's own targets, is cleared only without a
+// grace period.
+func (r *Runner) familyCleared(key string) bool {
+	if r.unhealthyMode != UnhealthyModeRemove && r.unhealthyMode != UnhealthyModeFallback {
+		return false
+	}
+	since, ok := r.familyDownSince[key]
+	if !ok {
+		return r.clearGracePeriod == 0
+	}
+	return time.Since(since) >= r.clearGracePeriod
+}
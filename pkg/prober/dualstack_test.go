@@ -0,0 +1,211 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSplitFamilies(t *testing.T) {
+	v4, v6 := splitFamilies([]string{"2001:db8::1", "10.0.0.1", "::ffff:10.0.0.2", "fd00::2", "not-an-ip"})
+	if want := []string{"10.0.0.1", "::ffff:10.0.0.2"}; !slices.Equal(v4, want) {
+		t.Errorf("Expected IPv4 %v, got %v", want, v4)
+	}
+	if want := []string{"2001:db8::1", "fd00::2"}; !slices.Equal(v6, want) {
+		t.Errorf("Expected IPv6 %v, got %v", want, v6)
+	}
+}
+
+func TestOptions_ValidateDualStack(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "unset", opts: Options{}},
+		{name: "separate key", opts: Options{AnnotationKeyV6: "example.com/target-v6"}},
+		{name: "same key", opts: Options{AnnotationKeyV6: DefaultAnnotationKey}, expectError: true},
+		{name: "with record type", opts: Options{AnnotationKeyV6: "example.com/target-v6", RecordType: "A"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			tt.opts.setDefaults()
+			if err := tt.opts.validate(); tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_DualStack(t *testing.T) {
+	var v6Down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		switch {
+		case host == "10.0.0.2", host == "2001:db8::2":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case v6Down.Load() && net.ParseIP(host).To4() == nil:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	var patches int
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		annotationKeyV6:           "new.example.com/target-v6",
+		ips:                       []string{"2001:db8::1", "10.0.0.1", "10.0.0.2", "2001:db8::2", "10.0.0.3", "2001:db8::3"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	tick := func() map[string]string {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations
+	}
+
+	ann := tick()
+	if got := ann["new.example.com/target"]; got != "10.0.0.1,10.0.0.3" {
+		t.Errorf("Expected IPv4 annotation %q, got %q", "10.0.0.1,10.0.0.3", got)
+	}
+	if got := ann["new.example.com/target-v6"]; got != "2001:db8::1,2001:db8::3" {
+		t.Errorf("Expected IPv6 annotation %q, got %q", "2001:db8::1,2001:db8::3", got)
+	}
+	if patches != 1 {
+		t.Errorf("Expected a single patch, got %d", patches)
+	}
+
+	tick()
+	if patches != 1 {
+		t.Errorf("Expected no patch when both families are current, got %d", patches)
+	}
+
+	// an IPv6 outage leaves the IPv6 key alone and the IPv4 key current
+	v6Down.Store(true)
+	ann = tick()
+	if patches != 1 {
+		t.Errorf("Expected no patch while only IPv6 is down, got %d", patches)
+	}
+	if got := ann["new.example.com/target-v6"]; got != "2001:db8::1,2001:db8::3" {
+		t.Errorf("Expected IPv6 annotation to be left untouched, got %q", got)
+	}
+}
+
+func TestRunner_Tick_DualStackFamilyUnhealthy(t *testing.T) {
+	const (
+		key   = "new.example.com/target"
+		keyV6 = "new.example.com/target-v6"
+	)
+	tests := []struct {
+		name        string
+		mode        string
+		fallback    []string
+		grace       time.Duration
+		expectV6    string
+		expectV6Set bool
+	}{
+		{name: "keep", mode: UnhealthyModeKeep, expectV6: "2001:db8::1", expectV6Set: true},
+		{name: "remove", mode: UnhealthyModeRemove},
+		{name: "remove within grace period", mode: UnhealthyModeRemove, grace: time.Hour, expectV6: "2001:db8::1", expectV6Set: true},
+		{name: "fallback", mode: UnhealthyModeFallback, fallback: []string{"192.0.2.10", "2001:db8::ff"}, expectV6: "2001:db8::ff", expectV6Set: true},
+		{name: "fallback without targets of the family", mode: UnhealthyModeFallback, fallback: []string{"192.0.2.10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v6Down atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				host, _, _ := net.SplitHostPort(r.Host)
+				if v6Down.Load() && net.ParseIP(host).To4() == nil {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).Build()
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             key,
+				annotationKeyV6:           keyV6,
+				unhealthyMode:             tt.mode,
+				fallbackTargets:           tt.fallback,
+				clearGracePeriod:          tt.grace,
+				ips:                       []string{"10.0.0.1", "2001:db8::1"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			tick := func() map[string]string {
+				t.Helper()
+				if err := runner.tick(context.Background()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				got := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+					t.Fatalf("failed to get Ingress: %v", err)
+				}
+				return got.Annotations
+			}
+
+			if ann := tick(); ann[keyV6] != "2001:db8::1" {
+				t.Fatalf("Expected the IPv6 target to be published, got %v", ann)
+			}
+
+			v6Down.Store(true)
+			ann := tick()
+			if got, ok := ann[keyV6]; ok != tt.expectV6Set || got != tt.expectV6 {
+				t.Errorf("Expected IPv6 annotation %q (set=%v), got %q (set=%v)", tt.expectV6, tt.expectV6Set, got, ok)
+			}
+			if got := ann[key]; got != "10.0.0.1" {
+				t.Errorf("Expected the IPv4 annotation to stay current, got %q", got)
+			}
+
+			v6Down.Store(false)
+			if ann := tick(); ann[keyV6] != "2001:db8::1" {
+				t.Errorf("Expected the IPv6 target to be published again after recovery, got %v", ann)
+			}
+		})
+	}
+}
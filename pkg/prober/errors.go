@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+)
+
+// Probe error types, used as the "type" label of probe_errors_total and in
+// logs and status.
+const (
+	ErrorTypeDNS            = "dns"
+	ErrorTypeConnect        = "connect"
+	ErrorTypeTLS            = "tls"
+	ErrorTypeTLSPin         = "tls-pin-mismatch"
+	ErrorTypeTimeout        = "timeout"
+	ErrorTypeHTTPStatus     = "http-status"
+	ErrorTypeBodyMismatch   = "body-mismatch"
+	ErrorTypeHeaderMismatch = "header-mismatch"
+	ErrorTypeHealthExpr     = "health-expr"
+	ErrorTypeLatency        = "latency"
+	ErrorTypeOther          = "other"
+)
+
+// ProbeError is returned for an unhealthy target and carries its classification.
+type ProbeError struct {
+	Type string
+	Err  error
+}
+
+func (e *ProbeError) Error() string { return e.Type + ": " + e.Err.Error() }
+func (e *ProbeError) Unwrap() error { return e.Err }
+
+func newProbeError(typ string, err error) *ProbeError {
+	return &ProbeError{Type: typ, Err: err}
+}
+
+// classifyError maps a transport-level error onto one of the error types.
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorTypeDNS
+	}
+	if errors.Is(err, errCertPinMismatch) {
+		return ErrorTypeTLSPin
+	}
+	if isTLSError(err) {
+		return ErrorTypeTLS
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorTypeTimeout
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write" {
+			return ErrorTypeConnect
+		}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorTypeConnect
+	}
+	return ErrorTypeOther
+}
+
+func isTLSError(err error) bool {
+	var (
+		recordErr   tls.RecordHeaderError
+		verifyErr   *tls.CertificateVerificationError
+		alertErr    tls.AlertError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		opErr       *net.OpError
+	)
+	// crypto/tls reports alerts sent by the peer, such as a rejected version
+	// or cipher suite, as a "remote error" OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		return true
+	}
+	return errors.As(err, &recordErr) ||
+		errors.As(err, &verifyErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &unknownAuth) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
+// classifyProbeError returns the error type of err, which is usually a *ProbeError.
+func classifyProbeError(err error) string {
+	var pe *ProbeError
+	if errors.As(err, &pe) {
+		return pe.Type
+	}
+	return classifyError(err)
+}
@@ -0,0 +1,178 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestRunner_Probe_ErrorTypes(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer tlsServer.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve a port: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	dnsFailing := &http.Client{Transport: &http.Transport{
+		DialContext: func(_ context.Context, _, addr string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}}
+		},
+	}}
+	slowClient := newRoutedHTTPClient(slow)
+	slowClient.Timeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		runner   *Runner
+		ip       string
+		expected string
+	}{
+		{
+			name:     "dns",
+			runner:   &Runner{httpClient: dnsFailing, urlScheme: "http", httpPath: "/"},
+			ip:       "10.1.0.1",
+			expected: ErrorTypeDNS,
+		},
+		{
+			name: "connect",
+			runner: &Runner{
+				httpClient: &http.Client{Timeout: time.Second},
+				urlScheme:  "http",
+				httpPath:   "/",
+				probeAddrs: map[string]string{"10.1.0.2": closedAddr},
+			},
+			ip:       "10.1.0.2",
+			expected: ErrorTypeConnect,
+		},
+		{
+			name:     "tls",
+			runner:   &Runner{httpClient: newRoutedHTTPClient(tlsServer), urlScheme: "https", httpPath: "/"},
+			ip:       "10.1.0.3",
+			expected: ErrorTypeTLS,
+		},
+		{
+			name:     "timeout",
+			runner:   &Runner{httpClient: slowClient, urlScheme: "http", httpPath: "/"},
+			ip:       "10.1.0.4",
+			expected: ErrorTypeTimeout,
+		},
+		{
+			name:     "http-status",
+			runner:   &Runner{httpClient: newRoutedHTTPClient(unavailable), urlScheme: "http", httpPath: "/"},
+			ip:       "10.1.0.5",
+			expected: ErrorTypeHTTPStatus,
+		},
+		{
+			name: "body-mismatch",
+			runner: &Runner{
+				probeMode:     ProbeModeDNS,
+				dnsQueryName:  "health.example.com",
+				dnsRecordType: "A",
+				dnsExpect:     "192.0.2.10",
+				dnsPort:       "53",
+				newResolver: func(server string) dnsLookup {
+					return &stubResolver{server: server, ips: map[string][]net.IP{"10.1.0.6:53": {net.ParseIP("192.0.2.99")}}}
+				},
+			},
+			ip:       "10.1.0.6",
+			expected: ErrorTypeBodyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.runner.ips = []string{tt.ip}
+			before := testutil.ToFloat64(probeErrors.WithLabelValues(tt.ip, tt.expected))
+
+			healthy, failures := tt.runner.probeAll(context.Background())
+			if len(healthy) != 0 {
+				t.Fatalf("Expected no healthy IPs, got %v", healthy)
+			}
+			if got := classifyProbeError(failures[tt.ip]); got != tt.expected {
+				t.Errorf("Expected error type %q, got %q (%v)", tt.expected, got, failures[tt.ip])
+			}
+			if got := testutil.ToFloat64(probeErrors.WithLabelValues(tt.ip, tt.expected)) - before; got != 1 {
+				t.Errorf("Expected probe_errors_total to increase by 1, got %v", got)
+			}
+		})
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		runner := &Runner{ips: []string{"10.1.0.7"}, httpClient: newRoutedHTTPClient(okServer), urlScheme: "http", httpPath: "/"}
+		healthy, failures := runner.probeAll(context.Background())
+		if len(healthy) != 1 || len(failures) != 0 {
+			t.Errorf("Expected one healthy IP and no failures, got %v and %v", healthy, failures)
+		}
+	})
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "context deadline", err: fmt.Errorf("probe: %w", context.DeadlineExceeded), expected: ErrorTypeTimeout},
+		{name: "dial refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, expected: ErrorTypeConnect},
+		{name: "wrapped probe error", err: fmt.Errorf("x: %w", newProbeError(ErrorTypeTLS, errors.New("bad cert"))), expected: ErrorTypeTLS},
+		{name: "unknown", err: errors.New("boom"), expected: ErrorTypeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyProbeError(tt.err); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunner_Status_IncludesErrorTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.1.1.1"},
+		httpClient: newRoutedHTTPClient(server),
+		urlScheme:  "http",
+		httpPath:   "/",
+		timeout:    time.Second,
+	}
+	ctx := log.IntoContext(context.Background(), log.Log)
+	if err := runner.tick(ctx); err == nil {
+		t.Fatal("Expected tick to fail without healthy IPs")
+	}
+	if got := runner.Status().Errors["10.1.1.1"]; got != ErrorTypeHTTPStatus {
+		t.Errorf("Expected status error type %q, got %q", ErrorTypeHTTPStatus, got)
+	}
+}
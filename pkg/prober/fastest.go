@@ -0,0 +1,47 @@
+package prober
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// fastestIPs returns the k healthy IPs with the lowest measured latency, in
+// their configured order so the written value does not churn with latency
+// jitter. Equal latencies are ranked by IP. With k <= 0 or k >= len(ips), ips
+// is returned unchanged.
+func fastestIPs(ips []string, latencies map[string]time.Duration, k int) []string {
+	if k <= 0 || k >= len(ips) {
+		return ips
+	}
+	ranked := slices.Clone(ips)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		if la, lb := latencies[a], latencies[b]; la != lb {
+			if la < lb {
+				return -1
+			}
+			return 1
+		}
+		return compareIPs(a, b)
+	})
+	keep := make(map[string]struct{}, k)
+	for _, ip := range ranked[:k] {
+		keep[ip] = struct{}{}
+	}
+	return slices.DeleteFunc(slices.Clone(ips), func(ip string) bool {
+		_, ok := keep[ip]
+		return !ok
+	})
+}
+
+// compareIPs orders addresses numerically, falling back to string order for
+// values that are not IP addresses.
+func compareIPs(a, b string) int {
+	pa, errA := netip.ParseAddr(a)
+	pb, errB := netip.ParseAddr(b)
+	if errA == nil && errB == nil {
+		return pa.Compare(pb)
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFastestIPs(t *testing.T) {
+	latencies := map[string]time.Duration{
+		"10.0.0.1":  30 * time.Millisecond,
+		"10.0.0.2":  10 * time.Millisecond,
+		"10.0.0.10": 20 * time.Millisecond,
+		"10.0.0.3":  20 * time.Millisecond,
+	}
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.10", "10.0.0.3"}
+
+	tests := []struct {
+		name     string
+		k        int
+		expected []string
+	}{
+		{name: "disabled", k: 0, expected: ips},
+		{name: "k exceeds healthy", k: 5, expected: ips},
+		{name: "fastest one", k: 1, expected: []string{"10.0.0.2"}},
+		// 10.0.0.3 and 10.0.0.10 tie; the lower address wins
+		{name: "tie broken by IP", k: 2, expected: []string{"10.0.0.2", "10.0.0.3"}},
+		{name: "configured order kept", k: 3, expected: []string{"10.0.0.2", "10.0.0.10", "10.0.0.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fastestIPs(ips, latencies, tt.k); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_WriteFastest(t *testing.T) {
+	delays := map[string]time.Duration{
+		"10.0.0.1": 150 * time.Millisecond,
+		"10.0.0.2": 0,
+		"10.0.0.3": 75 * time.Millisecond,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		time.Sleep(delays[host])
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		writeFastest:              2,
+		ips:                       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	if v := got.Annotations["new.example.com/target"]; v != "10.0.0.2,10.0.0.3" {
+		t.Errorf("Expected the two fastest IPs, got %q", v)
+	}
+}
@@ -0,0 +1,132 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Forced states accepted by POST /override/{ip}; ForcedAuto lifts an override.
+const (
+	ForcedHealthy   = "healthy"
+	ForcedUnhealthy = "unhealthy"
+	ForcedAuto      = "auto"
+)
+
+// ErrorTypeForced classifies an IP forced unhealthy by an operator.
+const ErrorTypeForced = "forced"
+
+var errForcedUnhealthy = errors.New("forced unhealthy by operator override")
+
+// StateOverride is an operator-forced probe result, reported in Status.
+type StateOverride struct {
+	State string    `json:"state"`
+	Until time.Time `json:"until"`
+}
+
+// forcedStates holds the active operator overrides by IP. Expired ones are
+// dropped on access. The zero value is ready to use.
+type forcedStates struct {
+	now func() time.Time
+
+	mu sync.Mutex
+	m  map[string]StateOverride
+}
+
+func (f *forcedStates) clock() time.Time {
+	if f.now == nil {
+		return time.Now()
+	}
+	return f.now()
+}
+
+// set forces ip into state until ttl has passed; ForcedAuto removes it.
+func (f *forcedStates) set(ip, state string, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if state == ForcedAuto {
+		delete(f.m, ip)
+		return
+	}
+	if f.m == nil {
+		f.m = map[string]StateOverride{}
+	}
+	f.m[ip] = StateOverride{State: state, Until: f.clock().Add(ttl)}
+}
+
+// result reports whether ip has an active override and, if so, its forced
+// probe result: nil when forced healthy, a *ProbeError when forced unhealthy.
+func (f *forcedStates) result(ip string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.m[ip]
+	if !ok {
+		return false, nil
+	}
+	if !f.clock().Before(o.Until) {
+		delete(f.m, ip)
+		return false, nil
+	}
+	if o.State == ForcedUnhealthy {
+		return true, newProbeError(ErrorTypeForced, errForcedUnhealthy)
+	}
+	return true, nil
+}
+
+// active returns a copy of the unexpired overrides, or nil when there are none.
+func (f *forcedStates) active() map[string]StateOverride {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.clock()
+	var out map[string]StateOverride
+	for ip, o := range f.m {
+		if !now.Before(o.Until) {
+			delete(f.m, ip)
+			continue
+		}
+		if out == nil {
+			out = map[string]StateOverride{}
+		}
+		out[ip] = o
+	}
+	return out
+}
+
+// serveOverride forces the probe result of the IP in the path for the ttl
+// query parameter (state=healthy|unhealthy), or lifts it (state=auto), and
+// responds with the resulting Status. The forced result replaces the probe
+// from the next tick on.
+func (r *Runner) serveOverride(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(w, req) {
+		return
+	}
+	ip := req.PathValue("ip")
+	if _, err := netip.ParseAddr(ip); err != nil {
+		http.Error(w, fmt.Sprintf("invalid IP %q", ip), http.StatusBadRequest)
+		return
+	}
+	state := req.URL.Query().Get("state")
+	var ttl time.Duration
+	switch state {
+	case ForcedAuto:
+	case ForcedHealthy, ForcedUnhealthy:
+		var err error
+		if ttl, err = time.ParseDuration(req.URL.Query().Get("ttl")); err != nil || ttl <= 0 {
+			http.Error(w, "ttl must be a positive duration, e.g. 15m", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, fmt.Sprintf("state must be %s, %s or %s", ForcedHealthy, ForcedUnhealthy, ForcedAuto), http.StatusBadRequest)
+		return
+	}
+	r.forced.set(ip, state, ttl)
+	log.FromContext(req.Context()).Info("probe result override set", "ip", ip, "state", state, "ttl", ttl.String(), "remote", req.RemoteAddr)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Status())
+}
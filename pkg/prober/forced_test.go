@@ -0,0 +1,119 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_ForcedStates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	now := time.Unix(1700000000, 0)
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		adminToken:                "s3cret",
+	}
+	runner.forced.now = func() time.Time { return now }
+	handler := runner.StatusHandler()
+	post := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	tickAndGet := func() string {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations["new.example.com/target"]
+	}
+
+	if got := tickAndGet(); got != "10.0.0.1" {
+		t.Fatalf("Expected only the healthy IP, got %q", got)
+	}
+
+	if code := post("/override/10.0.0.2?state=healthy&ttl=10m", "guess"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", code)
+	}
+
+	for path, want := range map[string]int{
+		"/override/10.0.0.2?state=up&ttl=10m":      http.StatusBadRequest,
+		"/override/10.0.0.2?state=healthy":         http.StatusBadRequest,
+		"/override/10.0.0.2?state=healthy&ttl=-1m": http.StatusBadRequest,
+		"/override/web?state=healthy&ttl=10m":      http.StatusBadRequest,
+	} {
+		if code := post(path, "s3cret"); code != want {
+			t.Errorf("POST %s: expected %d, got %d", path, want, code)
+		}
+	}
+
+	if code := post("/override/10.0.0.2?state=healthy&ttl=10m", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := post("/override/10.0.0.1?state=unhealthy&ttl=5m", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if got := tickAndGet(); got != "10.0.0.2" {
+		t.Errorf("Expected the forced states to replace the probe results, got %q", got)
+	}
+	st := runner.Status()
+	if st.Overrides["10.0.0.1"].State != ForcedUnhealthy || st.Overrides["10.0.0.2"] != (StateOverride{State: ForcedHealthy, Until: now.Add(10 * time.Minute)}) {
+		t.Errorf("Expected both overrides in the status, got %+v", st.Overrides)
+	}
+	if st.Errors["10.0.0.1"] != ErrorTypeForced {
+		t.Errorf("Expected the forced failure to be reported as %q, got %+v", ErrorTypeForced, st.Errors)
+	}
+
+	// the unhealthy override expires first
+	now = now.Add(6 * time.Minute)
+	if got := tickAndGet(); got != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected the expired override to be lifted, got %q", got)
+	}
+	if _, ok := runner.Status().Overrides["10.0.0.1"]; ok {
+		t.Error("Expected the expired override to leave the status")
+	}
+
+	if code := post("/override/10.0.0.2?state=auto", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if got := tickAndGet(); got != "10.0.0.1" {
+		t.Errorf("Expected state=auto to restore the probe result, got %q", got)
+	}
+	if st := runner.Status(); st.Overrides != nil {
+		t.Errorf("Expected no active overrides, got %+v", st.Overrides)
+	}
+}
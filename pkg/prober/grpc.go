@@ -0,0 +1,94 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const ProbeModeGRPC = "grpc"
+
+// grpcCredentials uses TLS when the scheme is https and plaintext otherwise,
+// honoring InsecureSkipVerify, the pinned TLS versions and cipher suites, and
+// the Host header as the TLS server name.
+func (r *Runner) grpcCredentials() credentials.TransportCredentials {
+	if strings.ToLower(r.urlScheme) != "https" {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(r.tlsSettings.apply(&tls.Config{
+		InsecureSkipVerify: r.insecureSkipVerify,
+		ServerName:         r.hostHeader,
+	}))
+}
+
+// probeGRPC calls grpc.health.v1.Health/Check on ip; it is healthy when the
+// service is SERVING. Other serving states are reported as http-status errors.
+func (r *Runner) probeGRPC(ctx context.Context, logger logr.Logger, ip string) error {
+	port := r.grpcPort
+	if port == "" {
+		port = portForScheme(r.urlScheme)
+	}
+	addr := r.probeAddress(ip, port)
+	release, err := r.hostLimit.acquire(ctx, addr)
+	if err != nil {
+		return newProbeError(classifyError(err), err)
+	}
+	defer release()
+	logger.Info("probing IP", "ip", ip, "mode", ProbeModeGRPC, "addr", addr, "service", r.grpcService)
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(r.grpcCredentials())}
+	if r.hostHeader != "" {
+		dialOpts = append(dialOpts, grpc.WithAuthority(r.hostHeader))
+	}
+	conn, err := grpc.DialContext(ctx, addr, dialOpts...)
+	if err != nil {
+		typ := classifyError(err)
+		logger.Info("gRPC dial failed", "ip", ip, "addr", addr, "error", err.Error(), "error_type", typ)
+		return newProbeError(typ, err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: r.grpcService})
+	if err != nil {
+		typ := classifyGRPCError(err)
+		logger.Info("gRPC health check failed", "ip", ip, "addr", addr, "error", err.Error(), "error_type", typ)
+		return newProbeError(typ, err)
+	}
+	if resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+		logger.Info("IP marked as healthy", "ip", ip)
+		return nil
+	}
+	logger.Info("IP marked as unhealthy due to gRPC health status", "ip", ip, "status", resp.GetStatus().String(), "error_type", ErrorTypeHTTPStatus)
+	return newProbeError(ErrorTypeHTTPStatus, fmt.Errorf("gRPC health status %s", resp.GetStatus()))
+}
+
+// classifyGRPCError maps gRPC status codes onto the probe error types.
+func classifyGRPCError(err error) string {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return ErrorTypeTimeout
+	case codes.Unavailable:
+		if typ := classifyError(err); typ != ErrorTypeOther {
+			return typ
+		}
+		return ErrorTypeConnect
+	case codes.NotFound, codes.Unimplemented:
+		return ErrorTypeHTTPStatus
+	}
+	return classifyError(err)
+}
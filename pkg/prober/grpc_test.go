@@ -0,0 +1,82 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRunner_HealthyIPs_GRPCMode(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("ready", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("draining", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+
+	tests := []struct {
+		name          string
+		service       string
+		expectHealthy bool
+	}{
+		{name: "server SERVING", service: "", expectHealthy: true},
+		{name: "service SERVING", service: "ready", expectHealthy: true},
+		{name: "service NOT_SERVING", service: "draining", expectHealthy: false},
+		{name: "unknown service", service: "missing", expectHealthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{
+				ips:         []string{"127.0.0.1"},
+				probeMode:   ProbeModeGRPC,
+				urlScheme:   "http",
+				grpcPort:    port,
+				grpcService: tt.service,
+				timeout:     2 * time.Second,
+			}
+
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if tt.expectHealthy && (err != nil || len(healthy) != 1) {
+				t.Errorf("Expected IP to be healthy, got %v (err: %v)", healthy, err)
+			}
+			if !tt.expectHealthy && err == nil {
+				t.Errorf("Expected IP to be unhealthy, got %v", healthy)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_GRPCModeUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	_ = lis.Close()
+
+	runner := &Runner{
+		ips:       []string{"127.0.0.1"},
+		probeMode: ProbeModeGRPC,
+		urlScheme: "http",
+		grpcPort:  port,
+		timeout:   500 * time.Millisecond,
+	}
+
+	if healthy, _, err := runner.HealthyIPs(context.Background()); err == nil {
+		t.Errorf("Expected closed port to be unhealthy, got %v", healthy)
+	}
+}
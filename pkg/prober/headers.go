@@ -0,0 +1,48 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// expectedHeader is a response header an HTTP probe requires.
+type expectedHeader struct {
+	name  string
+	value string
+}
+
+// parseExpectHeaders parses "Name=Value" entries for headers or trailers. Names are canonicalized so
+// matching is case-insensitive; values are compared exactly.
+func parseExpectHeaders(specs []string) ([]expectedHeader, error) {
+	headers := make([]expectedHeader, 0, len(specs))
+	for _, s := range specs {
+		name, value, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid expected header %q (want Name=Value)", s)
+		}
+		headers = append(headers, expectedHeader{name: http.CanonicalHeaderKey(name), value: value})
+	}
+	return headers, nil
+}
+
+// checkHeaders returns an error for the first expected header that h lacks;
+// kind ("header" or "trailer") names it in the error. A header sent several
+// times matches when any of its values does.
+func checkHeaders(h http.Header, expected []expectedHeader, kind string) error {
+	for _, e := range expected {
+		values := h.Values(e.name)
+		found := false
+		for _, v := range values {
+			if v == e.value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s %s is %q, want %q", kind, e.name, strings.Join(values, ","), e.value)
+		}
+	}
+	return nil
+}
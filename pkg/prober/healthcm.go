@@ -0,0 +1,74 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// healthConfigMapKey returns the ConfigMap data key for ip. ConfigMap keys
+// may not contain ':', so IPv6 colons become '-'.
+func healthConfigMapKey(ip string) string {
+	return strings.ReplaceAll(ip, ":", "-")
+}
+
+// healthConfigMapData renders one "healthy|12ms" or "unhealthy|3ms" entry
+// per probed IP. IPs not probed this tick are left out.
+func healthConfigMapData(ips []string, failures map[string]error, latencies map[string]time.Duration) map[string]string {
+	data := make(map[string]string, len(latencies))
+	for _, ip := range ips {
+		latency, probed := latencies[ip]
+		if !probed {
+			continue
+		}
+		status := "healthy"
+		if failures[ip] != nil {
+			status = "unhealthy"
+		}
+		data[healthConfigMapKey(ip)] = fmt.Sprintf("%s|%dms", status, latency.Milliseconds())
+	}
+	return data
+}
+
+// writeHealthConfigMap replaces the health ConfigMap's data with the results
+// of this tick, creating the ConfigMap if needed. The merge patch carries
+// only the entries that changed and is skipped when none did.
+func (r *Runner) writeHealthConfigMap(ctx context.Context, failures map[string]error, latencies map[string]time.Duration) error {
+	data := healthConfigMapData(r.currentIPs(), failures, latencies)
+	cm := &corev1.ConfigMap{}
+	if err := r.k8s.Get(ctx, *r.healthConfigMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.healthConfigMap.Namespace, Name: r.healthConfigMap.Name},
+			Data:       data,
+		}
+		return r.k8s.Create(ctx, cm)
+	}
+	if maps.Equal(cm.Data, data) {
+		return nil
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	cm.Data = data
+	return r.k8s.Patch(ctx, cm, patch)
+}
+
+// recordHealthConfigMap updates the health ConfigMap when one is configured,
+// logging failures; the next tick retries.
+func (r *Runner) recordHealthConfigMap(ctx context.Context, failures map[string]error, latencies map[string]time.Duration) {
+	if r.healthConfigMap == nil || r.k8s == nil {
+		return
+	}
+	if err := r.writeHealthConfigMap(ctx, failures, latencies); err != nil {
+		log.FromContext(ctx).Error(err, "failed to update health ConfigMap", "configmap", r.healthConfigMap.String())
+	}
+}
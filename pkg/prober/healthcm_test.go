@@ -0,0 +1,134 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var testHealthRef = types.NamespacedName{Namespace: "prober", Name: "health"}
+
+func TestRunner_Tick_HealthConfigMap(t *testing.T) {
+	down := map[string]bool{"10.0.0.2": true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); down[host] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	runner := &Runner{
+		k8s:             k8s,
+		annotationKey:   "new.example.com/target",
+		healthConfigMap: &testHealthRef,
+		ips:             []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"},
+		httpClient:      newRoutedHTTPClient(server),
+		urlScheme:       "http",
+		httpPath:        "/",
+		timeout:         time.Second,
+	}
+	statuses := func() map[string]string {
+		t.Helper()
+		cm := &corev1.ConfigMap{}
+		if err := k8s.Get(context.Background(), testHealthRef, cm); err != nil {
+			t.Fatalf("failed to get ConfigMap: %v", err)
+		}
+		// latencies vary; compare the health half of each entry
+		out := map[string]string{}
+		for k, v := range cm.Data {
+			status, _, _ := strings.Cut(v, "|")
+			out[k] = status
+		}
+		return out
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{"10.0.0.1": "healthy", "10.0.0.2": "unhealthy", "2001-db8--1": "healthy"}
+	if got := statuses(); !maps.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// the next tick reflects recovery and drops IPs no longer configured
+	delete(down, "10.0.0.2")
+	runner.ips = []string{"10.0.0.1", "10.0.0.2"}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = map[string]string{"10.0.0.1": "healthy", "10.0.0.2": "healthy"}
+	if got := statuses(); !maps.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// an all-unhealthy tick is recorded too
+	down["10.0.0.1"], down["10.0.0.2"] = true, true
+	if err := runner.tick(context.Background()); !errors.Is(err, errNoHealthyIP) {
+		t.Fatalf("Expected errNoHealthyIP, got %v", err)
+	}
+	want = map[string]string{"10.0.0.1": "unhealthy", "10.0.0.2": "unhealthy"}
+	if got := statuses(); !maps.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRunner_WriteHealthConfigMap_SkipsUnchanged(t *testing.T) {
+	var patches int
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	runner := &Runner{k8s: k8s, healthConfigMap: &testHealthRef, ips: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}
+	failures := map[string]error{"10.0.0.2": errors.New("down")}
+	// 10.0.0.3 was not probed, e.g. with stop-after-healthy
+	latencies := map[string]time.Duration{"10.0.0.1": 12 * time.Millisecond, "10.0.0.2": 3 * time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		if err := runner.writeHealthConfigMap(context.Background(), failures, latencies); err != nil {
+			t.Fatalf("write %d: unexpected error: %v", i, err)
+		}
+	}
+	if patches != 0 {
+		t.Errorf("Expected no patch for unchanged results, got %d", patches)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := k8s.Get(context.Background(), testHealthRef, cm); err != nil {
+		t.Fatalf("failed to get ConfigMap: %v", err)
+	}
+	want := map[string]string{"10.0.0.1": "healthy|12ms", "10.0.0.2": "unhealthy|3ms"}
+	if !maps.Equal(cm.Data, want) {
+		t.Errorf("Expected %v, got %v", want, cm.Data)
+	}
+
+	latencies["10.0.0.1"] = 20 * time.Millisecond
+	if err := runner.writeHealthConfigMap(context.Background(), failures, latencies); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if patches != 1 {
+		t.Errorf("Expected a patch for changed results, got %d", patches)
+	}
+}
+
+func TestOptions_ValidateHealthConfigMap(t *testing.T) {
+	opts := Options{IPs: []string{"10.0.0.1"}, HealthConfigMap: "prober/health"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected a health ConfigMap without a client to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package prober
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// healthExprEnv is the environment a health expression is evaluated in.
+type healthExprEnv struct {
+	Status       int                 `expr:"status"`
+	LatencyMs    float64             `expr:"latencyMs"`
+	BodyContains func(string) bool   `expr:"bodyContains"`
+	Header       func(string) string `expr:"header"`
+}
+
+// healthExpr decides the health of an HTTP probe from its response, e.g.
+// `status == 200 && latencyMs < 250 && bodyContains("ok")`.
+type healthExpr struct {
+	src     string
+	program *vm.Program
+}
+
+// compileHealthExpr compiles src, which must evaluate to a bool. It returns
+// nil for an empty src.
+func compileHealthExpr(src string) (*healthExpr, error) {
+	if src == "" {
+		return nil, nil
+	}
+	program, err := expr.Compile(src, expr.Env(healthExprEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("invalid health expression: %w", err)
+	}
+	return &healthExpr{src: src, program: program}, nil
+}
+
+// eval evaluates the expression against a response. It returns nil when the
+// response is healthy.
+func (e *healthExpr) eval(resp *http.Response, latency time.Duration, body []byte) error {
+	env := healthExprEnv{
+		Status:       resp.StatusCode,
+		LatencyMs:    float64(latency) / float64(time.Millisecond),
+		BodyContains: func(s string) bool { return bytes.Contains(body, []byte(s)) },
+		Header:       resp.Header.Get,
+	}
+	out, err := expr.Run(e.program, env)
+	if err != nil {
+		return fmt.Errorf("evaluating health expression: %w", err)
+	}
+	if ok, _ := out.(bool); !ok {
+		return fmt.Errorf("health expression %q is false for status %d", e.src, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package prober
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOptions_ValidateHealthExpr(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "unset", opts: Options{}},
+		{name: "status", opts: Options{HealthExpr: "status == 200"}},
+		{name: "all variables", opts: Options{HealthExpr: `status < 500 && latencyMs < 100 && bodyContains("ok") && header("X-Role") == "primary"`}},
+		{name: "syntax error", opts: Options{HealthExpr: "status =="}, expectError: true},
+		{name: "unknown variable", opts: Options{HealthExpr: "code == 200"}, expectError: true},
+		{name: "not a bool", opts: Options{HealthExpr: "status + 1"}, expectError: true},
+		{name: "wrong argument type", opts: Options{HealthExpr: "bodyContains(1)"}, expectError: true},
+		{name: "tcp mode", opts: Options{HealthExpr: "status == 200", ProbeMode: ProbeModeTCP, ProbePorts: []string{"80"}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			if err := tt.opts.validate(); tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_HealthExpr(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		delay       time.Duration
+		expr        string
+		expectError bool
+	}{
+		{name: "status matches", status: http.StatusOK, expr: "status == 200"},
+		{name: "non-2xx accepted", status: http.StatusTooManyRequests, expr: "status in [200, 429]"},
+		{name: "2xx rejected", status: http.StatusNoContent, expr: "status == 200", expectError: true},
+		{name: "body contains", status: http.StatusOK, expr: `bodyContains("ready")`},
+		{name: "body lacks", status: http.StatusOK, expr: `bodyContains("draining")`, expectError: true},
+		{name: "header", status: http.StatusOK, expr: `header("x-role") == "primary"`},
+		{name: "latency within budget", status: http.StatusOK, expr: "latencyMs < 5000"},
+		{name: "latency over budget", status: http.StatusOK, delay: 50 * time.Millisecond, expr: "latencyMs < 20", expectError: true},
+		{name: "combined", status: http.StatusOK, expr: `status == 200 && (bodyContains("ready") || header("X-Role") == "standby")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				w.Header().Set("X-Role", "primary")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, `{"state":"ready"}`)
+			}))
+			defer server.Close()
+
+			runner, err := New(Options{
+				IPs:        []string{"10.0.0.1"},
+				HealthExpr: tt.expr,
+				HTTPClient: newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, failures := runner.probeAll(context.Background())
+			err = failures["10.0.0.1"]
+			if tt.expectError != (err != nil) {
+				t.Fatalf("Unexpected error state: %v", err)
+			}
+			if err != nil {
+				if got := classifyProbeError(err); got != ErrorTypeHealthExpr {
+					t.Errorf("Expected error type %q, got %q", ErrorTypeHealthExpr, got)
+				}
+			}
+		})
+	}
+}
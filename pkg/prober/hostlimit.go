@@ -0,0 +1,51 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// hostLimiter bounds simultaneous probes against the same host with one
+// semaphore per host. A nil *hostLimiter does not limit.
+type hostLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// newHostLimiter returns a limiter allowing limit probes per host, or nil when
+// limit is zero.
+func newHostLimiter(limit int) *hostLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &hostLimiter{limit: limit, sems: map[string]chan struct{}{}}
+}
+
+// acquire waits for a probe slot on the host of addr, a host or host:port,
+// and returns the function releasing it.
+func (l *hostLimiter) acquire(ctx context.Context, addr string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	l.mu.Lock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, l.limit)
+		l.sems[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// concurrencyTracker records the peak number of simultaneous calls per key.
+type concurrencyTracker struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	peak     map[string]int
+}
+
+func newConcurrencyTracker() *concurrencyTracker {
+	return &concurrencyTracker{inFlight: map[string]int{}, peak: map[string]int{}}
+}
+
+// hold counts each of keys as in flight for d.
+func (c *concurrencyTracker) hold(d time.Duration, keys ...string) {
+	c.mu.Lock()
+	for _, key := range keys {
+		c.inFlight[key]++
+		c.peak[key] = max(c.peak[key], c.inFlight[key])
+	}
+	c.mu.Unlock()
+	time.Sleep(d)
+	c.mu.Lock()
+	for _, key := range keys {
+		c.inFlight[key]--
+	}
+	c.mu.Unlock()
+}
+
+func (c *concurrencyTracker) peakOf(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peak[key]
+}
+
+func TestRunner_ProbeTCP_PerHostConcurrency(t *testing.T) {
+	tracker := newConcurrencyTracker()
+	runner := &Runner{
+		probeMode:  ProbeModeTCP,
+		probePorts: []string{"81", "82", "83", "84", "85", "86"},
+		timeout:    time.Second,
+		hostLimit:  newHostLimiter(2),
+		dialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, _, _ := net.SplitHostPort(address)
+			tracker.hold(20*time.Millisecond, host)
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		},
+	}
+	if err := runner.probeTCP(context.Background(), logr.Discard(), "10.0.0.1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := tracker.peakOf("10.0.0.1"); got > 2 {
+		t.Errorf("Expected at most 2 simultaneous dials to the same IP, peak was %d", got)
+	}
+}
+
+func TestRunner_ProbeAllTimed_PerHostConcurrencySharedProbeHost(t *testing.T) {
+	tracker := newConcurrencyTracker()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		tracker.hold(20*time.Millisecond, host, "all")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		httpClient:       newRoutedHTTPClient(server),
+		urlScheme:        "http",
+		httpPath:         "/",
+		timeout:          5 * time.Second,
+		probeConcurrency: 6,
+		hostLimit:        newHostLimiter(1),
+	}
+	// three targets probed through the same backend host
+	ts, err := parseTargets([]string{"10.0.0.1@backend", "10.0.0.2@backend", "10.0.0.3@backend", "10.0.0.4", "10.0.0.5", "10.0.0.6"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	runner.setTargets(ts)
+
+	healthy, failures, _, _ := runner.probeAllTimed(context.Background())
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}; !slices.Equal(healthy, want) {
+		t.Fatalf("Expected healthy %v in target order, got %v (failures: %v)", want, healthy, failures)
+	}
+	if got := tracker.peakOf("backend"); got != 1 {
+		t.Errorf("Expected one probe at a time against the shared backend, peak was %d", got)
+	}
+	if got := tracker.peakOf("all"); got < 2 {
+		t.Errorf("Expected other hosts to be probed alongside the backend, peak was %d", got)
+	}
+}
+
+func TestHostLimiter_AcquireCancelled(t *testing.T) {
+	l := newHostLimiter(1)
+	release, err := l.acquire(context.Background(), "10.0.0.1:80")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "10.0.0.1:443"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the second probe on the host to wait until cancelled, got %v", err)
+	}
+	other, err := l.acquire(context.Background(), "10.0.0.2:80")
+	if err != nil {
+		t.Fatalf("Expected another host not to be limited, got %v", err)
+	}
+	other()
+}
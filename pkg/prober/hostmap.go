@@ -0,0 +1,48 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// parseHostMap parses "host=ip" entries into a map from lowercased host name
+// to IP address.
+func parseHostMap(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	hosts := make(map[string]string, len(entries))
+	for _, e := range entries {
+		host, ip, ok := strings.Cut(e, "=")
+		host, ip = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(ip)
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid host map entry %q (want host=ip)", e)
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP in host map entry %q: %w", e, err)
+		}
+		if _, dup := hosts[host]; dup {
+			return nil, fmt.Errorf("host %q is mapped more than once", host)
+		}
+		hosts[host] = addr.String()
+	}
+	return hosts, nil
+}
+
+// mapHosts wraps dial so connections to a mapped host name go to its IP
+// instead, keeping the port. The request keeps the host name, so Host and SNI
+// are unaffected.
+func mapHosts(dial dialContextFunc, hosts map[string]string) dialContextFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(address); err == nil {
+			if ip, ok := hosts[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, address)
+	}
+}
@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptions_HTTPClientHostMap(t *testing.T) {
+	var gotHost, gotSNI string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		gotSNI = hello.ServerName
+		return nil, nil
+	}}
+	server.StartTLS()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	opts := Options{IPs: []string{"10.0.0.1"}, HostMap: []string{"Probe.Example.com=127.0.0.1"}, InsecureSkipVerify: true}
+	if err := opts.validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://probe.example.com:"+port+"/", nil)
+	resp, err := opts.httpClient().Do(req)
+	if err != nil {
+		t.Fatalf("Expected the mapped IP to be dialed, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if gotSNI != "probe.example.com" {
+		t.Errorf("Expected SNI probe.example.com, got %q", gotSNI)
+	}
+	if gotHost != "probe.example.com:"+port {
+		t.Errorf("Expected Host probe.example.com:%s, got %q", port, gotHost)
+	}
+}
+
+func TestOptions_ValidateHostMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		hostMap []string
+		wantErr bool
+	}{
+		{name: "valid", hostMap: []string{"example.com=10.0.0.5", "api.example.com=2001:db8::1"}},
+		{name: "missing ip", hostMap: []string{"example.com"}, wantErr: true},
+		{name: "empty host", hostMap: []string{"=10.0.0.5"}, wantErr: true},
+		{name: "invalid ip", hostMap: []string{"example.com=backend"}, wantErr: true},
+		{name: "duplicate host", hostMap: []string{"example.com=10.0.0.5", "EXAMPLE.com=10.0.0.6"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{IPs: []string{"10.0.0.1"}, HostMap: tt.hostMap}
+			err := opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
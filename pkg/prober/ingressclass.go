@@ -0,0 +1,59 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resolveIngressClasses refreshes the names of the IngressClasses whose
+// spec.controller is ingressController, and the name of the default class.
+func (r *Runner) resolveIngressClasses(ctx context.Context) error {
+	list := &networkingv1.IngressClassList{}
+	if err := r.k8sOp(ctx, "list", func(ctx context.Context) error { return r.k8s.List(ctx, list) }); err != nil {
+		return fmt.Errorf("listing IngressClasses: %w", err)
+	}
+	classes := make(map[string]struct{}, len(list.Items))
+	r.defaultClass = ""
+	for _, ic := range list.Items {
+		if ic.Spec.Controller == r.ingressController {
+			classes[ic.Name] = struct{}{}
+		}
+		if ic.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			r.defaultClass = ic.Name
+		}
+	}
+	r.controllerClasses = classes
+	return nil
+}
+
+// className returns the ingress class obj belongs to: an Ingress's
+// spec.ingressClassName, else the class annotation. With ingressController
+// set, objects without either belong to the default IngressClass.
+func (r *Runner) className(obj client.Object) string {
+	if ing, ok := obj.(*networkingv1.Ingress); ok && ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName
+	}
+	if cls, ok := obj.GetAnnotations()[r.ingressClassAnnotationKey]; ok {
+		return cls
+	}
+	if r.ingressController != "" {
+		return r.defaultClass
+	}
+	return ""
+}
+
+// matchesClass reports whether obj belongs to a targeted class: one of
+// ingressClasses or, with ingressController set, an IngressClass run by that
+// controller.
+func (r *Runner) matchesClass(obj client.Object) bool {
+	if r.ingressController != "" {
+		_, ok := r.controllerClasses[r.className(obj)]
+		return ok
+	}
+	cls := r.className(obj)
+	return cls != "" && slices.Contains(r.ingressClasses, cls)
+}
@@ -0,0 +1,236 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newIngressClass(name, controller string, isDefault bool) *networkingv1.IngressClass {
+	ic := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       networkingv1.IngressClassSpec{Controller: controller},
+	}
+	if isDefault {
+		ic.Annotations = map[string]string{networkingv1.AnnotationIsDefaultIngressClass: "true"}
+	}
+	return ic
+}
+
+func newClassIngress(name, className string) *networkingv1.Ingress {
+	ing := newIngress(name, nil)
+	if className != "" {
+		ing.Spec.IngressClassName = &className
+	}
+	return ing
+}
+
+func TestRunner_Tick_IngressController(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		classes []*networkingv1.IngressClass
+		managed map[string]bool
+	}{
+		{
+			name: "default class run by the controller",
+			classes: []*networkingv1.IngressClass{
+				newIngressClass("nginx-a", "k8s.io/ingress-nginx", false),
+				newIngressClass("nginx-b", "k8s.io/ingress-nginx", true),
+				newIngressClass("haproxy", "haproxy.org/ingress-controller/haproxy", false),
+			},
+			managed: map[string]bool{"by-spec": true, "by-annotation": true, "no-class": true, "other": false, "legacy": false},
+		},
+		{
+			name: "default class run by another controller",
+			classes: []*networkingv1.IngressClass{
+				newIngressClass("nginx-a", "k8s.io/ingress-nginx", false),
+				newIngressClass("nginx-b", "k8s.io/ingress-nginx", false),
+				newIngressClass("haproxy", "haproxy.org/ingress-controller/haproxy", true),
+			},
+			managed: map[string]bool{"by-spec": true, "by-annotation": true, "no-class": false, "other": false, "legacy": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newClassIngress("by-spec", "nginx-a"),
+				newIngress("by-annotation", map[string]string{"kubernetes.io/ingress.class": "nginx-b"}),
+				newClassIngress("no-class", ""),
+				newClassIngress("other", "haproxy"),
+				newIngress("legacy", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			)
+			for _, ic := range tt.classes {
+				builder = builder.WithObjects(ic)
+			}
+			k8s := builder.Build()
+			runner, err := New(Options{
+				Client:            k8s,
+				AnnotationKey:     "new.example.com/target",
+				IPs:               []string{"10.0.0.1"},
+				HTTPClient:        newRoutedHTTPClient(server),
+				IngressController: "k8s.io/ingress-nginx",
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for name, want := range tt.managed {
+				got := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+					t.Fatalf("failed to get Ingress %s: %v", name, err)
+				}
+				if managed := got.Annotations["new.example.com/target"] == "10.0.0.1"; managed != want {
+					t.Errorf("Ingress %s: expected managed=%v, got annotations %v", name, want, got.Annotations)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_SpecMatchedIngressWithoutAnnotations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "ingress class", opts: Options{IngressClass: "nginx-a"}},
+		{name: "ingress controller", opts: Options{IngressController: "k8s.io/ingress-nginx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := newClassIngress("web", "nginx-a")
+			if ing.Annotations != nil {
+				t.Fatalf("Expected the Ingress to start without annotations, got %v", ing.Annotations)
+			}
+			var patches int
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				ing,
+				newClassIngress("other", "haproxy"),
+				newIngressClass("nginx-a", "k8s.io/ingress-nginx", false),
+				newIngressClass("haproxy", "haproxy.org/ingress-controller/haproxy", false),
+			).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patches++
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			opts := tt.opts
+			opts.Client = k8s
+			opts.AnnotationKey = "new.example.com/target"
+			opts.IPs = []string{"10.0.0.1"}
+			opts.HTTPClient = newRoutedHTTPClient(server)
+			runner, err := New(opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := runner.tick(context.Background()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			get := func(name string) map[string]string {
+				t.Helper()
+				got := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+					t.Fatalf("failed to get Ingress %s: %v", name, err)
+				}
+				return got.Annotations
+			}
+			if v := get("web")["new.example.com/target"]; v != "10.0.0.1" {
+				t.Errorf("Expected the spec-matched Ingress to be annotated, got %v", get("web"))
+			}
+			if got := get("other"); got != nil {
+				t.Errorf("Expected an Ingress of another class to be left alone, got %v", got)
+			}
+			if patches != 1 {
+				t.Errorf("Expected a single patch and a no-op second tick, got %d patches", patches)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_SpecClassWinsOverAnnotation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// both modes must resolve the same class when an Ingress carries both
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "ingress class", opts: Options{IngressClass: "nginx-a"}},
+		{name: "ingress controller", opts: Options{IngressController: "k8s.io/ingress-nginx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newClassIngress("spec-nginx", "nginx-a")
+			spec.Annotations = map[string]string{"kubernetes.io/ingress.class": "haproxy"}
+			annotated := newClassIngress("annotated-nginx", "haproxy")
+			annotated.Annotations = map[string]string{"kubernetes.io/ingress.class": "nginx-a"}
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				spec,
+				annotated,
+				newIngressClass("nginx-a", "k8s.io/ingress-nginx", false),
+				newIngressClass("haproxy", "haproxy.org/ingress-controller/haproxy", false),
+			).Build()
+			opts := tt.opts
+			opts.Client = k8s
+			opts.AnnotationKey = "new.example.com/target"
+			opts.IPs = []string{"10.0.0.1"}
+			opts.HTTPClient = newRoutedHTTPClient(server)
+			runner, err := New(opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			get := func(name string) map[string]string {
+				t.Helper()
+				got := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+					t.Fatalf("failed to get Ingress %s: %v", name, err)
+				}
+				return got.Annotations
+			}
+			if v := get("spec-nginx")["new.example.com/target"]; v != "10.0.0.1" {
+				t.Errorf("Expected spec.ingressClassName to select the Ingress, got %v", get("spec-nginx"))
+			}
+			if v, ok := get("annotated-nginx")["new.example.com/target"]; ok {
+				t.Errorf("Expected spec.ingressClassName to override the class annotation, got %q", v)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateIngressController(t *testing.T) {
+	opts := Options{IPs: []string{"10.0.0.1"}, IngressController: "k8s.io/ingress-nginx", TargetResource: TargetResourceService}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected an ingress controller to be rejected for Services")
+	}
+}
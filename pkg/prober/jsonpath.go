@@ -0,0 +1,74 @@
+package prober
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonExpectation checks the value at a dot path in a JSON response body,
+// e.g. "status" = "UP" or "checks.0.state" = "ok". Numeric segments index
+// into arrays.
+type jsonExpectation struct {
+	path  string
+	value string
+}
+
+// newJSONExpectation returns the expectation for path and value, or nil when
+// path is empty.
+func newJSONExpectation(path, value string) (*jsonExpectation, error) {
+	if path == "" {
+		return nil, nil
+	}
+	for _, seg := range strings.Split(path, ".") {
+		if seg == "" {
+			return nil, fmt.Errorf("invalid JSON path %q: empty segment", path)
+		}
+	}
+	return &jsonExpectation{path: path, value: value}, nil
+}
+
+// check parses body and compares the value at the path with the expected
+// value. Strings compare as-is, other values as their JSON text (numbers as
+// written, true, false, null).
+func (e *jsonExpectation) check(body []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	for _, seg := range strings.Split(e.path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[seg]
+			if !ok {
+				return fmt.Errorf("JSON path %q not found", e.path)
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return fmt.Errorf("JSON path %q not found", e.path)
+			}
+			v = node[i]
+		default:
+			return fmt.Errorf("JSON path %q not found", e.path)
+		}
+	}
+
+	got, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		got = string(b)
+	}
+	if got != e.value {
+		return fmt.Errorf("JSON path %q is %q, want %q", e.path, got, e.value)
+	}
+	return nil
+}
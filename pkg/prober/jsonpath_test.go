@@ -0,0 +1,92 @@
+package prober
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOptions_ValidateExpectJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "unset", opts: Options{}},
+		{name: "path and value", opts: Options{ExpectJSONPath: "status", ExpectJSONValue: "UP"}},
+		{name: "nested", opts: Options{ExpectJSONPath: "checks.0.state", ExpectJSONValue: "ok"}},
+		{name: "empty segment", opts: Options{ExpectJSONPath: "checks..state", ExpectJSONValue: "ok"}, expectError: true},
+		{name: "value without path", opts: Options{ExpectJSONValue: "UP"}, expectError: true},
+		{name: "tcp mode", opts: Options{ExpectJSONPath: "status", ExpectJSONValue: "UP", ProbeMode: ProbeModeTCP, ProbePorts: []string{"80"}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			if err := tt.opts.validate(); tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_ExpectJSON(t *testing.T) {
+	const body = `{"status":"UP","version":2,"ready":true,"details":{"db":{"status":"DOWN"}},"checks":[{"state":"ok"},{"state":"degraded"}]}`
+	tests := []struct {
+		name        string
+		body        string
+		status      int
+		path, value string
+		expectError bool
+	}{
+		{name: "top level", body: body, path: "status", value: "UP"},
+		{name: "top level mismatch", body: body, path: "status", value: "DOWN", expectError: true},
+		{name: "nested object", body: body, path: "details.db.status", value: "DOWN"},
+		{name: "nested mismatch", body: body, path: "details.db.status", value: "UP", expectError: true},
+		{name: "array index", body: body, path: "checks.1.state", value: "degraded"},
+		{name: "array index out of range", body: body, path: "checks.2.state", value: "ok", expectError: true},
+		{name: "number", body: body, path: "version", value: "2"},
+		{name: "bool", body: body, path: "ready", value: "true"},
+		{name: "missing path", body: body, path: "details.cache.status", value: "UP", expectError: true},
+		{name: "path through a string", body: body, path: "status.code", value: "UP", expectError: true},
+		{name: "not JSON", body: "UP", path: "status", value: "UP", expectError: true},
+		{name: "too large", body: `{"status":"UP","pad":"` + strings.Repeat("x", maxProbeBodyBytes) + `"}`, path: "status", value: "UP", expectError: true},
+		{name: "non-2xx", body: body, status: http.StatusServiceUnavailable, path: "status", value: "UP", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			runner, err := New(Options{
+				IPs:             []string{"10.0.0.1"},
+				ExpectJSONPath:  tt.path,
+				ExpectJSONValue: tt.value,
+				HTTPClient:      newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, failures := runner.probeAll(context.Background())
+			err = failures["10.0.0.1"]
+			if tt.expectError != (err != nil) {
+				t.Fatalf("Unexpected error state: %v", err)
+			}
+			if err != nil && tt.status == 0 && tt.name != "too large" {
+				if got := classifyProbeError(err); got != ErrorTypeBodyMismatch {
+					t.Errorf("Expected error type %q, got %q", ErrorTypeBodyMismatch, got)
+				}
+			}
+		})
+	}
+}
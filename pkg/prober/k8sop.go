@@ -0,0 +1,52 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// k8sOpRetries bounds the retries of a Kubernetes API call that failed
+// transiently; k8sOpRetryDelay spaces them out.
+const (
+	k8sOpRetries    = 2
+	k8sOpRetryDelay = 100 * time.Millisecond
+)
+
+// k8sOp runs a Kubernetes List or Patch call. With k8sOpTimeout set, each
+// attempt gets its own deadline and an attempt that timed out or failed with
+// a transient API error is retried up to k8sOpRetries times; otherwise op
+// runs once under ctx.
+func (r *Runner) k8sOp(ctx context.Context, name string, op func(context.Context) error) error {
+	if r.k8sOpTimeout <= 0 {
+		return op(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		opCtx, cancel := context.WithTimeout(ctx, r.k8sOpTimeout)
+		err := op(opCtx)
+		cancel()
+		if err == nil || ctx.Err() != nil || attempt >= k8sOpRetries || !isTransientAPIError(err) {
+			return err
+		}
+		log.FromContext(ctx).Info("transient Kubernetes API error; retrying", "operation", name, "attempt", attempt+1, "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(k8sOpRetryDelay):
+		}
+	}
+}
+
+// isTransientAPIError reports whether err is worth retrying: a timeout,
+// throttling or a temporarily unavailable API server.
+func isTransientAPIError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
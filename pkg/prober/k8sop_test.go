@@ -0,0 +1,101 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunner_ListTargets_K8sOpTimeout(t *testing.T) {
+	var calls atomic.Int32
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			calls.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).Build()
+
+	runner := &Runner{k8s: k8s, k8sOpTimeout: 20 * time.Millisecond}
+	start := time.Now()
+	_, err := runner.listTargets(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the List to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the op timeout to bound the List, took %v", elapsed)
+	}
+	if got := calls.Load(); got != k8sOpRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", k8sOpRetries+1, got)
+	}
+}
+
+func TestRunner_Tick_K8sOpRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		failures  int32
+		err       error
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"transient error is retried", time.Second, 1, apierrors.NewTooManyRequests("slow down", 0), 2, false},
+		{"retries are bounded", time.Second, 10, apierrors.NewServiceUnavailable("down"), k8sOpRetries + 1, true},
+		{"permanent error is not retried", time.Second, 1, apierrors.NewForbidden(schema.GroupResource{Resource: "ingresses"}, "web", errors.New("denied")), 1, true},
+		{"no retries without a timeout", 0, 1, apierrors.NewTooManyRequests("slow down", 0), 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if calls.Add(1) <= tt.failures {
+						return tt.err
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			runner, err := New(Options{
+				Client:        k8s,
+				AnnotationKey: "new.example.com/target",
+				IPs:           []string{"10.0.0.1"},
+				HTTPClient:    newRoutedHTTPClient(server),
+				K8sOpTimeout:  tt.timeout,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_ = runner.tick(context.Background())
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("Expected %d patch attempts, got %d", tt.wantCalls, got)
+			}
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if _, ok := got.Annotations["new.example.com/target"]; ok == tt.wantErr {
+				t.Errorf("Expected annotation written to be %v, got %v", !tt.wantErr, got.Annotations)
+			}
+		})
+	}
+}
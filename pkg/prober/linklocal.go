@@ -0,0 +1,39 @@
+package prober
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// validateLinkLocalZone checks the zone name applied to link-local targets.
+func validateLinkLocalZone(zone string) error {
+	if strings.ContainsAny(zone, "% \t[]") {
+		return fmt.Errorf("invalid link-local zone %q", zone)
+	}
+	return nil
+}
+
+// validIP reports whether ip is an IP address, accepting IPv6 zones such as
+// "fe80::1%eth0", which net.ParseIP rejects.
+func validIP(ip string) bool {
+	_, err := netip.ParseAddr(ip)
+	return err == nil
+}
+
+// withZone returns ip with zone attached when it is an IPv6 link-local
+// address written without one. Other addresses, including link-local ones
+// that already carry a zone, are returned unchanged.
+func withZone(ip, zone string) string {
+	if zone == "" {
+		return ip
+	}
+	a, err := netip.ParseAddr(ip)
+	if err != nil || !a.Is6() || a.Zone() != "" {
+		return ip
+	}
+	if !a.IsLinkLocalUnicast() && !a.IsLinkLocalMulticast() {
+		return ip
+	}
+	return a.WithZone(zone).String()
+}
@@ -0,0 +1,136 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dialRecorder records the addresses dialed through it and connects every
+// dial to addr.
+type dialRecorder struct {
+	mu    sync.Mutex
+	addrs []string
+	addr  string
+}
+
+func (d *dialRecorder) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.addrs = append(d.addrs, address)
+	d.mu.Unlock()
+	return (&net.Dialer{}).DialContext(ctx, network, d.addr)
+}
+
+func (d *dialRecorder) dialed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := slices.Clone(d.addrs)
+	slices.Sort(out)
+	return out
+}
+
+func TestRunner_Tick_LinkLocalTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	dials := &dialRecorder{addr: server.Listener.Addr().String()}
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:        k8s,
+		AnnotationKey: "new.example.com/target",
+		IPs:           []string{"fe80::1%eth0", "fe80::2"},
+		LinkLocalZone: "eth1",
+		HTTPClient:    &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{DialContext: dials.dial}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"[fe80::1%eth0]:80", "[fe80::2%eth1]:80"}
+	if got := dials.dialed(); !slices.Equal(got, want) {
+		t.Errorf("Expected dials to %v, got %v", want, got)
+	}
+	got := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	if v := got.Annotations["new.example.com/target"]; v != "fe80::1%eth0,fe80::2" {
+		t.Errorf("Expected the annotation to keep the target zones as written, got %q", v)
+	}
+}
+
+func TestRunner_ProbeTCP_LinkLocalZone(t *testing.T) {
+	var dialed []string
+	runner := &Runner{
+		probeMode:     ProbeModeTCP,
+		probePorts:    []string{"443"},
+		timeout:       time.Second,
+		linkLocalZone: "eth1",
+		dialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		},
+	}
+	for _, ip := range []string{"fe80::1%eth0", "fe80::2", "2001:db8::1"} {
+		if err := runner.probeTCP(context.Background(), logr.Discard(), ip); err != nil {
+			t.Fatalf("Unexpected error for %s: %v", ip, err)
+		}
+	}
+	want := []string{"[fe80::1%eth0]:443", "[fe80::2%eth1]:443", "[2001:db8::1]:443"}
+	if !slices.Equal(dialed, want) {
+		t.Errorf("Expected dials to %v, got %v", want, dialed)
+	}
+}
+
+func TestWithZone(t *testing.T) {
+	tests := []struct {
+		ip, zone, want string
+	}{
+		{"fe80::1", "eth0", "fe80::1%eth0"},
+		{"fe80::1%eth1", "eth0", "fe80::1%eth1"},
+		{"ff02::1", "eth0", "ff02::1%eth0"},
+		{"2001:db8::1", "eth0", "2001:db8::1"},
+		{"10.0.0.1", "eth0", "10.0.0.1"},
+		{"fe80::1", "", "fe80::1"},
+		{"example.com", "eth0", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := withZone(tt.ip, tt.zone); got != tt.want {
+			t.Errorf("withZone(%q, %q) = %q, want %q", tt.ip, tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestLinkLocalTargetParsing(t *testing.T) {
+	if _, err := parseOverrideTargets("fe80::1%eth0, 10.0.0.1"); err != nil {
+		t.Errorf("Expected zoned IPs in the override annotation to be accepted, got %v", err)
+	}
+	prefixes := []netip.Prefix{netip.MustParsePrefix("fe80::/10")}
+	if !allowedIP("fe80::1%eth0", prefixes) {
+		t.Error("Expected a zoned link-local IP to match fe80::/10")
+	}
+	opts := Options{IPs: []string{"fe80::1%eth0"}, LinkLocalZone: "eth0%1"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected an invalid link-local zone to be rejected")
+	}
+}
@@ -0,0 +1,39 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultLivenessStaleFactor is how many probe intervals may pass without a
+// completed tick before LivezCheck fails.
+const DefaultLivenessStaleFactor = 3
+
+// markTickDone records that Start completed a tick (or started, before the
+// first one) and the interval until the next.
+func (r *Runner) markTickDone(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastTickDone = time.Now()
+	r.tickInterval = interval
+}
+
+// LivezCheck is a healthz.Checker that fails once no tick has completed for
+// livenessStaleFactor times the current probe interval, so a wedged loop gets
+// the pod restarted. It succeeds before Start runs and with the factor unset.
+func (r *Runner) LivezCheck(_ *http.Request) error {
+	if r.livenessStaleFactor <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastTickDone.IsZero() {
+		return nil
+	}
+	limit := time.Duration(r.livenessStaleFactor) * r.tickInterval
+	if since := time.Since(r.lastTickDone); since > limit {
+		return fmt.Errorf("no tick completed in %s (limit %s)", since.Round(time.Millisecond), limit)
+	}
+	return nil
+}
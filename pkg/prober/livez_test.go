@@ -0,0 +1,87 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunner_LivezCheck_StalledTick(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// the second tick wedges in List, ignoring its deadline
+	release := make(chan struct{})
+	lists := 0
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if lists++; lists > 1 {
+				<-release
+			}
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+
+	interval := 50 * time.Millisecond
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		interval:                  interval,
+		maxInterval:               interval,
+		livenessStaleFactor:       3,
+	}
+	if err := runner.LivezCheck(nil); err != nil {
+		t.Fatalf("Expected liveness to pass before Start, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = runner.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		close(release)
+		<-done
+	}()
+
+	started := time.Now()
+	deadline := started.Add(5 * time.Second)
+	for runner.LivezCheck(nil) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected liveness to fail once the tick stalled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the first tick completes, the second stalls one interval later
+	if elapsed := time.Since(started); elapsed < 3*interval {
+		t.Errorf("Liveness failed after %s, before the %s threshold", elapsed, 3*interval)
+	}
+}
+
+func TestRunner_LivezCheck_Disabled(t *testing.T) {
+	runner := &Runner{}
+	runner.markTickDone(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if err := runner.LivezCheck(nil); err != nil {
+		t.Errorf("Expected liveness to pass without a stale factor, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package prober
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxMetadataLabels bounds the target labels exported as metric labels.
+const maxMetadataLabels = 8
+
+var metadataLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validateMetadataLabels checks the target label keys exported as metric
+// labels: valid Prometheus label names, other than "ip", without duplicates.
+func validateMetadataLabels(keys []string) error {
+	if len(keys) > maxMetadataLabels {
+		return fmt.Errorf("at most %d metadata labels are allowed, got %d", maxMetadataLabels, len(keys))
+	}
+	seen := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if !metadataLabelPattern.MatchString(k) || k == "ip" || k[0] == '_' {
+			return fmt.Errorf("invalid metadata label %q", k)
+		}
+		if _, ok := seen[k]; ok {
+			return fmt.Errorf("duplicate metadata label %q", k)
+		}
+		seen[k] = struct{}{}
+	}
+	return nil
+}
+
+// metadata returns the metadataLabels of ip's target labels, or nil when it
+// has none of them.
+func (r *Runner) metadata(ip string) map[string]string {
+	r.ipsMu.RLock()
+	defer r.ipsMu.RUnlock()
+	var out map[string]string
+	for _, k := range r.metadataLabels {
+		v, ok := r.labels[ip][k]
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(r.metadataLabels))
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// TargetMetrics returns a collector of per-IP gauges labelled with the IP and
+// its metadata labels: probe_target_healthy and, with a health window,
+// probe_target_success_ratio. Targets without a metadata label export it
+// empty.
+func (r *Runner) TargetMetrics() prometheus.Collector {
+	return targetCollector{r: r}
+}
+
+type targetCollector struct {
+	r *Runner
+}
+
+// Describe sends nothing: the label names follow the configured metadata
+// labels, so the collector is unchecked.
+func (c targetCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c targetCollector) Collect(ch chan<- prometheus.Metric) {
+	names := append([]string{"ip"}, c.r.metadataLabels...)
+	healthyDesc := prometheus.NewDesc("probe_target_healthy", "Whether the IP was healthy in the most recent tick.", names, nil)
+	ratioDesc := prometheus.NewDesc("probe_target_success_ratio", "Share of successful probes of the IP over the health window.", names, nil)
+
+	st := c.r.Status()
+	healthy := make(map[string]bool, len(st.Healthy))
+	for _, ip := range st.Healthy {
+		healthy[ip] = true
+	}
+	for _, ip := range c.r.currentIPs() {
+		meta := c.r.metadata(ip)
+		values := make([]string, 0, len(names))
+		values = append(values, ip)
+		for _, k := range c.r.metadataLabels {
+			values = append(values, meta[k])
+		}
+		v := 0.0
+		if healthy[ip] {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(healthyDesc, prometheus.GaugeValue, v, values...)
+		if ratio, ok := st.SuccessRatio[ip]; ok {
+			ch <- prometheus.MustNewConstMetric(ratioDesc, prometheus.GaugeValue, ratio, values...)
+		}
+	}
+}
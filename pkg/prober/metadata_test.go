@@ -0,0 +1,85 @@
+package prober
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRunner_MetadataLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner, err := New(Options{
+		IPs:            []string{"10.0.0.1;provider=aws;region=eu;zone=eu-west-1a", "10.0.0.2;provider=gcp", "10.0.0.3"},
+		MetadataLabels: []string{"provider", "region"},
+		HTTPClient:     newRoutedHTTPClient(server),
+		HealthWindow:   2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	st := runner.Status()
+	if got := st.Metadata["10.0.0.1"]; !maps.Equal(got, map[string]string{"provider": "aws", "region": "eu"}) {
+		t.Errorf("Expected only the metadata labels in status, got %v", got)
+	}
+	if got := st.Metadata["10.0.0.2"]; !maps.Equal(got, map[string]string{"provider": "gcp"}) {
+		t.Errorf("Unexpected metadata for 10.0.0.2: %v", got)
+	}
+	if _, ok := st.Metadata["10.0.0.3"]; ok {
+		t.Errorf("Expected no metadata for an unlabelled IP, got %v", st.Metadata)
+	}
+
+	want := `
+# HELP probe_target_healthy Whether the IP was healthy in the most recent tick.
+# TYPE probe_target_healthy gauge
+probe_target_healthy{ip="10.0.0.1",provider="aws",region="eu"} 1
+probe_target_healthy{ip="10.0.0.2",provider="gcp",region=""} 0
+probe_target_healthy{ip="10.0.0.3",provider="",region=""} 1
+# HELP probe_target_success_ratio Share of successful probes of the IP over the health window.
+# TYPE probe_target_success_ratio gauge
+probe_target_success_ratio{ip="10.0.0.1",provider="aws",region="eu"} 1
+probe_target_success_ratio{ip="10.0.0.2",provider="gcp",region=""} 0
+probe_target_success_ratio{ip="10.0.0.3",provider="",region=""} 1
+`
+	if err := testutil.CollectAndCompare(runner.TargetMetrics(), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateMetadataLabels(t *testing.T) {
+	tests := []struct {
+		keys    []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"provider", "region", "tier_2"}, false},
+		{[]string{"ip"}, true},
+		{[]string{"__name__"}, true},
+		{[]string{"cloud-provider"}, true},
+		{[]string{"1st"}, true},
+		{[]string{"provider", "provider"}, true},
+		{[]string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}, true},
+	}
+	for _, tt := range tests {
+		if err := validateMetadataLabels(tt.keys); (err != nil) != tt.wantErr {
+			t.Errorf("validateMetadataLabels(%v) error = %v, wantErr %v", tt.keys, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package prober
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	probeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "probe_duration_seconds",
+		Help:    "Duration of individual target probes in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+	probeSuccessRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "probe_success_ratio",
+		Help: "Share of successful probes per IP over the health window.",
+	}, []string{"ip"})
+	probeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "probe_errors_total",
+		Help: "Failed probes per IP by error type.",
+	}, []string{"ip", "type"})
+	patchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "patch_failures_total",
+		Help: "Annotation patches that failed after retries.",
+	})
+	tickDeadlineExceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tick_deadline_exceeded_total",
+		Help: "Ticks cut short because they ran past their deadline.",
+	})
+	tickTimeoutSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tick_timeout_seconds",
+		Help: "Deadline applied to the most recent tick.",
+	})
+	probeTimeoutSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_timeout_seconds",
+		Help: "Configured timeout of a single probe.",
+	})
+	patchBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "patch_breaker_state",
+		Help: "State of the patch circuit breaker: 0 closed, 1 open, 2 half-open.",
+	})
+	secondsSinceLastUpdate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "seconds_since_last_update",
+		Help: "Seconds since the prober last updated the annotations of each managed object.",
+	}, []string{"ingress"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(probeDuration, probeSuccessRatio, probeErrors, patchFailures, tickDeadlineExceeded, tickTimeoutSeconds, probeTimeoutSeconds, patchBreakerState, secondsSinceLastUpdate)
+}
+
+// observeWithExemplar records v and attaches exemplar when obs supports it,
+// falling back to a plain observation otherwise.
+func observeWithExemplar(obs prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if eo, ok := obs.(prometheus.ExemplarObserver); ok {
+		eo.ObserveWithExemplar(v, exemplar)
+		return
+	}
+	obs.Observe(v)
+}
+
+// OpenMetricsHandler serves the controller-runtime registry with OpenMetrics
+// negotiation enabled, which is required for exemplars to be exposed.
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -0,0 +1,56 @@
+package prober
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbeDuration_ExemplarScrapedBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.9.8.7"},
+		httpClient: newRoutedHTTPClient(server),
+		urlScheme:  "http",
+		httpPath:   "/",
+	}
+	if _, _, err := runner.HealthyIPs(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	metrics := httptest.NewServer(OpenMetricsHandler())
+	defer metrics.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, metrics.URL, nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if !strings.Contains(string(body), `# {ip="10.9.8.7"}`) {
+		t.Errorf("Expected probe_duration_seconds exemplar for the probed IP, got:\n%s", body)
+	}
+}
+
+func TestObserveWithExemplar_FallsBackWithoutExemplarSupport(t *testing.T) {
+	var observed []float64
+	obs := prometheus.ObserverFunc(func(v float64) { observed = append(observed, v) })
+
+	observeWithExemplar(obs, 0.25, prometheus.Labels{"ip": "10.0.0.1"})
+
+	if len(observed) != 1 || observed[0] != 0.25 {
+		t.Errorf("Expected plain observation of 0.25, got %v", observed)
+	}
+}
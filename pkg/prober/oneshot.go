@@ -0,0 +1,100 @@
+package prober
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats for one-shot results.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputCSV  = "csv"
+)
+
+// ValidateOutputFormat accepts text, json and csv.
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputText, OutputJSON, OutputCSV:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q (want %s, %s or %s)", format, OutputText, OutputJSON, OutputCSV)
+}
+
+// TargetResult is the outcome of probing a single target in a one-shot run.
+type TargetResult struct {
+	IP      string `json:"ip"`
+	Healthy bool   `json:"healthy"`
+	// LatencyMs is how long the probe took, in milliseconds.
+	LatencyMs float64 `json:"latencyMs"`
+	// ErrorType and Error describe why an unhealthy target failed.
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProbeOnce probes the configured targets once and returns a result per
+// probed target in probe order. Targets skipped because enough healthy ones
+// were found or ctx ended are not included.
+func (r *Runner) ProbeOnce(ctx context.Context) []TargetResult {
+	_, failures, latencies, _ := r.probeAllTimed(ctx)
+	var results []TargetResult
+	for _, ip := range r.currentIPs() {
+		latency, probed := latencies[ip]
+		if !probed {
+			continue
+		}
+		res := TargetResult{IP: ip, Healthy: true, LatencyMs: float64(latency) / float64(time.Millisecond)}
+		if err := failures[ip]; err != nil {
+			res.Healthy = false
+			res.ErrorType = classifyProbeError(err)
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// WriteResults prints one-shot results to w in format: an aligned table
+// (text), a JSON array (json) or comma-separated rows with a header (csv).
+func WriteResults(w io.Writer, format string, results []TargetResult) error {
+	switch format {
+	case OutputText:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "IP\tSTATUS\tLATENCY\tERROR")
+		for _, res := range results {
+			status, errText := "healthy", ""
+			if !res.Healthy {
+				status, errText = "unhealthy", res.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.IP, status, formatLatencyMs(res.LatencyMs)+"ms", errText)
+		}
+		return tw.Flush()
+	case OutputJSON:
+		if results == nil {
+			results = []TargetResult{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"ip", "healthy", "latency_ms", "error_type", "error"})
+		for _, res := range results {
+			_ = cw.Write([]string{res.IP, strconv.FormatBool(res.Healthy), formatLatencyMs(res.LatencyMs), res.ErrorType, res.Error})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return ValidateOutputFormat(format)
+	}
+}
+
+func formatLatencyMs(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 3, 64)
+}
@@ -0,0 +1,129 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var oneShotResults = []TargetResult{
+	{IP: "10.0.0.1", Healthy: true, LatencyMs: 12.5},
+	{IP: "10.0.0.2", LatencyMs: 3, ErrorType: ErrorTypeHTTPStatus, Error: "http-status: unexpected status code 503"},
+}
+
+func TestRunner_ProbeOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner, err := New(Options{IPs: []string{"10.0.0.1", "10.0.0.2"}, HTTPClient: newRoutedHTTPClient(server)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := runner.ProbeOnce(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	if res := results[0]; res.IP != "10.0.0.1" || !res.Healthy || res.Error != "" || res.LatencyMs <= 0 {
+		t.Errorf("Unexpected result for the healthy IP: %+v", res)
+	}
+	if res := results[1]; res.IP != "10.0.0.2" || res.Healthy || res.ErrorType != ErrorTypeHTTPStatus || res.LatencyMs <= 0 {
+		t.Errorf("Unexpected result for the unhealthy IP: %+v", res)
+	}
+}
+
+func TestWriteResults_Text(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputText, oneShotResults); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %q", buf.String())
+	}
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "IP STATUS LATENCY ERROR" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "10.0.0.1 healthy 12.500ms" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+	if !strings.HasPrefix(strings.Join(strings.Fields(lines[2]), " "), "10.0.0.2 unhealthy 3.000ms http-status:") {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+	// columns are aligned
+	if strings.Index(lines[1], "healthy") != strings.Index(lines[0], "STATUS") {
+		t.Errorf("Expected aligned columns, got %q", buf.String())
+	}
+}
+
+func TestWriteResults_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputJSON, oneShotResults); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Expected a JSON array, got %q: %v", buf.String(), err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 results, got %v", got)
+	}
+	if got[0]["ip"] != "10.0.0.1" || got[0]["healthy"] != true || got[0]["latencyMs"] != 12.5 {
+		t.Errorf("Unexpected first result %v", got[0])
+	}
+	if _, ok := got[0]["error"]; ok {
+		t.Errorf("Expected no error field for a healthy IP, got %v", got[0])
+	}
+	if got[1]["healthy"] != false || got[1]["errorType"] != ErrorTypeHTTPStatus {
+		t.Errorf("Unexpected second result %v", got[1])
+	}
+
+	buf.Reset()
+	if err := WriteResults(&buf, OutputJSON, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestWriteResults_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputCSV, oneShotResults); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV: %v", err)
+	}
+	expected := [][]string{
+		{"ip", "healthy", "latency_ms", "error_type", "error"},
+		{"10.0.0.1", "true", "12.500", "", ""},
+		{"10.0.0.2", "false", "3.000", ErrorTypeHTTPStatus, "http-status: unexpected status code 503"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %v", len(expected), records)
+	}
+	for i := range expected {
+		if strings.Join(records[i], "|") != strings.Join(expected[i], "|") {
+			t.Errorf("Record %d: expected %v, got %v", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestWriteResults_UnsupportedFormat(t *testing.T) {
+	if err := WriteResults(&bytes.Buffer{}, "yaml", oneShotResults); err == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
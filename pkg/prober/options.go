@@ -0,0 +1,741 @@
+package prober
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultAnnotationKey             = "external-dns.alpha.kubernetes.io/target"
+	DefaultIngressClassAnnotationKey = "kubernetes.io/ingress.class"
+	DefaultIngressClass              = "public-nginx"
+	DefaultHTTPPath                  = "/"
+	DefaultScheme                    = "http"
+	DefaultInterval                  = 30 * time.Second
+	DefaultMaxInterval               = 5 * time.Minute
+	DefaultTimeout                   = 2 * time.Second
+	DefaultLogSuppressInterval       = 5 * time.Minute
+	DefaultPatchConcurrency          = 1
+	DefaultProbeConcurrency          = 1
+	DefaultPatchBreakerThreshold     = 5
+	DefaultPatchBreakerCooldown      = time.Minute
+	DefaultProbeMethod               = http.MethodGet
+	DefaultProbeContentType          = "application/json"
+
+	// ManagedAnnotationKey marks Ingresses the prober has taken ownership of,
+	// under the default annotation prefix.
+	ManagedAnnotationKey = DefaultAnnotationPrefix + "/" + managedAnnotationName
+)
+
+// Options configures a Runner. Zero values fall back to the package defaults.
+type Options struct {
+	// Client is used to list and patch the target objects. Leave nil for probe-only use.
+	Client client.Client
+	// TargetResource selects the annotated objects: TargetResourceIngress
+	// (default), TargetResourceService, or the Gateway API's
+	// TargetResourceGateway or TargetResourceHTTPRoute, which require the
+	// gateway.networking.k8s.io/v1 types in the client's scheme. Objects other
+	// than Ingresses are matched by the same class annotation.
+	TargetResource string
+
+	IngressClassAnnotationKey string
+	// IngressClass is a comma-separated list of classes; objects of any of
+	// them are managed. An Ingress's class comes from its
+	// spec.ingressClassName or, without one, the class annotation.
+	IngressClass string
+	// IngressController, when set, targets Ingresses of every IngressClass
+	// whose spec.controller matches it instead of matching IngressClass.
+	// Ingresses without a class belong to the default IngressClass. Ingress
+	// target resource only.
+	IngressController string
+
+	AnnotationKey string
+	// AnnotationKeyV6, when set, receives the healthy IPv6 targets while
+	// AnnotationKey receives only the IPv4 ones.
+	AnnotationKeyV6 string
+	// ShadowAnnotationKey always receives the rendered target value, so the
+	// prober's output can be compared with a manually maintained main key.
+	ShadowAnnotationKey string
+	// ShadowOnly writes nothing but the shadow key, leaving the main key,
+	// the record type hint and region keys untouched.
+	ShadowOnly bool
+	// AnnotationPrefix namespaces the prober's own annotations (managed,
+	// targets, probe-path, all-targets). Empty means DefaultAnnotationPrefix.
+	AnnotationPrefix string
+	// AnnotationValueTemplate is a text/template rendered with TemplateData
+	// to produce the annotation value.
+	AnnotationValueTemplate string
+	// RecordType (A, AAAA or CNAME) is written to RecordTypeAnnotationKey
+	// alongside the target annotation. Targets must match its address family.
+	RecordType string
+	// RequireCurrentValue restricts patching to Ingresses whose annotation is
+	// empty or equals this sentinel (e.g. "auto"), plus those already managed.
+	RequireCurrentValue string
+	// CompareAsSet treats annotation values split on TargetSeparator as sets,
+	// so a value another controller merely reordered does not trigger a patch.
+	CompareAsSet bool
+	// TargetSeparator joins the healthy IPs in the annotation value and splits
+	// the current value for CompareAsSet. Empty means DefaultTargetSeparator.
+	TargetSeparator string
+	// WriteTimestampAnnotation also sets the "last-updated" annotation under
+	// AnnotationPrefix to the RFC 3339 time whenever the target value changes.
+	WriteTimestampAnnotation bool
+	// WriteCountAnnotation also sets the "healthy-count" annotation under
+	// AnnotationPrefix to the number of healthy IPs written to each object.
+	WriteCountAnnotation bool
+	// RequireApproval holds every annotation change for an operator: the
+	// desired annotations are written as JSON to the "proposed" annotation
+	// under AnnotationPrefix and applied only once "approve" is set to "true"
+	// next to an unchanged proposal.
+	RequireApproval bool
+	// CleanupOnShutdown removes the annotation from every Ingress managed during
+	// the session when Start returns.
+	CleanupOnShutdown bool
+	// ReadinessGate makes ReadyzCheck fail until a tick completed with a healthy IP.
+	ReadinessGate bool
+	// RegionAnnotationTemplate is a text/template with .Region producing the
+	// annotation key that receives each region's healthy IPs. Targets are
+	// assigned to a region with the "region" label.
+	RegionAnnotationTemplate string
+	// RemoveAnnotationKeys are deleted from managed Ingresses when present.
+	RemoveAnnotationKeys []string
+	// PatchConcurrency bounds how many Ingress patches are in flight at once.
+	PatchConcurrency int
+	// PatchStrategy is PatchStrategyMerge (default) or PatchStrategyApply.
+	// With server-side apply, patched Ingresses also get ManagedAnnotationKey.
+	PatchStrategy string
+	// FieldManager names the prober in managedFields.
+	FieldManager string
+
+	// IPs is the list of targets to probe. Required unless IPsFile or
+	// IPsConfigMap is set.
+	// Entries may carry labels as "IP;key=value[;key=value...]" and a separate
+	// probe address as "IP@PROBE_HOST[:PORT]", in which case PROBE_HOST is
+	// probed while IP is written to annotations. An Ingress can replace the
+	// list for itself with TargetsAnnotationKey.
+	IPs []string
+	// MetadataLabels lists the target label keys (e.g. "provider", "region")
+	// reported per IP in Status and as labels of Runner.TargetMetrics. Keys
+	// must be Prometheus label names; at most maxMetadataLabels are allowed.
+	MetadataLabels []string
+	// DiscoverSelector switches to endpoint discovery: every tick the running
+	// Pods matching this label selector (in DiscoverNamespace, or all
+	// namespaces) become the probe targets. DiscoverWrite selects whether IPs
+	// or the healthy discovered pod IPs are written.
+	DiscoverSelector  string
+	DiscoverNamespace string
+	DiscoverWrite     string
+	// ResolveTargets allows hostnames among the targets; each is replaced by
+	// the addresses it resolves to whenever the targets are loaded.
+	ResolveTargets bool
+	// DNSServer (ip[:port]) answers target resolution instead of the system
+	// resolver.
+	DNSServer string
+	// ProbeMode selects how targets are checked: ProbeModeHTTP (default),
+	// ProbeModeDNS, ProbeModeGRPC or ProbeModeTCP.
+	ProbeMode string
+	// DNS probe settings: each target is queried as a DNS server on DNSPort for
+	// DNSQueryName/DNSRecordType and is healthy when the answer contains DNSExpect.
+	DNSQueryName  string
+	DNSRecordType string
+	DNSExpect     string
+	DNSPort       string
+	// gRPC probe settings: targets are checked with grpc.health.v1.Health/Check
+	// on GRPCPort (defaults to the scheme's port), using TLS when Scheme is https.
+	GRPCPort    string
+	GRPCService string
+	// TCP probe settings: each of ProbePorts (defaults to the scheme's port)
+	// is dialed in parallel; PortsMode is PortsModeAll (default) or PortsModeAny.
+	ProbePorts []string
+	PortsMode  string
+
+	// DisableIPNormalization keeps target addresses exactly as configured
+	// instead of rewriting them to canonical form (e.g. "::ffff:1.2.3.4" to
+	// "1.2.3.4") and dropping the resulting duplicates.
+	DisableIPNormalization bool
+	// AllowDuplicateTargets keeps repeated target addresses, probing and
+	// writing each occurrence. By default only the first one is kept.
+	AllowDuplicateTargets bool
+	// AllowedCIDRs restricts targets to these prefixes; targets outside them
+	// fail configuration (CIDRModeReject, the default) or are skipped with a
+	// warning (CIDRModeSkip). Empty allows every target.
+	AllowedCIDRs []string
+	CIDRMode     string
+
+	// IPsFile holds the target list (comma or newline separated) and replaces
+	// IPs. It is re-read on SIGHUP and on file changes.
+	IPsFile string
+	// IPsConfigMap reads the target list from a ConfigMap key given as
+	// "namespace/name/key", re-read at the start of every tick. It requires
+	// Client and takes precedence over IPs once loaded.
+	IPsConfigMap string
+
+	// Scheme is http, https or a comma-separated list such as "http,https"
+	// probed on each scheme's port and combined according to SchemeMatch:
+	// SchemeMatchAll (default) or SchemeMatchAny. HTTP probe mode only.
+	Scheme      string
+	SchemeMatch string
+	// HTTPPath is the probed path and may carry a query string, e.g.
+	// "/health?verbose=1".
+	HTTPPath string
+	// HTTPPaths, when set, are probed instead of HTTPPath; an IP is healthy
+	// when PathQuorum of them pass, or all of them when PathQuorum is zero.
+	// HTTP probe mode only.
+	HTTPPaths  []string
+	PathQuorum int
+	// NormalizePath gives every probe path a leading slash and applies
+	// TrailingSlash (TrailingSlashKeep, TrailingSlashStrip or TrailingSlashAdd)
+	// before the probe URL is built.
+	NormalizePath bool
+	TrailingSlash string
+	HostHeader    string
+	// HostFromIngress probes each Ingress with its first rule host as the
+	// Host header and writes the healthy set for that host. Ingresses without
+	// a rule host fall back to HostHeader. HTTP probe mode only.
+	HostFromIngress bool
+	// ProbeMethod is the HTTP method used for probes: GET (default), HEAD or POST.
+	ProbeMethod string
+	// ProbeBody is sent with every POST probe; ProbeBodyFile loads it from a file instead.
+	ProbeBody     string
+	ProbeBodyFile string
+	// ProbeContentType is the Content-Type of ProbeBody.
+	ProbeContentType string
+	// ProbeBasicAuthUser enables HTTP basic auth on probes. The password comes
+	// from ProbeBasicAuthPass or is read from ProbeBasicAuthPassFile.
+	ProbeBasicAuthUser     string
+	ProbeBasicAuthPass     string
+	ProbeBasicAuthPassFile string
+	// ProbeBearerTokenFile holds a token sent as "Authorization: Bearer" with
+	// HTTP probes. It is re-read every tick to pick up rotation.
+	ProbeBearerTokenFile string
+	// An HTTP probe is healthy only when every configured criterion passes;
+	// they are checked in this order and the first failure is recorded as
+	// the probe's error type: status (ExpectStatus or HealthExpr), MaxLatency,
+	// ExpectHeaders, ExpectTrailers, ExpectBody and the ExpectJSONPath value.
+	//
+	// ExpectStatus lists the accepted status codes and ranges, e.g.
+	// "200,204,300-399"; empty accepts any 2xx status.
+	ExpectStatus string
+	// MaxLatency fails a response that took longer; zero does not limit.
+	MaxLatency time.Duration
+	// ExpectHeaders ("Name=Value") must all be present in the response for
+	// the IP to be healthy.
+	ExpectHeaders []string
+	// ExpectTrailers ("Name=Value") must all be present in the trailers of
+	// the response; the body is read (up to 1 MiB) to receive them.
+	ExpectTrailers []string
+	// ExpectBody must occur in the response body; the body is read (up to
+	// 1 MiB) when set.
+	ExpectBody string
+	// HealthExpr decides the health of an HTTP probe instead of the 2xx
+	// status rule. It is an expr-lang expression over status, latencyMs,
+	// bodyContains(s) and header(name) that must evaluate to a bool; the body
+	// is read (up to 1 MiB) when set. Expected headers and trailers still apply.
+	HealthExpr string
+	// ExpectJSONPath is a dot path (e.g. "status" or "checks.0.state") into
+	// the JSON response body whose value must equal ExpectJSONValue; the body
+	// is read (up to 1 MiB) when set.
+	ExpectJSONPath  string
+	ExpectJSONValue string
+	// DrainBody reads probe response bodies to EOF (up to 1 MiB) before
+	// closing them so keep-alive connections are reused across ticks;
+	// otherwise bodies are closed unread.
+	DrainBody bool
+	// ProbeStagger spaces out probe starts within a tick.
+	ProbeStagger time.Duration
+	// ProbeConcurrency bounds how many IPs are probed at once within a tick
+	// (default DefaultProbeConcurrency, one at a time).
+	ProbeConcurrency int
+	// PerHostConcurrency bounds simultaneous probes against the same host or
+	// IP, such as the ports of a TCP probe or, with ProbeConcurrency above
+	// one, targets sharing a probe host. Zero does not limit.
+	PerHostConcurrency int
+	// DeadCooldown pauses probing of an IP for this long once it failed
+	// DeadAfter consecutive probes (default DefaultDeadAfter); it stays out
+	// of the healthy set until a probe after the cooldown succeeds. Zero
+	// probes every IP on every tick.
+	DeadCooldown time.Duration
+	DeadAfter    int
+	// StopAfterHealthy stops probing once this many healthy IPs were found; 0 probes all.
+	StopAfterHealthy int
+	// UsePartialResults writes the IPs confirmed healthy before the tick
+	// deadline when probing did not finish; otherwise such a tick leaves the
+	// annotations unchanged.
+	UsePartialResults bool
+	// WriteFastest writes only the K healthy IPs that answered fastest in the
+	// tick, ties broken by IP; 0 writes all healthy IPs.
+	WriteFastest int
+	// SingleTarget writes one healthy IP, chosen by SingleTargetPolicy
+	// (first, fastest, random or round-robin), for providers that accept a
+	// single target only.
+	SingleTarget       bool
+	SingleTargetPolicy string
+	// OrderPolicy orders the written healthy IPs: OrderSorted, OrderShuffle
+	// or OrderLatencyWeightedShuffle, reshuffled every tick. Empty keeps the
+	// configured order.
+	OrderPolicy string
+	// RandSeed seeds the random choices (shuffles, single-target random,
+	// backoff jitter) for reproducible runs; zero uses the global source.
+	RandSeed int64
+	Interval time.Duration
+	// MaxInterval caps the backed-off interval after consecutive failing cycles.
+	// Set it equal to Interval to disable backoff.
+	MaxInterval time.Duration
+	// Timeout bounds each HTTP request.
+	Timeout time.Duration
+	// K8sOpTimeout bounds each Kubernetes List and Patch call on its own; a
+	// call that times out or fails with a transient API error is retried a
+	// few times. Zero leaves the calls bounded by the tick only, without
+	// retries.
+	K8sOpTimeout time.Duration
+	// TickDeadline caps a whole tick; remaining probes are cancelled once it
+	// passes. Zero derives the deadline from Timeout and the number of targets.
+	TickDeadline time.Duration
+	// LogSuppressInterval limits how often a lasting no-healthy-IP condition
+	// is logged after it was first reported.
+	LogSuppressInterval time.Duration
+	// PatchBreakerThreshold consecutive failed patches open the patch circuit
+	// breaker; patching is then skipped for PatchBreakerCooldown before a
+	// single trial patch is let through.
+	PatchBreakerThreshold int
+	PatchBreakerCooldown  time.Duration
+	// AnnotationCooldown is the minimum time between two patches of the same
+	// object; updates due earlier are deferred. The first patch of an object
+	// is never held back. Zero disables the cooldown.
+	AnnotationCooldown time.Duration
+	// FailHealthzOnPatchErrors makes HealthzCheck fail once patches failed in
+	// this many consecutive ticks. Zero disables the check.
+	FailHealthzOnPatchErrors int
+	// LivenessStaleFactor makes LivezCheck fail once no tick has completed
+	// for this many probe intervals. Zero disables the check.
+	LivenessStaleFactor int
+	InsecureSkipVerify  bool
+	// ExpectCertSHA256 pins HTTPS probes to the leaf certificate with this
+	// hex SHA-256 fingerprint; other certificates fail the probe.
+	ExpectCertSHA256 string
+	// TLSMinVersion and TLSMaxVersion ("1.0" to "1.3") bound the TLS versions
+	// HTTPS and gRPC probes negotiate. Empty keeps the crypto/tls defaults.
+	TLSMinVersion string
+	TLSMaxVersion string
+	// TLSCipherSuites restricts TLS 1.2 and earlier to these IANA cipher
+	// suite names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	TLSCipherSuites []string
+	// ProbeSourceIP binds outgoing probe connections to this local address.
+	ProbeSourceIP string
+	// DialTimeout bounds establishing a probe connection, separately from
+	// Timeout, which covers the whole request. Zero leaves dialing bounded by
+	// Timeout only.
+	DialTimeout time.Duration
+	// TCPKeepAlive is the keep-alive period of probe connections; zero uses
+	// the Go default and a negative value disables keep-alives.
+	TCPKeepAlive time.Duration
+	// ProbeSOCKS5 tunnels HTTP and TCP probes through this SOCKS5 proxy, given
+	// as host:port or socks5://[user:password@]host:port.
+	ProbeSOCKS5 string
+	// HostMap statically resolves host names in probe URLs, as "host=ip"
+	// entries, so a probe carrying the real Host and SNI dials a chosen IP.
+	HostMap []string
+	// LinkLocalZone is the interface zone (e.g. "eth0") used to dial IPv6
+	// link-local targets listed without one. Targets written with a zone,
+	// such as "fe80::1%eth0", keep it, including in the annotation.
+	LinkLocalZone string
+	// DisableRedirects evaluates the original 3xx response instead of following it.
+	DisableRedirects bool
+	// RedirectToHTTPSHealthy counts a 301 or 302 from an http probe to an
+	// https Location as healthy, whatever the expected status. HTTP probe
+	// mode only.
+	RedirectToHTTPSHealthy bool
+
+	// TracerProvider receives a span per tick with a child span per IP
+	// probe; nil disables tracing.
+	TracerProvider trace.TracerProvider
+
+	// UpdateWindow restricts annotation updates to recurring UTC time ranges,
+	// a comma-separated list of "[DAY[-DAY] ]HH:MM-HH:MM" such as
+	// "Mon-Fri 09:00-17:00". Outside them probing continues and the latest
+	// desired targets are written on the first tick inside a window. Empty
+	// allows updates at any time.
+	UpdateWindow string
+
+	// HealthWindow is the number of ticks the per-IP success ratio is computed over.
+	HealthWindow int
+	// StateConfigMap ("namespace/name") persists the last healthy set, the
+	// health windows and the backoff state across restarts. It requires Client.
+	StateConfigMap string
+	// HealthConfigMap ("namespace/name") is updated every tick with one
+	// "healthy|12ms" or "unhealthy|12ms" entry per probed IP, keyed by IP
+	// with IPv6 colons replaced by '-'. It requires Client.
+	HealthConfigMap string
+
+	// AdminToken, when set, is required as a bearer token by the admin
+	// endpoints (POST /probe, /pause and /resume).
+	AdminToken string
+	// StartPaused starts with annotation updates paused until POST /resume.
+	StartPaused bool
+	// UnhealthyMode decides what a tick does when no target is healthy:
+	// UnhealthyModeKeep (default) leaves the annotations, UnhealthyModeRemove
+	// deletes them and UnhealthyModeFallback writes FallbackTargets. The
+	// latter two only act once the healthy set has been empty for
+	// ClearGracePeriod, so brief total outages are ridden out.
+	UnhealthyMode    string
+	FallbackTargets  []string
+	ClearGracePeriod time.Duration
+	// SkipInitialTick waits for the first interval instead of probing right
+	// away in Start, so pods rolled together don't probe in lockstep.
+	SkipInitialTick bool
+
+	// AuditLogFile, when set, gets a JSON line (AuditRecord) appended for
+	// every annotation changed by a successful patch.
+	AuditLogFile string
+	// WebhookURL receives a POST with a WebhookPayload whenever the healthy set changes.
+	WebhookURL     string
+	WebhookTimeout time.Duration
+	// PublishURL receives a PUT with a PublishPayload carrying the healthy
+	// set on every tick that finds a healthy IP, sent with PublishHeaders
+	// ("Name=Value", e.g. an Authorization header). With PublishOnly the
+	// healthy set is only published and no annotation is written.
+	PublishURL     string
+	PublishHeaders []string
+	PublishOnly    bool
+
+	// HTTPClient overrides the client built from Timeout and InsecureSkipVerify.
+	HTTPClient *http.Client
+}
+
+func (o *Options) setDefaults() {
+	if o.AnnotationKey == "" {
+		o.AnnotationKey = DefaultAnnotationKey
+	}
+	if o.AnnotationValueTemplate == "" {
+		o.AnnotationValueTemplate = DefaultAnnotationValueTemplate
+	}
+	if o.TargetSeparator == "" {
+		o.TargetSeparator = DefaultTargetSeparator
+	}
+	if o.DiscoverSelector != "" && o.DiscoverWrite == "" {
+		o.DiscoverWrite = DiscoverWriteIPs
+	}
+	if o.TargetResource == "" {
+		o.TargetResource = TargetResourceIngress
+	}
+	if o.IngressClassAnnotationKey == "" {
+		o.IngressClassAnnotationKey = DefaultIngressClassAnnotationKey
+	}
+	if o.IngressClass == "" {
+		o.IngressClass = DefaultIngressClass
+	}
+	if o.HTTPPath == "" {
+		o.HTTPPath = DefaultHTTPPath
+	}
+	if o.ProbeMode == "" {
+		o.ProbeMode = ProbeModeHTTP
+	}
+	if o.CIDRMode == "" {
+		o.CIDRMode = CIDRModeReject
+	}
+	if o.PortsMode == "" {
+		o.PortsMode = PortsModeAll
+	}
+	if o.DNSRecordType == "" {
+		o.DNSRecordType = DefaultDNSRecordType
+	}
+	if o.DNSPort == "" {
+		o.DNSPort = DefaultDNSPort
+	}
+	if o.PatchStrategy == "" {
+		o.PatchStrategy = PatchStrategyMerge
+	}
+	if o.FieldManager == "" {
+		o.FieldManager = DefaultFieldManager
+	}
+	if o.PatchConcurrency <= 0 {
+		o.PatchConcurrency = DefaultPatchConcurrency
+	}
+	if o.ProbeConcurrency <= 0 {
+		o.ProbeConcurrency = DefaultProbeConcurrency
+	}
+	if o.ProbeMethod == "" {
+		o.ProbeMethod = DefaultProbeMethod
+	}
+	if o.ProbeContentType == "" {
+		o.ProbeContentType = DefaultProbeContentType
+	}
+	if o.Scheme == "" {
+		o.Scheme = DefaultScheme
+	}
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = DefaultMaxInterval
+	}
+	if o.MaxInterval < o.Interval {
+		o.MaxInterval = o.Interval
+	}
+	if o.PatchBreakerThreshold <= 0 {
+		o.PatchBreakerThreshold = DefaultPatchBreakerThreshold
+	}
+	if o.PatchBreakerCooldown <= 0 {
+		o.PatchBreakerCooldown = DefaultPatchBreakerCooldown
+	}
+	if o.SingleTargetPolicy == "" {
+		o.SingleTargetPolicy = SingleTargetFirst
+	}
+	if o.LogSuppressInterval <= 0 {
+		o.LogSuppressInterval = DefaultLogSuppressInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.HealthWindow <= 0 {
+		o.HealthWindow = DefaultHealthWindow
+	}
+	if o.WebhookTimeout <= 0 {
+		o.WebhookTimeout = DefaultWebhookTimeout
+	}
+}
+
+func (o *Options) validate() error {
+	if len(o.IPs) == 0 && o.IPsFile == "" && o.IPsConfigMap == "" && o.DiscoverSelector == "" {
+		return fmt.Errorf("at least one IP is required")
+	}
+	if err := o.validateDiscovery(); err != nil {
+		return err
+	}
+	if o.IPsConfigMap != "" && o.Client == nil {
+		return fmt.Errorf("an IPs ConfigMap requires a Kubernetes client")
+	}
+	if o.StateConfigMap != "" && o.Client == nil {
+		return fmt.Errorf("a state ConfigMap requires a Kubernetes client")
+	}
+	if o.HealthConfigMap != "" && o.Client == nil {
+		return fmt.Errorf("a health ConfigMap requires a Kubernetes client")
+	}
+	if o.HostFromIngress && o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+		return fmt.Errorf("host from ingress requires the http probe mode")
+	}
+	switch o.ProbeMode {
+	case "", ProbeModeHTTP, ProbeModeGRPC:
+	case ProbeModeTCP:
+		if err := validateProbePorts(o.ProbePorts); err != nil {
+			return err
+		}
+		switch o.PortsMode {
+		case "", PortsModeAll, PortsModeAny:
+		default:
+			return fmt.Errorf("unsupported ports mode %q (want %s or %s)", o.PortsMode, PortsModeAll, PortsModeAny)
+		}
+	case ProbeModeDNS:
+		if o.DNSQueryName == "" || o.DNSExpect == "" {
+			return fmt.Errorf("dns probe mode requires a query name and an expected value")
+		}
+		if err := validateDNSRecordType(o.DNSRecordType); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported probe mode %q", o.ProbeMode)
+	}
+	if err := o.validateSchemes(); err != nil {
+		return err
+	}
+	if o.HTTPPath != "" {
+		if _, err := parseProbePath(o.probePath(o.HTTPPath)); err != nil {
+			return err
+		}
+	}
+	if err := o.validatePaths(); err != nil {
+		return err
+	}
+	if err := o.validatePathNormalization(); err != nil {
+		return err
+	}
+	if err := validateRecordType(o.RecordType); err != nil {
+		return err
+	}
+	if err := validateAnnotationPrefix(o.AnnotationPrefix); err != nil {
+		return err
+	}
+	if err := o.validateDualStack(); err != nil {
+		return err
+	}
+	switch o.CIDRMode {
+	case "", CIDRModeReject, CIDRModeSkip:
+	default:
+		return fmt.Errorf("unsupported CIDR mode %q (want %s or %s)", o.CIDRMode, CIDRModeReject, CIDRModeSkip)
+	}
+	if err := validateTargetResource(o.TargetResource); err != nil {
+		return err
+	}
+	if o.IngressController != "" && o.TargetResource != "" && o.TargetResource != TargetResourceIngress {
+		return fmt.Errorf("an ingress controller requires the %s target resource", TargetResourceIngress)
+	}
+	if err := validatePatchStrategy(o.PatchStrategy); err != nil {
+		return err
+	}
+	switch o.ProbeMethod {
+	case "", http.MethodGet, http.MethodHead:
+		if o.ProbeBody != "" || o.ProbeBodyFile != "" {
+			return fmt.Errorf("a probe body requires the POST probe method")
+		}
+	case http.MethodPost:
+	default:
+		return fmt.Errorf("unsupported probe method %q", o.ProbeMethod)
+	}
+	if o.ProbeBody != "" && o.ProbeBodyFile != "" {
+		return fmt.Errorf("probe body and probe body file are mutually exclusive")
+	}
+	if o.ProbeBasicAuthUser == "" && (o.ProbeBasicAuthPass != "" || o.ProbeBasicAuthPassFile != "") {
+		return fmt.Errorf("a basic auth password requires a basic auth user")
+	}
+	if o.ProbeBasicAuthPass != "" && o.ProbeBasicAuthPassFile != "" {
+		return fmt.Errorf("basic auth password and password file are mutually exclusive")
+	}
+	if o.ProbeBearerTokenFile != "" && o.ProbeBasicAuthUser != "" {
+		return fmt.Errorf("basic auth and a bearer token are mutually exclusive")
+	}
+	if err := o.validateCriteria(); err != nil {
+		return err
+	}
+	if _, err := parseExpectHeaders(o.ExpectHeaders); err != nil {
+		return err
+	}
+	if _, err := parseExpectHeaders(o.ExpectTrailers); err != nil {
+		return err
+	}
+	if o.RedirectToHTTPSHealthy && o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+		return fmt.Errorf("treating HTTPS redirects as healthy requires the http probe mode")
+	}
+	if o.HealthExpr != "" {
+		if o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+			return fmt.Errorf("a health expression requires the http probe mode")
+		}
+		if _, err := compileHealthExpr(o.HealthExpr); err != nil {
+			return err
+		}
+	}
+	if o.ExpectJSONPath != "" {
+		if o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+			return fmt.Errorf("an expected JSON path requires the http probe mode")
+		}
+		if _, err := newJSONExpectation(o.ExpectJSONPath, o.ExpectJSONValue); err != nil {
+			return err
+		}
+	} else if o.ExpectJSONValue != "" {
+		return fmt.Errorf("an expected JSON value requires an expected JSON path")
+	}
+	if o.ExpectCertSHA256 != "" {
+		if _, err := parseCertFingerprint(o.ExpectCertSHA256); err != nil {
+			return err
+		}
+	}
+	if _, err := o.tlsSettings(); err != nil {
+		return err
+	}
+	if o.PerHostConcurrency < 0 {
+		return fmt.Errorf("per-host concurrency must not be negative")
+	}
+	if o.DeadCooldown < 0 {
+		return fmt.Errorf("dead cooldown must not be negative")
+	}
+	if o.DeadAfter < 0 {
+		return fmt.Errorf("dead-after must not be negative")
+	}
+	if o.K8sOpTimeout < 0 {
+		return fmt.Errorf("kubernetes operation timeout must not be negative")
+	}
+	if o.DialTimeout < 0 {
+		return fmt.Errorf("dial timeout must not be negative")
+	}
+	if o.ProbeSourceIP != "" && net.ParseIP(o.ProbeSourceIP) == nil {
+		return fmt.Errorf("invalid probe source IP %q", o.ProbeSourceIP)
+	}
+	if o.DNSServer != "" {
+		if !o.ResolveTargets {
+			return fmt.Errorf("DNS server requires target resolution to be enabled")
+		}
+		if _, err := dnsServerAddress(o.DNSServer); err != nil {
+			return err
+		}
+	}
+	if o.FailHealthzOnPatchErrors < 0 {
+		return fmt.Errorf("fail-healthz-on-patch-errors must not be negative")
+	}
+	if o.LivenessStaleFactor < 0 {
+		return fmt.Errorf("liveness stale factor must not be negative")
+	}
+	if o.AnnotationCooldown < 0 {
+		return fmt.Errorf("annotation cooldown must not be negative")
+	}
+	if o.WriteFastest < 0 {
+		return fmt.Errorf("write-fastest must not be negative")
+	}
+	if err := validateSingleTargetPolicy(o.SingleTargetPolicy); err != nil {
+		return err
+	}
+	if err := validateOrderPolicy(o.OrderPolicy); err != nil {
+		return err
+	}
+	if err := o.validateUnhealthyMode(); err != nil {
+		return err
+	}
+	if o.ProbeSOCKS5 != "" {
+		if _, err := parseSOCKS5URL(o.ProbeSOCKS5); err != nil {
+			return err
+		}
+	}
+	if _, err := parseHostMap(o.HostMap); err != nil {
+		return err
+	}
+	if err := validateTargetSeparator(o.TargetSeparator); err != nil {
+		return err
+	}
+	if err := validateLinkLocalZone(o.LinkLocalZone); err != nil {
+		return err
+	}
+	if err := o.validateShadow(); err != nil {
+		return err
+	}
+	if err := o.validatePublish(); err != nil {
+		return err
+	}
+	if err := validateMetadataLabels(o.MetadataLabels); err != nil {
+		return err
+	}
+	if _, err := newUpdateWindow(o.UpdateWindow); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *Options) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	// validate has already checked the TLS versions and cipher suites
+	settings, _ := o.tlsSettings()
+	tr := &http.Transport{
+		DialContext:     o.dialContext(),
+		TLSClientConfig: settings.apply(&tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}),
+	}
+	if o.ExpectCertSHA256 != "" {
+		// validate has already checked the fingerprint
+		pin, _ := parseCertFingerprint(o.ExpectCertSHA256)
+		tr.TLSClientConfig.VerifyConnection = verifyCertPin(pin)
+	}
+	c := &http.Client{
+		Transport: tr,
+		Timeout:   o.Timeout,
+	}
+	if o.DisableRedirects {
+		c.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return c
+}
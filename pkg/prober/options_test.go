@@ -0,0 +1,104 @@
+package prober
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOptions_ValidateProbeSourceIP(t *testing.T) {
+	tests := []struct {
+		sourceIP    string
+		expectError bool
+	}{
+		{"", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"not-an-ip", true},
+		{"10.0.0.1:80", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sourceIP, func(t *testing.T) {
+			opts := Options{IPs: []string{"10.0.0.1"}, ProbeSourceIP: tt.sourceIP}
+			err := opts.validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for %q, got none", tt.sourceIP)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error for %q: %v", tt.sourceIP, err)
+			}
+		})
+	}
+}
+
+func TestOptions_HTTPClientBindsProbeSourceIP(t *testing.T) {
+	remote := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote <- r.RemoteAddr
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// any 127.0.0.0/8 address is routable on loopback
+	opts := Options{Timeout: time.Second, ProbeSourceIP: "127.0.0.2"}
+	resp, err := opts.httpClient().Get(server.URL)
+	if err != nil {
+		t.Skipf("loopback source binding not available: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	host, _, _ := net.SplitHostPort(<-remote)
+	if host != "127.0.0.2" {
+		t.Errorf("Expected probe to originate from 127.0.0.2, got %s", host)
+	}
+}
+
+func TestOptions_HTTPClientDialTimeout(t *testing.T) {
+	// 100::/64 is a discard prefix (RFC 6666): SYNs go unanswered
+	const blackhole = "http://[100::1]:80/"
+	opts := Options{Timeout: 10 * time.Second, DialTimeout: 200 * time.Millisecond}
+	started := time.Now()
+	resp, err := opts.httpClient().Get(blackhole)
+	elapsed := time.Since(started)
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Skip("blackhole address unexpectedly reachable")
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Skipf("blackhole address not routable here: %v", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected the dial to give up after the 200ms dial timeout, took %s", elapsed)
+	}
+}
+
+func TestOptions_ValidateProbeMethod(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "default", opts: Options{}},
+		{name: "head", opts: Options{ProbeMethod: http.MethodHead}},
+		{name: "post with body", opts: Options{ProbeMethod: http.MethodPost, ProbeBody: `{}`}},
+		{name: "post with body file", opts: Options{ProbeMethod: http.MethodPost, ProbeBodyFile: "body.json"}},
+		{name: "get with body", opts: Options{ProbeMethod: http.MethodGet, ProbeBody: `{}`}, expectError: true},
+		{name: "body and body file", opts: Options{ProbeMethod: http.MethodPost, ProbeBody: `{}`, ProbeBodyFile: "body.json"}, expectError: true},
+		{name: "unsupported", opts: Options{ProbeMethod: http.MethodDelete}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
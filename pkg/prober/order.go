@@ -0,0 +1,103 @@
+package prober
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Annotation order policies. Without one the healthy IPs keep their
+// configured order.
+const (
+	OrderSorted                 = "sorted"
+	OrderShuffle                = "shuffle"
+	OrderLatencyWeightedShuffle = "latency-weighted-shuffle"
+)
+
+// validateOrderPolicy accepts "" (configured order) and the known policies.
+func validateOrderPolicy(p string) error {
+	switch p {
+	case "", OrderSorted, OrderShuffle, OrderLatencyWeightedShuffle:
+		return nil
+	}
+	return fmt.Errorf("unsupported order policy %q (want %s, %s or %s)", p, OrderSorted, OrderShuffle, OrderLatencyWeightedShuffle)
+}
+
+// seededInt63n returns a rand.Int63n replacement drawing from a source seeded
+// with seed, safe for concurrent use.
+func seededInt63n(seed int64) func(int64) int64 {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return func(n int64) int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Int63n(n)
+	}
+}
+
+// orderIPs returns ips in the order the order policy writes them. The
+// weighted shuffle draws faster IPs to the front more often: each pick is
+// weighted by the inverse of the IP's latency this tick, and IPs without a
+// measured latency weigh as much as the slowest measured one.
+func (r *Runner) orderIPs(ips []string, latencies map[string]time.Duration) []string {
+	switch r.orderPolicy {
+	case OrderSorted:
+		ordered := slices.Clone(ips)
+		slices.SortFunc(ordered, compareIPs)
+		return ordered
+	case OrderShuffle:
+		ordered := slices.Clone(ips)
+		for i := len(ordered) - 1; i > 0; i-- {
+			j := r.randInt63n(int64(i + 1))
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+		return ordered
+	case OrderLatencyWeightedShuffle:
+		return r.weightedShuffle(ips, latencies)
+	}
+	return ips
+}
+
+// weightedShuffle draws ips without replacement, each with a weight
+// inversely proportional to its latency.
+func (r *Runner) weightedShuffle(ips []string, latencies map[string]time.Duration) []string {
+	var slowest time.Duration
+	for _, ip := range ips {
+		if l := latencies[ip]; l > slowest {
+			slowest = l
+		}
+	}
+	remaining := slices.Clone(ips)
+	weights := make([]int64, len(remaining))
+	for i, ip := range remaining {
+		l := latencies[ip]
+		if l <= 0 {
+			l = slowest
+		}
+		// integer weights keep the draw on randInt63n; an hour over a
+		// nanosecond latency still fits comfortably in an int64 sum
+		weights[i] = 1
+		if l > 0 && l < time.Hour {
+			weights[i] = int64(time.Hour / l)
+		}
+	}
+
+	ordered := make([]string, 0, len(ips))
+	for len(remaining) > 0 {
+		var total int64
+		for _, w := range weights {
+			total += w
+		}
+		pick := r.randInt63n(total)
+		i := 0
+		for ; pick >= weights[i]; i++ {
+			pick -= weights[i]
+		}
+		ordered = append(ordered, remaining[i])
+		remaining = slices.Delete(remaining, i, i+1)
+		weights = slices.Delete(weights, i, i+1)
+	}
+	return ordered
+}
@@ -0,0 +1,71 @@
+package prober
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunner_OrderIPs(t *testing.T) {
+	ips := []string{"10.0.0.10", "10.0.0.2", "10.0.0.1", "10.0.0.3"}
+	latencies := map[string]time.Duration{"10.0.0.10": 40 * time.Millisecond, "10.0.0.2": 20 * time.Millisecond, "10.0.0.1": 5 * time.Millisecond}
+
+	tests := []struct {
+		policy   string
+		expected [][]string
+	}{
+		{policy: "", expected: [][]string{ips, ips}},
+		{policy: OrderSorted, expected: [][]string{
+			{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.10"},
+			{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.10"},
+		}},
+		{policy: OrderShuffle, expected: [][]string{
+			{"10.0.0.1", "10.0.0.10", "10.0.0.2", "10.0.0.3"},
+			{"10.0.0.10", "10.0.0.3", "10.0.0.1", "10.0.0.2"},
+		}},
+		{policy: OrderLatencyWeightedShuffle, expected: [][]string{
+			{"10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.10"},
+			{"10.0.0.2", "10.0.0.1", "10.0.0.3", "10.0.0.10"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			runner := &Runner{orderPolicy: tt.policy, randInt63n: seededInt63n(42)}
+			for i, want := range tt.expected {
+				if got := runner.orderIPs(ips, latencies); !reflect.DeepEqual(got, want) {
+					t.Errorf("Tick %d: expected %v, got %v", i, want, got)
+				}
+			}
+			if ips[0] != "10.0.0.10" || ips[3] != "10.0.0.3" {
+				t.Errorf("Expected the input to be left untouched, got %v", ips)
+			}
+		})
+	}
+}
+
+func TestRunner_OrderIPs_LatencyWeighted(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2"}
+	latencies := map[string]time.Duration{"10.0.0.1": 30 * time.Millisecond, "10.0.0.2": 10 * time.Millisecond}
+	runner := &Runner{orderPolicy: OrderLatencyWeightedShuffle, randInt63n: seededInt63n(1)}
+
+	first := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		first[runner.orderIPs(ips, latencies)[0]]++
+	}
+	// weights 1/30 and 1/10 put the faster IP first three times out of four
+	if got := first["10.0.0.2"]; got < 2800 || got > 3200 {
+		t.Errorf("Expected the faster IP first in about 3000 of 4000 ticks, got %d", got)
+	}
+}
+
+func TestValidateOrderPolicy(t *testing.T) {
+	for _, p := range []string{"", OrderSorted, OrderShuffle, OrderLatencyWeightedShuffle} {
+		if err := validateOrderPolicy(p); err != nil {
+			t.Errorf("Unexpected error for %q: %v", p, err)
+		}
+	}
+	if err := validateOrderPolicy("reverse"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,206 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// TargetsAnnotationKey lists the IPs to probe and write for a single Ingress,
+// overriding the global target list. Like the keys below it moves with
+// Options.AnnotationPrefix; the constant holds the default.
+const TargetsAnnotationKey = DefaultAnnotationPrefix + "/" + targetsAnnotationName
+
+// ProbePathAnnotationKey sets the HTTP path an Ingress's targets are probed
+// on, overriding the global path for that Ingress.
+const ProbePathAnnotationKey = DefaultAnnotationPrefix + "/" + probePathAnnotationName
+
+// AllTargetsAnnotationKey set to "true" writes every configured target to an
+// Ingress regardless of health, e.g. for a monitoring endpoint.
+const AllTargetsAnnotationKey = DefaultAnnotationPrefix + "/" + allTargetsAnnotationName
+
+// probeKey identifies a probe result cached within a tick.
+type probeKey struct {
+	// path is empty for the configured probe paths
+	ip, path, host string
+}
+
+// healthySets resolves the healthy IPs for each Ingress within one tick.
+// Probes needed by per-Ingress overrides run at most once per IP and path per
+// tick, and override lists are parsed once per distinct annotation value.
+type healthySets struct {
+	r      *Runner
+	global []string
+
+	mu     sync.Mutex
+	parsed map[string][]string
+	probed map[probeKey]bool
+}
+
+func (r *Runner) newHealthySets(global []string) *healthySets {
+	return &healthySets{r: r, global: global, parsed: map[string][]string{}, probed: map[probeKey]bool{}}
+}
+
+// forObject returns the healthy IPs to write to obj: every target when obj
+// asks for all of them (AllTargetsAnnotationKey), the global healthy set,
+// or, when obj overrides the targets (TargetsAnnotationKey) or the probe path
+// (ProbePathAnnotationKey, HTTP mode only), or is probed with its own rule
+// host (-host-from-ingress), the healthy subset probed for it. Except for
+// all targets, the result is narrowed to obj's zone (see sameZone).
+func (h *healthySets) forObject(ctx context.Context, obj client.Object) ([]string, error) {
+	value, hasTargets := obj.GetAnnotations()[h.r.proberKey(targetsAnnotationName)]
+	if obj.GetAnnotations()[h.r.proberKey(allTargetsAnnotationName)] == "true" {
+		return h.allTargets(ctx, value, hasTargets)
+	}
+	healthy, err := h.probedFor(ctx, obj, value, hasTargets)
+	if err != nil {
+		return nil, err
+	}
+	return h.r.sameZone(ctx, obj, healthy), nil
+}
+
+// probedFor returns the global healthy set or, for an object with its own
+// targets, probe path or rule host, the healthy subset probed for it.
+func (h *healthySets) probedFor(ctx context.Context, obj client.Object, value string, hasTargets bool) ([]string, error) {
+	path := obj.GetAnnotations()[h.r.proberKey(probePathAnnotationName)]
+	var host string
+	if h.r.probeMode != "" && h.r.probeMode != ProbeModeHTTP {
+		path = ""
+	} else {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		if _, err := parseProbePath(path); err != nil {
+			return nil, fmt.Errorf("%s: %w", h.r.proberKey(probePathAnnotationName), err)
+		}
+		if h.r.hostFromIngress {
+			host = ruleHost(obj)
+		}
+	}
+	if !hasTargets && path == "" && host == "" {
+		return h.global, nil
+	}
+	if host == "" {
+		host = h.r.hostHeader
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ips := h.r.currentIPs()
+	if hasTargets {
+		var err error
+		if ips, err = h.overrideTargets(ctx, value); err != nil {
+			return nil, err
+		}
+	}
+
+	logger := log.FromContext(ctx)
+	healthy := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		key := probeKey{ip: ip, path: path, host: host}
+		ok, seen := h.probed[key]
+		if forced, err := h.r.forced.result(ip); !seen && forced {
+			ok, seen = err == nil, true
+			h.probed[key] = ok
+		}
+		if !seen {
+			switch {
+			case h.r.probeMode != "" && h.r.probeMode != ProbeModeHTTP:
+				ok = h.r.probe(ctx, logger, ip) == nil
+			case path == "":
+				ok = h.r.probePaths(ctx, logger, ip, host) == nil
+			default:
+				ok = h.r.probeHTTP(ctx, logger, ip, path, host) == nil
+			}
+			h.probed[key] = ok
+		}
+		if ok {
+			healthy = append(healthy, ip)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, errNoHealthyIP
+	}
+	return healthy, nil
+}
+
+// allTargets returns the unfiltered targets for an object: its override list
+// when it has one, otherwise the configured IPs.
+func (h *healthySets) allTargets(ctx context.Context, value string, hasTargets bool) ([]string, error) {
+	if hasTargets {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.overrideTargets(ctx, value)
+	}
+	if h.r.discoverSelector != nil && h.r.discoverWrite == DiscoverWriteIPs {
+		// the discovered endpoints are probed, the configured IPs written
+		return h.r.writeIPs, nil
+	}
+	return h.r.currentIPs(), nil
+}
+
+// ruleHost returns the first rule host of an Ingress, hostname of an
+// HTTPRoute or listener hostname of a Gateway, or "" for objects without one
+// and for other objects.
+func ruleHost(obj client.Object) string {
+	switch o := obj.(type) {
+	case *networkingv1.Ingress:
+		for _, rule := range o.Spec.Rules {
+			if rule.Host != "" {
+				return rule.Host
+			}
+		}
+	case *gatewayv1.HTTPRoute:
+		for _, h := range o.Spec.Hostnames {
+			if h != "" {
+				return string(h)
+			}
+		}
+	case *gatewayv1.Gateway:
+		for _, l := range o.Spec.Listeners {
+			if l.Hostname != nil && *l.Hostname != "" {
+				return string(*l.Hostname)
+			}
+		}
+	}
+	return ""
+}
+
+// overrideTargets parses and prepares an override list, caching the result.
+// Callers must hold h.mu.
+func (h *healthySets) overrideTargets(ctx context.Context, value string) ([]string, error) {
+	if ips, ok := h.parsed[value]; ok {
+		return ips, nil
+	}
+	key := h.r.proberKey(targetsAnnotationName)
+	ips, err := parseOverrideTargets(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	ts, err := h.r.prepareTargets(ctx, targetSet{ips: ips})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	h.parsed[value] = ts.ips
+	return ts.ips, nil
+}
+
+// parseOverrideTargets parses the value of TargetsAnnotationKey.
+func parseOverrideTargets(value string) ([]string, error) {
+	ips := parseIPList(value)
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no IPs listed")
+	}
+	for _, ip := range ips {
+		if !validIP(ip) {
+			return nil, fmt.Errorf("invalid IP %q", ip)
+		}
+	}
+	return ips, nil
+}
@@ -0,0 +1,337 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_TargetOverrides(t *testing.T) {
+	unhealthy := map[string]bool{"10.0.1.2": true, "10.0.2.1": true, "10.0.2.2": true}
+	var (
+		mu     sync.Mutex
+		probes = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		probes[host]++
+		mu.Unlock()
+		if unhealthy[host] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ingresses := map[string]map[string]string{
+		"global":    {},
+		"override":  {TargetsAnnotationKey: "10.0.1.1, 10.0.1.2,10.0.1.3"},
+		"shared":    {TargetsAnnotationKey: "10.0.1.3"},
+		"all-down":  {TargetsAnnotationKey: "10.0.2.1,10.0.2.2", "new.example.com/target": "10.0.2.1"},
+		"malformed": {TargetsAnnotationKey: "not-an-ip", "new.example.com/target": "keep"},
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, ann := range ingresses {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		builder = builder.WithObjects(newIngress(name, ann))
+	}
+	k8s := builder.Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"global":    "10.0.0.1,10.0.0.2",
+		"override":  "10.0.1.1,10.0.1.3",
+		"shared":    "10.0.1.3",
+		"all-down":  "10.0.2.1",
+		"malformed": "keep",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+	if probes["10.0.1.3"] != 1 {
+		t.Errorf("Expected an override IP shared by two Ingresses to be probed once, got %d", probes["10.0.1.3"])
+	}
+}
+
+func TestParseOverrideTargets(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    int
+		expectError bool
+	}{
+		{value: "1.1.1.1,2.2.2.2", expected: 2},
+		{value: " 1.1.1.1 ,\n2001:db8::1 ", expected: 2},
+		{value: "", expectError: true},
+		{value: "1.1.1.1,example.com", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ips, err := parseOverrideTargets(tt.value)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("Unexpected error state: %v", err)
+			}
+			if len(ips) != tt.expected {
+				t.Errorf("Expected %d IPs, got %v", tt.expected, ips)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_ProbePathOverrides(t *testing.T) {
+	// healthy lists, per path, the IPs answering 200
+	healthy := map[string]map[string]bool{
+		"/":        {"10.0.0.1": true, "10.0.0.2": true},
+		"/app-a":   {"10.0.0.1": true},
+		"/app-b":   {"10.0.0.2": true, "10.0.1.1": true},
+		"/offline": {},
+	}
+	var (
+		mu     sync.Mutex
+		probes = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		probes[host+r.URL.Path]++
+		mu.Unlock()
+		if healthy[r.URL.Path][host] {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ingresses := map[string]map[string]string{
+		"default-path": {},
+		"a":            {ProbePathAnnotationKey: "/app-a"},
+		"a-again":      {ProbePathAnnotationKey: "app-a"},
+		"b":            {ProbePathAnnotationKey: "/app-b"},
+		"b-override":   {ProbePathAnnotationKey: "/app-b", TargetsAnnotationKey: "10.0.1.1,10.0.1.2"},
+		"offline":      {ProbePathAnnotationKey: "/offline", "new.example.com/target": "keep"},
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, ann := range ingresses {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		builder = builder.WithObjects(newIngress(name, ann))
+	}
+	k8s := builder.Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"default-path": "10.0.0.1,10.0.0.2",
+		"a":            "10.0.0.1",
+		"a-again":      "10.0.0.1",
+		"b":            "10.0.0.2",
+		"b-override":   "10.0.1.1",
+		"offline":      "keep",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+	for _, key := range []string{"10.0.0.1/app-a", "10.0.0.2/app-a", "10.0.0.2/app-b"} {
+		if probes[key] != 1 {
+			t.Errorf("Expected %s to be probed once, got %d", key, probes[key])
+		}
+	}
+}
+
+// probedIPTransport records the probed address in a request header, since
+// the Host header no longer carries it once overridden.
+type probedIPTransport struct {
+	next http.RoundTripper
+}
+
+func (t probedIPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	ip, _, _ := net.SplitHostPort(req.URL.Host)
+	req.Header.Set("X-Probed-IP", ip)
+	return t.next.RoundTrip(req)
+}
+
+func TestRunner_Tick_HostFromIngress(t *testing.T) {
+	// healthy lists, per Host header, the IPs answering 200
+	healthy := map[string]map[string]bool{
+		"fallback.example.com": {"10.0.0.1": true, "10.0.0.2": true},
+		"a.example.com":        {"10.0.0.1": true},
+		"b.example.com":        {"10.0.0.2": true},
+	}
+	var (
+		mu     sync.Mutex
+		probes = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.Header.Get("X-Probed-IP")
+		mu.Lock()
+		probes[r.Host+"/"+ip]++
+		mu.Unlock()
+		if healthy[r.Host][ip] {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	withHosts := func(ing *networkingv1.Ingress, hosts ...string) *networkingv1.Ingress {
+		for _, h := range hosts {
+			ing.Spec.Rules = append(ing.Spec.Rules, networkingv1.IngressRule{Host: h})
+		}
+		return ing
+	}
+	class := func() map[string]string {
+		return map[string]string{"kubernetes.io/ingress.class": "public-nginx"}
+	}
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		withHosts(newIngress("a", class()), "a.example.com"),
+		withHosts(newIngress("a-shared", class()), "", "a.example.com", "b.example.com"),
+		withHosts(newIngress("b", class()), "b.example.com"),
+		newIngress("no-rules", class()),
+	).Build()
+
+	client := newRoutedHTTPClient(server)
+	client.Transport = probedIPTransport{next: client.Transport}
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                client,
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		hostHeader:                "fallback.example.com",
+		hostFromIngress:           true,
+		timeout:                   time.Second,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"a":        "10.0.0.1",
+		"a-shared": "10.0.0.1",
+		"b":        "10.0.0.2",
+		"no-rules": "10.0.0.1,10.0.0.2",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+	for _, key := range []string{"a.example.com/10.0.0.1", "a.example.com/10.0.0.2", "b.example.com/10.0.0.1"} {
+		if probes[key] != 1 {
+			t.Errorf("Expected %s to be probed once, got %d", key, probes[key])
+		}
+	}
+}
+
+func TestRunner_Tick_AllTargetsAnnotation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.1" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ingresses := map[string]map[string]string{
+		"web":               {},
+		"monitoring":        {AllTargetsAnnotationKey: "true"},
+		"monitoring-subset": {AllTargetsAnnotationKey: "true", TargetsAnnotationKey: "10.0.1.1,10.0.1.2"},
+		"not-true":          {AllTargetsAnnotationKey: "yes"},
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, ann := range ingresses {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		builder = builder.WithObjects(newIngress(name, ann))
+	}
+	k8s := builder.Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		singleTarget:              true,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"web":               "10.0.0.1",
+		"monitoring":        "10.0.0.1,10.0.0.2,10.0.0.3",
+		"monitoring-subset": "10.0.1.1,10.0.1.2",
+		"not-true":          "10.0.0.1",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+}
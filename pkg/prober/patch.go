@@ -0,0 +1,70 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Patch strategies for writing annotations.
+const (
+	// PatchStrategyMerge sends JSON merge patches (the default).
+	PatchStrategyMerge = "merge"
+	// PatchStrategyApply uses server-side apply, owning the written
+	// annotations under the configured field manager.
+	PatchStrategyApply = "apply"
+
+	DefaultFieldManager = "ingress-target-prober"
+)
+
+// validatePatchStrategy rejects unknown patch strategies.
+func validatePatchStrategy(s string) error {
+	switch s {
+	case "", PatchStrategyMerge, PatchStrategyApply:
+		return nil
+	}
+	return fmt.Errorf("unsupported patch strategy %q (want %s or %s)", s, PatchStrategyMerge, PatchStrategyApply)
+}
+
+// sendPatch writes a planned update using the configured patch strategy.
+func (r *Runner) sendPatch(ctx context.Context, u targetUpdate) error {
+	if r.patchStrategy != PatchStrategyApply {
+		return r.k8sOp(ctx, "patch", func(ctx context.Context) error {
+			return r.k8s.Patch(ctx, u.obj, u.patch, client.FieldOwner(r.fieldManager))
+		})
+	}
+
+	// apply only the fields we own; everything else stays with its manager
+	annotations := make(map[string]string, len(u.desired)+1)
+	for k, v := range u.desired {
+		annotations[k] = v
+	}
+	annotations[r.proberKey(managedAnnotationName)] = "true"
+	obj := r.applyTarget(u.obj, annotations)
+	err := r.k8sOp(ctx, "patch", func(ctx context.Context) error {
+		return r.k8s.Patch(ctx, obj, client.Apply, client.FieldOwner(r.fieldManager), client.ForceOwnership)
+	})
+	if err != nil {
+		return err
+	}
+	if len(u.stale) == 0 {
+		return nil
+	}
+
+	// stale keys are usually owned by another manager, so apply can't drop
+	// them; a raw merge patch nulls exactly those keys and nothing else
+	remove := make(map[string]any, len(u.stale))
+	for _, k := range u.stale {
+		remove[k] = nil
+	}
+	data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": remove}})
+	if err != nil {
+		return err
+	}
+	return r.k8sOp(ctx, "patch", func(ctx context.Context) error {
+		return r.k8s.Patch(ctx, u.obj, client.RawPatch(types.MergePatchType, data), client.FieldOwner(r.fieldManager))
+	})
+}
@@ -0,0 +1,157 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// recordedPatch is a patch call seen by the fake client.
+type recordedPatch struct {
+	patchType    types.PatchType
+	fieldManager string
+	force        bool
+}
+
+// newApplyingClient returns a fake client that records patch calls and
+// emulates server-side apply, which the fake client does not support, by
+// merging the applied annotations into the stored object.
+func newApplyingClient(mu *sync.Mutex, calls *[]recordedPatch, objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			po := &client.PatchOptions{}
+			po.ApplyOptions(opts)
+			mu.Lock()
+			*calls = append(*calls, recordedPatch{patch.Type(), po.FieldManager, po.Force != nil && *po.Force})
+			mu.Unlock()
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			cur := obj.DeepCopyObject().(client.Object)
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), cur); err != nil {
+				return err
+			}
+			annotations := cur.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			for k, v := range obj.GetAnnotations() {
+				annotations[k] = v
+			}
+			cur.SetAnnotations(annotations)
+			return c.Update(ctx, cur)
+		},
+	}).Build()
+}
+
+func TestRunner_Tick_PatchStrategies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		strategy      string
+		fieldManager  string
+		expectedTypes []types.PatchType
+		expectForce   bool
+		expectMarker  bool
+	}{
+		{
+			name:          "merge",
+			strategy:      PatchStrategyMerge,
+			fieldManager:  "custom-manager",
+			expectedTypes: []types.PatchType{types.MergePatchType},
+		},
+		{
+			name:          "apply",
+			strategy:      PatchStrategyApply,
+			expectedTypes: []types.PatchType{types.ApplyPatchType, types.MergePatchType},
+			expectForce:   true,
+			expectMarker:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				calls []recordedPatch
+			)
+			k8s := newApplyingClient(&mu, &calls, newIngress("web", map[string]string{
+				"kubernetes.io/ingress.class": "public-nginx",
+				"old.example.com/target":      "9.9.9.9",
+			}))
+			runner, err := New(Options{
+				Client:               k8s,
+				AnnotationKey:        "new.example.com/target",
+				RemoveAnnotationKeys: []string{"old.example.com/target"},
+				IPs:                  []string{"10.0.0.1"},
+				PatchStrategy:        tt.strategy,
+				FieldManager:         tt.fieldManager,
+				HTTPClient:           newRoutedHTTPClient(server),
+				Timeout:              time.Second,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			wantManager := tt.fieldManager
+			if wantManager == "" {
+				wantManager = DefaultFieldManager
+			}
+			if len(calls) != len(tt.expectedTypes) {
+				t.Fatalf("Expected %d patch calls, got %+v", len(tt.expectedTypes), calls)
+			}
+			for i, call := range calls {
+				if call.patchType != tt.expectedTypes[i] {
+					t.Errorf("Call %d: expected patch type %q, got %q", i, tt.expectedTypes[i], call.patchType)
+				}
+				if call.fieldManager != wantManager {
+					t.Errorf("Call %d: expected field manager %q, got %q", i, wantManager, call.fieldManager)
+				}
+			}
+			if calls[0].force != tt.expectForce {
+				t.Errorf("Expected force ownership %v, got %v", tt.expectForce, calls[0].force)
+			}
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if got.Annotations["new.example.com/target"] != "10.0.0.1" {
+				t.Errorf("Expected annotation to be set, got %v", got.Annotations)
+			}
+			if _, ok := got.Annotations["old.example.com/target"]; ok {
+				t.Errorf("Expected stale annotation to be removed, got %v", got.Annotations)
+			}
+			if (got.Annotations[ManagedAnnotationKey] == "true") != tt.expectMarker {
+				t.Errorf("Expected managed annotation present=%v, got %v", tt.expectMarker, got.Annotations)
+			}
+		})
+	}
+}
+
+func TestValidatePatchStrategy(t *testing.T) {
+	for _, s := range []string{"", PatchStrategyMerge, PatchStrategyApply} {
+		if err := validatePatchStrategy(s); err != nil {
+			t.Errorf("Unexpected error for %q: %v", s, err)
+		}
+	}
+	if err := validatePatchStrategy("strategic"); err == nil {
+		t.Error("Expected error for unsupported strategy")
+	}
+}
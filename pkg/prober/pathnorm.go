@@ -0,0 +1,66 @@
+package prober
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trailing slash policies applied by NormalizePath.
+const (
+	TrailingSlashKeep  = "keep"
+	TrailingSlashStrip = "strip"
+	TrailingSlashAdd   = "add"
+)
+
+func (o *Options) validatePathNormalization() error {
+	switch o.TrailingSlash {
+	case "", TrailingSlashKeep:
+	case TrailingSlashStrip, TrailingSlashAdd:
+		if !o.NormalizePath {
+			return fmt.Errorf("a trailing slash policy requires path normalization")
+		}
+	default:
+		return fmt.Errorf("unsupported trailing slash policy %q (want %s, %s or %s)", o.TrailingSlash, TrailingSlashKeep, TrailingSlashStrip, TrailingSlashAdd)
+	}
+	return nil
+}
+
+// normalizePath gives path a leading slash and applies the trailing slash
+// policy to the path part, leaving any query string alone. The root path
+// keeps its slash.
+func normalizePath(path, trailingSlash string) string {
+	p, query, hasQuery := strings.Cut(path, "?")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	switch trailingSlash {
+	case TrailingSlashStrip:
+		if p = strings.TrimRight(p, "/"); p == "" {
+			p = "/"
+		}
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+	}
+	if hasQuery {
+		return p + "?" + query
+	}
+	return p
+}
+
+// probePath returns path as it is sent, normalized when configured.
+func (r *Runner) probePath(path string) string {
+	if !r.normalizePath {
+		return path
+	}
+	return normalizePath(path, r.trailingSlash)
+}
+
+// probePath mirrors Runner.probePath for validating configured paths.
+func (o *Options) probePath(path string) string {
+	if !o.NormalizePath {
+		return path
+	}
+	return normalizePath(path, o.TrailingSlash)
+}
@@ -0,0 +1,113 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		path          string
+		trailingSlash string
+		expected      string
+	}{
+		{path: "health", expected: "/health"},
+		{path: "/health/", trailingSlash: TrailingSlashKeep, expected: "/health/"},
+		{path: "health/", trailingSlash: TrailingSlashStrip, expected: "/health"},
+		{path: "/health//", trailingSlash: TrailingSlashStrip, expected: "/health"},
+		{path: "/health", trailingSlash: TrailingSlashAdd, expected: "/health/"},
+		{path: "/health/", trailingSlash: TrailingSlashAdd, expected: "/health/"},
+		{path: "health?verbose=1", trailingSlash: TrailingSlashAdd, expected: "/health/?verbose=1"},
+		{path: "/health/?next=/a/", trailingSlash: TrailingSlashStrip, expected: "/health?next=/a/"},
+		{path: "", expected: "/"},
+		{path: "/", trailingSlash: TrailingSlashStrip, expected: "/"},
+		{path: "?ready", trailingSlash: TrailingSlashAdd, expected: "/?ready"},
+	}
+	for _, tt := range tests {
+		if got := normalizePath(tt.path, tt.trailingSlash); got != tt.expected {
+			t.Errorf("normalizePath(%q, %q): expected %q, got %q", tt.path, tt.trailingSlash, tt.expected, got)
+		}
+	}
+}
+
+func TestRunner_HealthyIPs_NormalizePath(t *testing.T) {
+	var mu sync.Mutex
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = r.URL.RequestURI()
+		mu.Unlock()
+		if r.URL.Path != "/healthz/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		path          string
+		normalize     bool
+		trailingSlash string
+		expected      string
+		expectHealthy bool
+	}{
+		{name: "not normalized", path: "/healthz", expected: "/healthz"},
+		{name: "leading slash only", path: "healthz/", normalize: true, expected: "/healthz/", expectHealthy: true},
+		{name: "trailing slash added", path: "/healthz?full=1", normalize: true, trailingSlash: TrailingSlashAdd, expected: "/healthz/?full=1", expectHealthy: true},
+		{name: "trailing slash stripped", path: "/healthz/", normalize: true, trailingSlash: TrailingSlashStrip, expected: "/healthz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{
+				ips:           []string{"10.0.0.1"},
+				httpClient:    newRoutedHTTPClient(server),
+				urlScheme:     "http",
+				httpPath:      tt.path,
+				normalizePath: tt.normalize,
+				trailingSlash: tt.trailingSlash,
+				timeout:       time.Second,
+			}
+			// an error only reports that no IP is healthy
+			healthy, _, _ := runner.HealthyIPs(context.Background())
+			mu.Lock()
+			got := requested
+			mu.Unlock()
+			if got != tt.expected {
+				t.Errorf("Expected request for %q, got %q", tt.expected, got)
+			}
+			if (len(healthy) == 1) != tt.expectHealthy {
+				t.Errorf("Expected healthy=%v, got %v", tt.expectHealthy, healthy)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidatePathNormalization(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "relative path normalized", opts: Options{HTTPPath: "health", NormalizePath: true}},
+		{name: "relative paths normalized", opts: Options{HTTPPaths: []string{"a", "b/"}, NormalizePath: true, TrailingSlash: TrailingSlashStrip}},
+		{name: "keep without normalization", opts: Options{TrailingSlash: TrailingSlashKeep}},
+		{name: "relative path", opts: Options{HTTPPath: "health"}, wantErr: true},
+		{name: "trailing slash without normalization", opts: Options{TrailingSlash: TrailingSlashAdd}, wantErr: true},
+		{name: "unknown policy", opts: Options{NormalizePath: true, TrailingSlash: "both"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// validatePaths checks HTTPPaths and PathQuorum.
+func (o *Options) validatePaths() error {
+	for _, p := range o.HTTPPaths {
+		if _, err := parseProbePath(o.probePath(p)); err != nil {
+			return err
+		}
+	}
+	if len(o.HTTPPaths) > 0 && o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+		return fmt.Errorf("HTTP paths require the http probe mode")
+	}
+	switch {
+	case o.PathQuorum < 0:
+		return fmt.Errorf("path quorum must not be negative")
+	case o.PathQuorum > 0 && len(o.HTTPPaths) == 0:
+		return fmt.Errorf("a path quorum requires HTTP paths")
+	case o.PathQuorum > len(o.HTTPPaths):
+		return fmt.Errorf("path quorum %d exceeds the %d HTTP paths", o.PathQuorum, len(o.HTTPPaths))
+	}
+	return nil
+}
+
+// probePaths probes ip on every configured HTTP path and succeeds once
+// pathQuorum of them passed (all when unset). It stops early once the quorum
+// is reached or can no longer be reached, returning the first failure. Without
+// httpPaths it probes httpPath alone.
+func (r *Runner) probePaths(ctx context.Context, logger logr.Logger, ip, host string) error {
+	if len(r.httpPaths) == 0 {
+		return r.probeHTTP(ctx, logger, ip, r.httpPath, host)
+	}
+	need := r.pathQuorum
+	if need <= 0 || need > len(r.httpPaths) {
+		need = len(r.httpPaths)
+	}
+	var (
+		passed, failed int
+		firstErr       error
+	)
+	for _, path := range r.httpPaths {
+		if err := r.probeHTTP(ctx, logger, ip, path, host); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			passed++
+		}
+		if passed >= need {
+			return nil
+		}
+		if len(r.httpPaths)-failed < need {
+			logger.Info("path quorum not reached", "ip", ip, "passed", passed, "failed", failed, "quorum", need)
+			return firstErr
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,85 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_HealthyIPs_PathQuorum(t *testing.T) {
+	// each IP passes a different number of the three paths
+	passing := map[string]string{
+		"10.0.0.1": "/a /b /c",
+		"10.0.0.2": "/a /b",
+		"10.0.0.3": "/c",
+		"10.0.0.4": "",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		for _, p := range strings.Fields(passing[host]) {
+			if p == r.URL.Path {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		quorum   int
+		expected string
+	}{
+		{quorum: 0, expected: "10.0.0.1"},
+		{quorum: 1, expected: "10.0.0.1,10.0.0.2,10.0.0.3"},
+		{quorum: 2, expected: "10.0.0.1,10.0.0.2"},
+		{quorum: 3, expected: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		runner := &Runner{
+			ips:        []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			httpClient: newRoutedHTTPClient(server),
+			urlScheme:  "http",
+			httpPath:   "/",
+			httpPaths:  []string{"/a", "/b", "/c"},
+			pathQuorum: tt.quorum,
+			timeout:    time.Second,
+		}
+		healthy, _, err := runner.HealthyIPs(context.Background())
+		if err != nil {
+			t.Fatalf("quorum %d: unexpected error: %v", tt.quorum, err)
+		}
+		if got := strings.Join(healthy, ","); got != tt.expected {
+			t.Errorf("quorum %d: expected %q, got %q", tt.quorum, tt.expected, got)
+		}
+	}
+}
+
+func TestOptions_ValidatePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "paths without quorum", opts: Options{HTTPPaths: []string{"/a", "/b"}}},
+		{name: "quorum", opts: Options{HTTPPaths: []string{"/a", "/b"}, PathQuorum: 1}},
+		{name: "invalid path", opts: Options{HTTPPaths: []string{"/a", "b"}}, wantErr: true},
+		{name: "negative quorum", opts: Options{HTTPPaths: []string{"/a"}, PathQuorum: -1}, wantErr: true},
+		{name: "quorum without paths", opts: Options{PathQuorum: 1}, wantErr: true},
+		{name: "quorum above path count", opts: Options{HTTPPaths: []string{"/a", "/b"}, PathQuorum: 3}, wantErr: true},
+		{name: "tcp mode", opts: Options{HTTPPaths: []string{"/a"}, ProbeMode: ProbeModeTCP}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
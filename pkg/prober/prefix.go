@@ -0,0 +1,46 @@
+package prober
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultAnnotationPrefix namespaces the annotations the prober reads and
+// writes on its own behalf, such as ManagedAnnotationKey.
+const DefaultAnnotationPrefix = "ingress-target-prober"
+
+// Names of the prober-controlled annotations below the annotation prefix.
+const (
+	managedAnnotationName     = "managed"
+	targetsAnnotationName     = "targets"
+	probePathAnnotationName   = "probe-path"
+	allTargetsAnnotationName  = "all-targets"
+	lastUpdatedAnnotationName = "last-updated"
+	proposedAnnotationName    = "proposed"
+	approveAnnotationName     = "approve"
+	countAnnotationName       = "healthy-count"
+)
+
+// validateAnnotationPrefix accepts "" (the default) and DNS subdomains, the
+// form Kubernetes requires of an annotation key prefix.
+func validateAnnotationPrefix(p string) error {
+	if p == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(p); len(errs) > 0 {
+		return fmt.Errorf("invalid annotation prefix %q: %s", p, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// proberKey returns the key of the prober-controlled annotation name under
+// the configured prefix.
+func (r *Runner) proberKey(name string) string {
+	prefix := r.annotationPrefix
+	if prefix == "" {
+		prefix = DefaultAnnotationPrefix
+	}
+	return prefix + "/" + name
+}
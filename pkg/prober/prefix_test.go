@@ -0,0 +1,101 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_AnnotationPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ingresses := map[string]map[string]string{
+		"sentinel":        {"new.example.com/target": "auto"},
+		"managed":         {"new.example.com/target": "old", "acme.io/managed": "true"},
+		"default-managed": {"new.example.com/target": "old", ManagedAnnotationKey: "true"},
+		"override":        {"new.example.com/target": "auto", "acme.io/targets": "10.0.1.1"},
+		"default-targets": {"new.example.com/target": "auto", TargetsAnnotationKey: "10.0.1.1"},
+		"all":             {"new.example.com/target": "auto", "acme.io/all-targets": "true"},
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, ann := range ingresses {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		builder = builder.WithObjects(newIngress(name, ann))
+	}
+	k8s := builder.Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		annotationPrefix:          "acme.io",
+		requireCurrentValue:       "auto",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"sentinel":        "10.0.0.1",
+		"managed":         "10.0.0.1",
+		"default-managed": "old",
+		"override":        "10.0.1.1",
+		"default-targets": "10.0.0.1",
+		"all":             "10.0.0.1,10.0.0.2",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+		if want != "old" && got.Annotations["acme.io/managed"] != "true" {
+			t.Errorf("Ingress %s: expected the managed marker under the custom prefix, got %v", name, got.Annotations)
+		}
+	}
+}
+
+func TestOptions_ValidateAnnotationPrefix(t *testing.T) {
+	tests := []struct {
+		prefix      string
+		expectError bool
+	}{
+		{prefix: ""},
+		{prefix: DefaultAnnotationPrefix},
+		{prefix: "prober.acme.io"},
+		{prefix: "acme.io/prober", expectError: true},
+		{prefix: "Acme", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			opts := Options{IPs: []string{"10.0.0.1"}, AnnotationPrefix: tt.prefix}
+			err := opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package prober
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PreflightCheck is the outcome of one preflight check.
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// preflightVerbs are the verbs the prober needs on its target resource: the
+// informer cache lists and watches, updates patch.
+var preflightVerbs = []string{"list", "watch", "patch"}
+
+// Preflight checks that the API server is reachable, that the prober may
+// list, watch and patch its target resource, and that at least one target
+// is healthy. Every check runs, even after an earlier one failed.
+func (r *Runner) Preflight(ctx context.Context) []PreflightCheck {
+	var checks []PreflightCheck
+	if r.k8s == nil {
+		checks = append(checks, PreflightCheck{Name: "api-server", Detail: "no Kubernetes client"})
+	} else {
+		checks = append(checks, r.preflightAPIServer(ctx))
+		kind := kindOf(r.targetResource)
+		for _, verb := range preflightVerbs {
+			checks = append(checks, r.preflightAccess(ctx, verb, kind.gvk.Group, kind.plural))
+		}
+		if r.ingressController != "" {
+			checks = append(checks, r.preflightAccess(ctx, "list", "networking.k8s.io", "ingressclasses"))
+		}
+	}
+	return append(checks, r.preflightTargets(ctx))
+}
+
+// preflightAPIServer lists a single target object to prove the API server
+// answers.
+func (r *Runner) preflightAPIServer(ctx context.Context) PreflightCheck {
+	list := kindOf(r.targetResource).newList()
+	if err := r.k8s.List(ctx, list, client.Limit(1)); err != nil {
+		return PreflightCheck{Name: "api-server", Detail: err.Error()}
+	}
+	return PreflightCheck{Name: "api-server", OK: true}
+}
+
+// preflightAccess asks the API server via a SelfSubjectAccessReview whether
+// the prober may verb resource in every namespace.
+func (r *Runner) preflightAccess(ctx context.Context, verb, group, resource string) PreflightCheck {
+	name := fmt.Sprintf("rbac:%s %s", verb, resource)
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: verb, Group: group, Resource: resource},
+		},
+	}
+	if err := r.k8s.Create(ctx, review); err != nil {
+		return PreflightCheck{Name: name, Detail: err.Error()}
+	}
+	if !review.Status.Allowed {
+		detail := "denied"
+		if review.Status.Reason != "" {
+			detail += ": " + review.Status.Reason
+		}
+		return PreflightCheck{Name: name, Detail: detail}
+	}
+	return PreflightCheck{Name: name, OK: true}
+}
+
+// preflightTargets probes the targets and passes when one is healthy.
+func (r *Runner) preflightTargets(ctx context.Context) PreflightCheck {
+	healthy, _, err := r.HealthyIPs(ctx)
+	if err != nil {
+		return PreflightCheck{Name: "targets", Detail: fmt.Sprintf("none of %d targets healthy", len(r.currentIPs()))}
+	}
+	return PreflightCheck{Name: "targets", OK: true, Detail: "healthy: " + strings.Join(healthy, ",")}
+}
+
+// PreflightPassed reports whether every check passed.
+func PreflightPassed(checks []PreflightCheck) bool {
+	for _, c := range checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// WritePreflight prints preflight checks to w in format, like WriteResults.
+func WritePreflight(w io.Writer, format string, checks []PreflightCheck) error {
+	switch format {
+	case OutputText:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+		for _, c := range checks {
+			status := "ok"
+			if !c.OK {
+				status = "failed"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, status, c.Detail)
+		}
+		return tw.Flush()
+	case OutputJSON:
+		if checks == nil {
+			checks = []PreflightCheck{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(checks)
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"check", "ok", "detail"})
+		for _, c := range checks {
+			_ = cw.Write([]string{c.Name, strconv.FormatBool(c.OK), c.Detail})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return ValidateOutputFormat(format)
+	}
+}
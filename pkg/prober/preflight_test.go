@@ -0,0 +1,130 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// reviewingClient answers SelfSubjectAccessReviews by allowing the verbs in
+// allowed and fails List with listErr when set.
+func reviewingClient(allowed map[string]bool, listErr error) client.Client {
+	return fake.NewClientBuilder().WithScheme(testScheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+			if !ok {
+				return c.Create(ctx, obj, opts...)
+			}
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = allowed[attrs.Verb+" "+attrs.Resource]
+			if !review.Status.Allowed {
+				review.Status.Reason = "no RBAC policy matched"
+			}
+			return nil
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if listErr != nil {
+				return listErr
+			}
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+}
+
+func TestRunner_Preflight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "10.0.0.9") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	all := map[string]bool{"list ingresses": true, "watch ingresses": true, "patch ingresses": true}
+	tests := []struct {
+		name    string
+		allowed map[string]bool
+		listErr error
+		ips     []string
+		failed  []string
+	}{
+		{name: "all passing", allowed: all, ips: []string{"10.0.0.9", "10.0.0.1"}},
+		{
+			name:    "patch denied",
+			allowed: map[string]bool{"list ingresses": true, "watch ingresses": true},
+			ips:     []string{"10.0.0.1"},
+			failed:  []string{"rbac:patch ingresses"},
+		},
+		{name: "no healthy target", allowed: all, ips: []string{"10.0.0.9"}, failed: []string{"targets"}},
+		{
+			name:    "API server unreachable",
+			allowed: all,
+			listErr: errors.New("dial tcp 10.96.0.1:443: connect: connection refused"),
+			ips:     []string{"10.0.0.1"},
+			failed:  []string{"api-server"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := New(Options{
+				Client:     reviewingClient(tt.allowed, tt.listErr),
+				IPs:        tt.ips,
+				HTTPClient: newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			checks := runner.Preflight(context.Background())
+			if len(checks) != 5 {
+				t.Fatalf("Expected 5 checks, got %+v", checks)
+			}
+			var failed []string
+			for _, c := range checks {
+				if !c.OK {
+					failed = append(failed, c.Name)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("Expected failed checks %v, got %+v", tt.failed, checks)
+			}
+			if PreflightPassed(checks) != (len(tt.failed) == 0) {
+				t.Errorf("PreflightPassed() = %v with failed checks %v", PreflightPassed(checks), failed)
+			}
+		})
+	}
+}
+
+func TestWritePreflight(t *testing.T) {
+	checks := []PreflightCheck{
+		{Name: "api-server", OK: true},
+		{Name: "rbac:patch ingresses", Detail: "denied: no RBAC policy matched"},
+	}
+	var buf bytes.Buffer
+	if err := WritePreflight(&buf, OutputText, checks); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "CHECK                 STATUS  DETAIL\n" +
+		"api-server            ok      \n" +
+		"rbac:patch ingresses  failed  denied: no RBAC policy matched\n"
+	if buf.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := WritePreflight(&buf, OutputCSV, checks); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "rbac:patch ingresses,false,denied: no RBAC policy matched\n") {
+		t.Errorf("Unexpected CSV output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,362 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var errNoHealthyIP = errors.New("no healthy IP found")
+
+// HealthyIPs probes the configured IPs and returns the ones answering with a
+// 2xx status. When stopAfterHealthy is set, probing stops once that many
+// healthy IPs have been found. When ctx ends before every IP was probed,
+// the IPs confirmed healthy so far are returned with partial set.
+func (r *Runner) HealthyIPs(ctx context.Context) (healthy []string, partial bool, err error) {
+	healthy, _, _, partial = r.probeAllTimed(ctx)
+	if len(healthy) == 0 {
+		return nil, partial, errNoHealthyIP
+	}
+	return healthy, partial, nil
+}
+
+// probeAll probes the configured IPs and returns the healthy ones along with
+// the classified error for every IP found unhealthy.
+func (r *Runner) probeAll(ctx context.Context) ([]string, map[string]error) {
+	healthy, failures, _, _ := r.probeAllTimed(ctx)
+	return healthy, failures
+}
+
+// probeAllTimed is probeAll that also returns how long the probe of each
+// probed IP took and whether ctx ended before every IP was probed. An IP whose
+// probe was cut short by ctx counts as not probed rather than failed.
+// Up to probeConcurrency IPs are probed at once; the healthy set keeps the
+// order of the configured IPs. Once stopAfterHealthy IPs are healthy, probes
+// still in flight are cancelled and the remaining IPs are not probed.
+func (r *Runner) probeAllTimed(ctx context.Context) (healthy []string, failures map[string]error, latencies map[string]time.Duration, partial bool) {
+	return r.probeIPs(ctx, r.currentIPs())
+}
+
+// probeIPs is probeAllTimed for the given IPs only.
+func (r *Runner) probeIPs(ctx context.Context, ips []string) (healthy []string, failures map[string]error, latencies map[string]time.Duration, partial bool) {
+	logger := log.FromContext(ctx)
+	healthy = make([]string, 0, len(ips))
+	failures = map[string]error{}
+	latencies = make(map[string]time.Duration, len(ips))
+	// stopCtx also ends once enough healthy IPs were found
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	type outcome struct {
+		probed  bool
+		err     error
+		latency time.Duration
+	}
+	outcomes := make([]outcome, len(ips))
+	sem := make(chan struct{}, max(1, r.probeConcurrency))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var healthyCount int
+	var enough bool
+	start := time.Now()
+	for i, ip := range ips {
+		if err := r.waitForProbeSlot(stopCtx, start, i); err != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-stopCtx.Done():
+		}
+		if stopCtx.Err() != nil {
+			break
+		}
+		forced, forcedErr := r.forced.result(ip)
+		if !forced {
+			if until, ok := r.dead.paused(ip); ok {
+				logger.V(1).Info("skipping dead IP during cooldown", "ip", ip, "until", until.Format(time.RFC3339))
+				failures[ip] = newProbeError(ErrorTypeDead, errDeadCooldown)
+				<-sem
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			started := time.Now()
+			probeCtx, span := r.startSpan(stopCtx, "probe", attribute.String("ip", ip))
+			err := forcedErr
+			if forced {
+				logger.Info("probe result forced by operator override", "ip", ip, "healthy", err == nil)
+			} else {
+				err = r.probe(probeCtx, logger, ip)
+			}
+			endProbeSpan(span, err, time.Since(started))
+			if err != nil && stopCtx.Err() != nil {
+				// cut short by ctx or by enough healthy IPs: not probed
+				return
+			}
+			outcomes[i] = outcome{probed: true, err: err, latency: time.Since(started)}
+			if !forced && r.dead.record(ip, err == nil) {
+				logger.Info("IP marked as dead; pausing probes", "ip", ip, "cooldown", r.dead.cooldown.String())
+			}
+			if err != nil {
+				probeErrors.WithLabelValues(ip, classifyProbeError(err)).Inc()
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			healthyCount++
+			if r.stopAfterHealthy > 0 && healthyCount >= r.stopAfterHealthy {
+				enough = true
+				stop()
+			}
+		}(i, ip)
+	}
+	wg.Wait()
+
+	skipped := 0
+	for i, ip := range ips {
+		o := outcomes[i]
+		if !o.probed {
+			if _, dead := failures[ip]; !dead {
+				skipped++
+			}
+			continue
+		}
+		latencies[ip] = o.latency
+		if o.err != nil {
+			failures[ip] = o.err
+		} else {
+			healthy = append(healthy, ip)
+		}
+	}
+	switch {
+	case skipped == 0:
+	case enough:
+		logger.Info("enough healthy IPs found; skipping remaining probes", "healthy_count", len(healthy), "skipped", skipped)
+	default:
+		logger.Info("probe cycle cancelled before all IPs were probed", "error", ctx.Err().Error(), "skipped", skipped)
+		partial = true
+	}
+	return healthy, failures, latencies, partial
+}
+
+// waitForProbeSlot delays the i-th probe so that probe starts are spaced by probeStagger.
+func (r *Runner) waitForProbeSlot(ctx context.Context, start time.Time, i int) error {
+	if r.probeStagger <= 0 || i == 0 {
+		return nil
+	}
+	d := time.Until(start.Add(time.Duration(i) * r.probeStagger))
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// probe checks a single IP using the configured probe mode. It returns nil
+// when the IP is healthy and a *ProbeError otherwise.
+func (r *Runner) probe(ctx context.Context, logger logr.Logger, ip string) error {
+	switch r.probeMode {
+	case ProbeModeDNS:
+		return r.probeDNS(ctx, logger, ip)
+	case ProbeModeGRPC:
+		return r.probeGRPC(ctx, logger, ip)
+	case ProbeModeTCP:
+		return r.probeTCP(ctx, logger, ip)
+	default:
+		return r.probePaths(ctx, logger, ip, r.hostHeader)
+	}
+}
+
+// method returns the HTTP method used for probes, GET unless configured.
+func (r *Runner) method() string {
+	if r.probeMethod == "" {
+		return http.MethodGet
+	}
+	return r.probeMethod
+}
+
+// newBodyFactory returns a function producing a fresh reader over body for
+// every request, so concurrent and redirected probes never share a reader.
+// It returns nil for an empty body.
+func newBodyFactory(body []byte) func() io.Reader {
+	if len(body) == 0 {
+		return nil
+	}
+	return func() io.Reader { return bytes.NewReader(body) }
+}
+
+// basicAuth holds the credentials sent with HTTP probes. It is never logged.
+type basicAuth struct {
+	user, pass string
+}
+
+// newBasicAuth returns the probe credentials, reading the password from
+// passFile when set. It returns nil when no user is configured.
+func newBasicAuth(user, pass, passFile string) (*basicAuth, error) {
+	if user == "" {
+		return nil, nil
+	}
+	if passFile != "" {
+		b, err := os.ReadFile(passFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading basic auth password file: %w", err)
+		}
+		// secrets mounted from files commonly end in a newline
+		pass = strings.TrimRight(string(b), "\r\n")
+	}
+	return &basicAuth{user: user, pass: pass}, nil
+}
+
+// maxProbeBodyBytes bounds how much of a response body a probe reads.
+const maxProbeBodyBytes = 1 << 20
+
+var errProbeBodyTooLarge = fmt.Errorf("response body exceeds %d bytes", maxProbeBodyBytes)
+
+// drainBody reads body to EOF, at most maxProbeBodyBytes, so that trailers
+// sent after it become available.
+func drainBody(body io.Reader) error {
+	_, err := readBody(body)
+	return err
+}
+
+// readBody reads body to EOF and returns it, failing with
+// errProbeBodyTooLarge past maxProbeBodyBytes.
+func readBody(body io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(body, maxProbeBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxProbeBodyBytes {
+		return nil, errProbeBodyTooLarge
+	}
+	return b, nil
+}
+
+// parseProbePath parses an HTTP probe path with an optional query string,
+// such as "/health?verbose=1". Fragments are never sent and are rejected, as
+// are absolute URLs.
+func parseProbePath(path string) (*url.URL, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe path %q: %w", path, err)
+	}
+	if strings.Contains(path, "#") {
+		return nil, fmt.Errorf("invalid probe path %q: fragments are not allowed", path)
+	}
+	if ref.Scheme != "" || ref.Host != "" || (ref.Path != "" && !strings.HasPrefix(ref.Path, "/")) {
+		return nil, fmt.Errorf("invalid probe path %q: must start with /", path)
+	}
+	return ref, nil
+}
+
+// probeURL issues a single HTTP probe against ip on path over scheme.
+func (r *Runner) probeURL(ctx context.Context, logger logr.Logger, ip, scheme, path, host string) error {
+	ref, err := parseProbePath(r.probePath(path))
+	if err != nil {
+		return newProbeError(ErrorTypeOther, err)
+	}
+	ref.Scheme = scheme
+	ref.Host = r.probeAddress(ip, portForScheme(scheme))
+	u := ref.String()
+	release, err := r.hostLimit.acquire(ctx, ref.Host)
+	if err != nil {
+		return newProbeError(classifyError(err), err)
+	}
+	defer release()
+	logger.Info("probing IP", "ip", ip, "url", u)
+	if r.timeout > 0 {
+		// bounds reading and closing the body too, not just the round trip
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if r.probeBody != nil {
+		body = r.probeBody()
+	}
+	req, _ := http.NewRequestWithContext(ctx, r.method(), u, body)
+	if body != nil && r.probeContentType != "" {
+		req.Header.Set("Content-Type", r.probeContentType)
+	}
+	if r.basicAuth != nil {
+		req.SetBasicAuth(r.basicAuth.user, r.basicAuth.pass)
+	}
+	if token := r.bearerToken.get(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// Set Host header if specified
+	if host != "" {
+		req.Host = host
+		logger.Info("setting Host header", "ip", ip, "host", host)
+	}
+
+	started := time.Now()
+	resp, err := r.probeClient(scheme).Do(req)
+	observeWithExemplar(probeDuration, time.Since(started).Seconds(), prometheus.Labels{"ip": ip})
+	if err != nil {
+		typ := classifyError(err)
+		logger.Info("HTTP request failed", "ip", ip, "url", u, "error", err.Error(), "error_type", typ)
+		return newProbeError(typ, err)
+	}
+	var (
+		respBody []byte
+		drainErr error
+	)
+	readFully := r.healthExpr != nil || r.expectJSON != nil || r.expectBody != "" || len(r.expectTrailers) > 0
+	if readFully {
+		// trailers are only populated once the body has been read to EOF
+		respBody, drainErr = readBody(resp.Body)
+	}
+	latency := time.Since(started)
+	if !readFully && r.drainResponses {
+		// a body read to EOF lets the transport reuse the connection; one
+		// past the limit is not worth reading and just closes it
+		_ = drainBody(resp.Body)
+	}
+	_ = resp.Body.Close()
+	logger.Info("HTTP response received", "ip", ip, "url", u, "status_code", resp.StatusCode)
+	if r.redirectToHTTPSHealthy && strings.ToLower(scheme) == "http" {
+		if loc, ok := httpsRedirect(resp); ok {
+			logger.Info("IP marked as healthy on redirect to HTTPS", "ip", ip, "status_code", resp.StatusCode, "location", loc)
+			return nil
+		}
+	}
+	if typ, err := r.checkResponse(resp, latency, respBody, drainErr); err != nil {
+		logger.Info("IP marked as unhealthy", "ip", ip, "status_code", resp.StatusCode, "error", err.Error(), "error_type", typ)
+		return newProbeError(typ, err)
+	}
+	logger.Info("IP marked as healthy", "ip", ip)
+	return nil
+}
+
+func portForScheme(s string) string {
+	if strings.ToLower(s) == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,831 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestRunner_HealthyIPs(t *testing.T) {
+	tests := []struct {
+		name            string
+		ips             []string
+		httpScheme      string
+		httpPath        string
+		hostHeader      string
+		serverResponses map[string]int // IP -> status code
+		expectedHealthy []string
+		expectError     bool
+	}{
+		{
+			name:            "all IPs healthy with 200 status",
+			ips:             []string{"127.0.0.1", "127.0.0.2"},
+			httpScheme:      "http",
+			httpPath:        "/",
+			serverResponses: map[string]int{"127.0.0.1": 200, "127.0.0.2": 200},
+			expectedHealthy: []string{"127.0.0.1", "127.0.0.2"},
+			expectError:     false,
+		},
+		{
+			name:            "mixed status codes - some healthy",
+			ips:             []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"},
+			httpScheme:      "http",
+			httpPath:        "/",
+			serverResponses: map[string]int{"127.0.0.1": 200, "127.0.0.2": 404, "127.0.0.3": 201},
+			expectedHealthy: []string{"127.0.0.1", "127.0.0.3"},
+			expectError:     false,
+		},
+		{
+			name:            "all IPs unhealthy - 4xx status codes",
+			ips:             []string{"127.0.0.1", "127.0.0.2"},
+			httpScheme:      "http",
+			httpPath:        "/",
+			serverResponses: map[string]int{"127.0.0.1": 404, "127.0.0.2": 500},
+			expectedHealthy: []string{},
+			expectError:     true,
+		},
+		{
+			name:            "all IPs unhealthy - 5xx status codes",
+			ips:             []string{"127.0.0.1"},
+			httpScheme:      "http",
+			httpPath:        "/",
+			serverResponses: map[string]int{"127.0.0.1": 503},
+			expectedHealthy: []string{},
+			expectError:     true,
+		},
+		{
+			name:            "HTTP scheme with custom path",
+			ips:             []string{"127.0.0.1"},
+			httpScheme:      "http",
+			httpPath:        "/health",
+			serverResponses: map[string]int{"127.0.0.1": 200},
+			expectedHealthy: []string{"127.0.0.1"},
+			expectError:     false,
+		},
+		{
+			name:            "with Host header",
+			ips:             []string{"127.0.0.1"},
+			httpScheme:      "http",
+			httpPath:        "/",
+			hostHeader:      "example.com",
+			serverResponses: map[string]int{"127.0.0.1": 200},
+			expectedHealthy: []string{"127.0.0.1"},
+			expectError:     false,
+		},
+		{
+			name:            "empty IP list",
+			ips:             []string{},
+			httpScheme:      "http",
+			httpPath:        "/",
+			serverResponses: map[string]int{},
+			expectedHealthy: []string{},
+			expectError:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create mock servers for each IP
+			servers := make(map[string]*httptest.Server)
+			serverURLs := make(map[string]string)
+
+			for _, ip := range tt.ips {
+				statusCode := tt.serverResponses[ip]
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					// Verify Host header if specified
+					if tt.hostHeader != "" && r.Host != tt.hostHeader {
+						t.Errorf("Expected Host header %q, got %q", tt.hostHeader, r.Host)
+					}
+
+					// Verify path
+					if r.URL.Path != tt.httpPath {
+						t.Errorf("Expected path %q, got %q", tt.httpPath, r.URL.Path)
+					}
+
+					w.WriteHeader(statusCode)
+					fmt.Fprintf(w, "Response from %s", ip)
+				}))
+				servers[ip] = server
+				serverURLs[ip] = server.URL
+			}
+
+			// Create runner with mock configuration
+			runner := &Runner{
+				ips:        tt.ips,
+				httpClient: &http.Client{Timeout: 5 * time.Second},
+				urlScheme:  tt.httpScheme,
+				httpPath:   tt.httpPath,
+				hostHeader: tt.hostHeader,
+			}
+
+			// Create a testable version of HealthyIPs that uses mock servers
+			testHealthyIPs := func(ctx context.Context) ([]string, error) {
+				healthy := make([]string, 0, len(runner.ips))
+				for _, ip := range runner.ips {
+					// Use the mock server URL instead of constructing from IP
+					serverURL := serverURLs[ip]
+
+					req, _ := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+tt.httpPath, nil)
+					if runner.hostHeader != "" {
+						req.Host = runner.hostHeader
+					}
+
+					resp, err := runner.httpClient.Do(req)
+					if err != nil {
+						continue
+					}
+					_ = resp.Body.Close()
+
+					if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+						healthy = append(healthy, ip)
+					}
+				}
+				if len(healthy) == 0 {
+					return nil, fmt.Errorf("no healthy IP found")
+				}
+				return healthy, nil
+			}
+
+			// Run the test
+			ctx := context.Background()
+			healthyIPs, err := testHealthyIPs(ctx)
+
+			// Clean up servers
+			for _, server := range servers {
+				server.Close()
+			}
+
+			// Verify results
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+
+			if len(healthyIPs) != len(tt.expectedHealthy) {
+				t.Errorf("Expected %d healthy IPs, got %d", len(tt.expectedHealthy), len(healthyIPs))
+			}
+
+			// Check that all expected healthy IPs are present
+			healthyMap := make(map[string]bool)
+			for _, ip := range healthyIPs {
+				healthyMap[ip] = true
+			}
+			for _, expectedIP := range tt.expectedHealthy {
+				if !healthyMap[expectedIP] {
+					t.Errorf("Expected IP %s to be healthy but it wasn't", expectedIP)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_ConnectionErrors(t *testing.T) {
+	// Test with non-existent IPs to simulate connection errors
+	runner := &Runner{
+		ips:        []string{"192.0.2.1", "192.0.2.2"}, // RFC 5737 test addresses
+		httpClient: &http.Client{Timeout: 1 * time.Second},
+		urlScheme:  "http",
+		httpPath:   "/",
+	}
+
+	ctx := context.Background()
+	healthyIPs, _, err := runner.HealthyIPs(ctx)
+
+	if err == nil {
+		t.Errorf("Expected error for unreachable IPs, but got none")
+	}
+
+	if len(healthyIPs) != 0 {
+		t.Errorf("Expected no healthy IPs for unreachable addresses, got %d", len(healthyIPs))
+	}
+}
+
+func TestRunner_HealthyIPs_Timeout(t *testing.T) {
+	// Create a server that responds slowly
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second) // Longer than our timeout
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	// Extract IP from server URL
+	serverIP := strings.TrimPrefix(server.URL, "http://")
+	serverIP = strings.Split(serverIP, ":")[0]
+
+	runner := &Runner{
+		ips:        []string{serverIP},
+		httpClient: &http.Client{Timeout: 100 * time.Millisecond}, // Very short timeout
+		urlScheme:  "http",
+		httpPath:   "/",
+	}
+
+	// Create a testable version that uses our test server
+	testHealthyIPs := func(ctx context.Context) ([]string, error) {
+		healthy := make([]string, 0, len(runner.ips))
+		for _, ip := range runner.ips {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			resp, err := runner.httpClient.Do(req)
+			if err != nil {
+				continue
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				healthy = append(healthy, ip)
+			}
+		}
+		if len(healthy) == 0 {
+			return nil, fmt.Errorf("no healthy IP found")
+		}
+		return healthy, nil
+	}
+
+	ctx := context.Background()
+	healthyIPs, err := testHealthyIPs(ctx)
+
+	if err == nil {
+		t.Errorf("Expected timeout error, but got none")
+	}
+
+	if len(healthyIPs) != 0 {
+		t.Errorf("Expected no healthy IPs due to timeout, got %d", len(healthyIPs))
+	}
+}
+
+func TestPortForScheme(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		expected string
+	}{
+		{"https", "443"},
+		{"http", "80"},
+		{"HTTP", "80"},
+		{"HTTPS", "443"},
+		{"", "80"}, // default case
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			result := portForScheme(tt.scheme)
+			if result != tt.expected {
+				t.Errorf("portForScheme(%q) = %q, expected %q", tt.scheme, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		disableRedirects bool
+		expectHealthy    bool
+	}{
+		{name: "redirect followed", disableRedirects: false, expectHealthy: true},
+		{name: "redirect evaluated as-is", disableRedirects: true, expectHealthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Timeout: time.Second, DisableRedirects: tt.disableRedirects}
+			httpClient := opts.httpClient()
+			httpClient.Transport = newRoutedHTTPClient(server).Transport
+
+			runner := &Runner{
+				ips:        []string{"10.0.0.1"},
+				httpClient: httpClient,
+				urlScheme:  "http",
+				httpPath:   "/",
+			}
+
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if tt.expectHealthy && (err != nil || len(healthy) != 1) {
+				t.Errorf("Expected IP to be healthy, got %v (err: %v)", healthy, err)
+			}
+			if !tt.expectHealthy && err == nil {
+				t.Errorf("Expected IP to be unhealthy, got %v", healthy)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_ProbeStagger(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	stagger := 100 * time.Millisecond
+	// free probe slots must not let probes start ahead of the stagger
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			starts = nil
+			runner := &Runner{
+				ips:              []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+				httpClient:       newRoutedHTTPClient(server),
+				urlScheme:        "http",
+				httpPath:         "/",
+				probeStagger:     stagger,
+				probeConcurrency: concurrency,
+			}
+
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if err != nil || len(healthy) != 3 {
+				t.Fatalf("Expected 3 healthy IPs, got %v (err: %v)", healthy, err)
+			}
+
+			if len(starts) != 3 {
+				t.Fatalf("Expected 3 probes, got %d", len(starts))
+			}
+			for i := 1; i < len(starts); i++ {
+				gap := starts[i].Sub(starts[i-1])
+				if gap < stagger-10*time.Millisecond || gap > 3*stagger {
+					t.Errorf("Expected probe %d to start ~%s after the previous one, got %s", i, stagger, gap)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_PartialOnDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			<-r.Context().Done()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		httpClient: newRoutedHTTPClient(server),
+		urlScheme:  "http",
+		httpPath:   "/",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	healthy, partial, err := runner.HealthyIPs(ctx)
+	if err != nil || !slices.Equal(healthy, []string{"10.0.0.1"}) {
+		t.Errorf("Expected the IP confirmed before the deadline, got %v (err: %v)", healthy, err)
+	}
+	if !partial {
+		t.Error("Expected the result to be marked partial")
+	}
+}
+
+func TestRunner_HealthyIPs_ProbeStaggerHonorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:          []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:   newRoutedHTTPClient(server),
+		urlScheme:    "http",
+		httpPath:     "/",
+		probeStagger: time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	healthy, partial, err := runner.HealthyIPs(ctx)
+	if err != nil || len(healthy) != 1 || healthy[0] != "10.0.0.1" {
+		t.Errorf("Expected only the first IP to be probed, got %v (err: %v)", healthy, err)
+	}
+	if !partial {
+		t.Error("Expected the result to be marked partial")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected stagger wait to stop at the context deadline, took %s", elapsed)
+	}
+}
+
+func TestRunner_HealthyIPs_StopAfterHealthy(t *testing.T) {
+	tests := []struct {
+		name             string
+		stopAfterHealthy int
+		expectedRequests int32
+		expectedHealthy  []string
+	}{
+		{name: "probe all by default", stopAfterHealthy: 0, expectedRequests: 5, expectedHealthy: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}},
+		{name: "stop after two healthy", stopAfterHealthy: 2, expectedRequests: 3, expectedHealthy: []string{"10.0.0.2", "10.0.0.3"}},
+		{name: "limit above available", stopAfterHealthy: 10, expectedRequests: 5, expectedHealthy: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if strings.HasPrefix(r.Host, "10.0.0.1") {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			runner := &Runner{
+				ips:              []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"},
+				httpClient:       newRoutedHTTPClient(server),
+				urlScheme:        "http",
+				httpPath:         "/",
+				stopAfterHealthy: tt.stopAfterHealthy,
+			}
+
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(healthy, tt.expectedHealthy) {
+				t.Errorf("Expected healthy %v, got %v", tt.expectedHealthy, healthy)
+			}
+			if got := requests.Load(); got != tt.expectedRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectedRequests, got)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_ProbeConcurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "10.0.0.1") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// the IPs ahead of the fast one hang until their probe is cancelled
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:              []string{"10.0.0.2", "10.0.0.3", "10.0.0.1", "10.0.0.4", "10.0.0.5"},
+		httpClient:       newRoutedHTTPClient(server),
+		urlScheme:        "http",
+		httpPath:         "/",
+		timeout:          10 * time.Second,
+		probeConcurrency: 3,
+		stopAfterHealthy: 1,
+	}
+
+	start := time.Now()
+	healthy, failures, latencies, partial := runner.probeAllTimed(context.Background())
+	// one at a time, the first slow IP alone would take 5s
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the fast IP to be probed alongside the slow ones and the rest cancelled, took %s", elapsed)
+	}
+	if !slices.Equal(healthy, []string{"10.0.0.1"}) {
+		t.Errorf("Expected only the fast IP to be healthy, got %v", healthy)
+	}
+	if len(failures) != 0 || len(latencies) != 1 {
+		t.Errorf("Expected the cancelled probes to count as not probed, got failures %v, latencies %v", failures, latencies)
+	}
+	if partial {
+		t.Error("Expected stopping after enough healthy IPs not to be partial")
+	}
+}
+
+func TestRunner_HealthyIPs_ProbeConcurrencyKeepsOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// earlier IPs answer later
+		if strings.HasPrefix(r.Host, "10.0.0.1") {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if strings.HasPrefix(r.Host, "10.0.0.3") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:              []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		httpClient:       newRoutedHTTPClient(server),
+		urlScheme:        "http",
+		httpPath:         "/",
+		probeConcurrency: 4,
+	}
+
+	healthy, failures := runner.probeAll(context.Background())
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.4"}; !slices.Equal(healthy, want) {
+		t.Errorf("Expected healthy %v in configured order, got %v", want, healthy)
+	}
+	if _, ok := failures["10.0.0.3"]; !ok || len(failures) != 1 {
+		t.Errorf("Expected only 10.0.0.3 to fail, got %v", failures)
+	}
+}
+
+func TestRunner_HealthyIPs_PostBody(t *testing.T) {
+	const body = `{"check":"deep"}`
+
+	type received struct{ method, contentType, body string }
+	var (
+		mu   sync.Mutex
+		reqs []received
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, received{r.Method, r.Header.Get("Content-Type"), string(b)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runner, err := New(Options{
+		IPs:              []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		ProbeMethod:      http.MethodPost,
+		ProbeBodyFile:    bodyFile,
+		ProbeContentType: "application/vnd.health+json",
+		HTTPClient:       newRoutedHTTPClient(server),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	healthy, _, err := runner.HealthyIPs(context.Background())
+	if err != nil || len(healthy) != 3 {
+		t.Fatalf("Expected all IPs healthy, got %v (%v)", healthy, err)
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(reqs))
+	}
+	for i, got := range reqs {
+		want := received{http.MethodPost, "application/vnd.health+json", body}
+		if got != want {
+			t.Errorf("Request %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestRunner_HealthyIPs_BasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "prober" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	passFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		user        string
+		pass        string
+		passFile    string
+		expectError bool
+	}{
+		{name: "correct credentials", user: "prober", pass: "s3cret"},
+		{name: "password from file", user: "prober", passFile: passFile},
+		{name: "wrong password", user: "prober", pass: "guess", expectError: true},
+		{name: "no credentials", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := New(Options{
+				IPs:                    []string{"10.0.0.1"},
+				ProbeBasicAuthUser:     tt.user,
+				ProbeBasicAuthPass:     tt.pass,
+				ProbeBasicAuthPassFile: tt.passFile,
+				HTTPClient:             newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			logs := &logCapture{}
+			ctx := log.IntoContext(context.Background(), logs.logger())
+
+			_, _, err = runner.HealthyIPs(ctx)
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+			if tt.pass != "" && logs.contains(tt.pass) {
+				t.Errorf("Expected the password to stay out of the logs")
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_ExpectHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		respHeaders map[string]string
+		expect      []string
+		expectError bool
+	}{
+		{name: "matching header", respHeaders: map[string]string{"X-Health": "ok"}, expect: []string{"X-Health=ok"}},
+		{name: "case-insensitive name", respHeaders: map[string]string{"X-Health": "ok"}, expect: []string{"x-health=ok"}},
+		{name: "all headers match", respHeaders: map[string]string{"X-Health": "ok", "X-Role": "primary"}, expect: []string{"X-Health=ok", "X-Role=primary"}},
+		{name: "value mismatch", respHeaders: map[string]string{"X-Health": "degraded"}, expect: []string{"X-Health=ok"}, expectError: true},
+		{name: "value case differs", respHeaders: map[string]string{"X-Health": "OK"}, expect: []string{"X-Health=ok"}, expectError: true},
+		{name: "one of several missing", respHeaders: map[string]string{"X-Health": "ok"}, expect: []string{"X-Health=ok", "X-Role=primary"}, expectError: true},
+		{name: "header absent", expect: []string{"X-Health=ok"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.respHeaders {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			runner, err := New(Options{
+				IPs:           []string{"10.0.0.1"},
+				ExpectHeaders: tt.expect,
+				HTTPClient:    newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, failures := runner.probeAll(context.Background())
+			err = failures["10.0.0.1"]
+			if tt.expectError != (err != nil) {
+				t.Fatalf("Unexpected error state: %v", err)
+			}
+			if err != nil {
+				if got := classifyProbeError(err); got != ErrorTypeHeaderMismatch {
+					t.Errorf("Expected error type %q, got %q", ErrorTypeHeaderMismatch, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateExpectHeaders(t *testing.T) {
+	for _, spec := range []string{"X-Health", "=ok", " =ok"} {
+		opts := Options{IPs: []string{"10.0.0.1"}, ExpectHeaders: []string{spec}}
+		if err := opts.validate(); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+	opts := Options{IPs: []string{"10.0.0.1"}, ExpectHeaders: []string{"X-Health=", "X-Role=a=b"}}
+	if err := opts.validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunner_HealthyIPs_ExpectTrailers(t *testing.T) {
+	tests := []struct {
+		name        string
+		trailer     string
+		bodySize    int
+		expect      []string
+		expectError bool
+	}{
+		{name: "matching trailer", trailer: "ok", bodySize: 64 << 10, expect: []string{"X-Health=ok"}},
+		{name: "case-insensitive name", trailer: "ok", expect: []string{"x-health=ok"}},
+		{name: "trailer mismatch", trailer: "degraded", expect: []string{"X-Health=ok"}, expectError: true},
+		{name: "trailer missing", expect: []string{"X-Health=ok"}, expectError: true},
+		{name: "body over the limit", trailer: "ok", bodySize: maxProbeBodyBytes + 1, expect: []string{"X-Health=ok"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "X-Health")
+				w.WriteHeader(http.StatusOK)
+				body := strings.Repeat("x", tt.bodySize)
+				// flush in chunks so the response is sent chunked
+				for len(body) > 0 {
+					n := min(len(body), 32<<10)
+					_, _ = io.WriteString(w, body[:n])
+					w.(http.Flusher).Flush()
+					body = body[n:]
+				}
+				if tt.trailer != "" {
+					w.Header().Set("X-Health", tt.trailer)
+				}
+			}))
+			defer server.Close()
+
+			runner, err := New(Options{
+				IPs:            []string{"10.0.0.1"},
+				ExpectTrailers: tt.expect,
+				HTTPClient:     newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, failures := runner.probeAll(context.Background())
+			if err := failures["10.0.0.1"]; tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunner_HealthyIPs_PathWithQuery(t *testing.T) {
+	type received struct{ host, requestURI string }
+	var (
+		mu   sync.Mutex
+		reqs []received
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqs = append(reqs, received{r.Host, r.RequestURI})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner, err := New(Options{
+		IPs:        []string{"10.0.0.1", "2001:db8::1"},
+		HTTPPath:   "/health?verbose=1&name=a%20b",
+		HTTPClient: newRoutedHTTPClient(server),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	healthy, _, err := runner.HealthyIPs(context.Background())
+	if err != nil || len(healthy) != 2 {
+		t.Fatalf("Expected all IPs healthy, got %v (%v)", healthy, err)
+	}
+	want := []received{
+		{"10.0.0.1:80", "/health?verbose=1&name=a%20b"},
+		{"[2001:db8::1]:80", "/health?verbose=1&name=a%20b"},
+	}
+	if !slices.Equal(reqs, want) {
+		t.Errorf("Expected requests %+v, got %+v", want, reqs)
+	}
+}
+
+func TestOptions_ValidateHTTPPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		expectError bool
+	}{
+		{path: "/"},
+		{path: "/health?verbose=1"},
+		{path: "/a%2Fb"},
+		{path: "/health#status", expectError: true},
+		{path: "/health?x=1#", expectError: true},
+		{path: "health", expectError: true},
+		{path: "http://example.com/health", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			opts := Options{IPs: []string{"10.0.0.1"}, HTTPPath: tt.path}
+			err := opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
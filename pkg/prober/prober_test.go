@@ -0,0 +1,77 @@
+package prober_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/b1r3k/ingress-target-prober/pkg/prober"
+)
+
+func TestNew_RequiresIPs(t *testing.T) {
+	if _, err := prober.New(prober.Options{}); err == nil {
+		t.Errorf("Expected error when no IPs are configured, got none")
+	}
+}
+
+func TestNew_ProbeOnlyWithoutClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "example.com" {
+			t.Errorf("Expected Host header %q, got %q", "example.com", r.Host)
+		}
+		if r.URL.Path != "/healthz" {
+			t.Errorf("Expected path %q, got %q", "/healthz", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := &net.Dialer{}
+	httpClient := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		},
+	}
+
+	r, err := prober.New(prober.Options{
+		IPs:        []string{"10.0.0.1", "10.0.0.2"},
+		HTTPPath:   "/healthz",
+		HostHeader: "example.com",
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	healthy, _, err := r.HealthyIPs(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(healthy) != 2 || healthy[0] != "10.0.0.1" || healthy[1] != "10.0.0.2" {
+		t.Errorf("Expected [10.0.0.1 10.0.0.2], got %v", healthy)
+	}
+}
+
+func TestNew_DefaultHTTPClientHonorsTimeout(t *testing.T) {
+	r, err := prober.New(prober.Options{
+		IPs:     []string{"192.0.2.1"}, // RFC 5737 test address
+		Timeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if _, _, err := r.HealthyIPs(context.Background()); err == nil {
+		t.Errorf("Expected error for unreachable IP, got none")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected probe to give up after the configured timeout, took %s", elapsed)
+	}
+}
@@ -0,0 +1,127 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	publishTimeout    = 5 * time.Second
+	publishAttempts   = 3
+	publishRetryDelay = 500 * time.Millisecond
+)
+
+// PublishPayload is PUT to the publish URL with the healthy set of a tick.
+type PublishPayload struct {
+	Healthy   []string  `json:"healthy"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type publisher struct {
+	url        string
+	header     http.Header
+	httpClient *http.Client
+	retryDelay time.Duration
+}
+
+// parsePublishHeaders parses "Name=Value" entries, such as an Authorization
+// header, sent with every publish request.
+func parsePublishHeaders(specs []string) (http.Header, error) {
+	h := http.Header{}
+	for _, s := range specs {
+		name, value, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid publish header %q (want Name=Value)", s)
+		}
+		h.Add(name, value)
+	}
+	return h, nil
+}
+
+// validatePublish checks the publish URL and headers; publish-only needs a URL.
+func (o *Options) validatePublish() error {
+	if o.PublishURL == "" {
+		if o.PublishOnly {
+			return fmt.Errorf("publish-only requires a publish URL")
+		}
+		return nil
+	}
+	u, err := url.Parse(o.PublishURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid publish URL %q", o.PublishURL)
+	}
+	_, err = parsePublishHeaders(o.PublishHeaders)
+	return err
+}
+
+func newPublisher(url string, headers []string) *publisher {
+	if url == "" {
+		return nil
+	}
+	// validated by Options.validatePublish
+	h, _ := parsePublishHeaders(headers)
+	return &publisher{
+		url:        url,
+		header:     h,
+		httpClient: &http.Client{Timeout: publishTimeout},
+		retryDelay: publishRetryDelay,
+	}
+}
+
+// put sends payload, retrying a bounded number of times on errors and non-2xx responses.
+func (p *publisher) put(ctx context.Context, payload PublishPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= publishAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.retryDelay):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		for name, values := range p.header {
+			req.Header[name] = values
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("publish endpoint returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
+
+// publish PUTs the healthy set to the publish URL, logging a failed delivery.
+func (r *Runner) publish(ctx context.Context, healthyIPs []string) error {
+	payload := PublishPayload{Healthy: healthyIPs, Timestamp: time.Now().UTC()}
+	if err := r.publisher.put(ctx, payload); err != nil {
+		log.FromContext(ctx).Error(err, "failed to publish healthy IPs", "url", r.publisher.url)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_Publish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for _, publishOnly := range []bool{false, true} {
+		name := "publish and annotate"
+		if publishOnly {
+			name = "publish only"
+		}
+		t.Run(name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				attempts int
+				payload  PublishPayload
+				method   string
+				auth     string
+			)
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				method, auth = r.Method, r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("failed to decode payload: %v", err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer endpoint.Close()
+
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).Build()
+			runner, err := New(Options{
+				Client:         k8s,
+				AnnotationKey:  "new.example.com/target",
+				IPs:            []string{"10.0.0.1", "10.0.0.2"},
+				HTTPClient:     newRoutedHTTPClient(server),
+				PublishURL:     endpoint.URL,
+				PublishHeaders: []string{"Authorization=Bearer secret"},
+				PublishOnly:    publishOnly,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			runner.publisher.retryDelay = 0
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			if attempts != 2 {
+				t.Errorf("Expected the failed PUT to be retried once, got %d attempts", attempts)
+			}
+			if method != http.MethodPut {
+				t.Errorf("Expected a PUT, got %s", method)
+			}
+			if auth != "Bearer secret" {
+				t.Errorf("Expected the configured Authorization header, got %q", auth)
+			}
+			if !slices.Equal(payload.Healthy, []string{"10.0.0.1", "10.0.0.2"}) || payload.Timestamp.IsZero() {
+				t.Errorf("Unexpected payload %+v", payload)
+			}
+			mu.Unlock()
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			value, annotated := got.Annotations["new.example.com/target"]
+			if publishOnly && annotated {
+				t.Errorf("Expected no annotation in publish-only mode, got %q", value)
+			}
+			if !publishOnly && value != "10.0.0.1,10.0.0.2" {
+				t.Errorf("Expected the annotation to still be written, got %q", value)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidatePublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"no publishing", Options{}, false},
+		{"publish", Options{PublishURL: "https://kv.example.com/healthy", PublishHeaders: []string{"Authorization=Bearer x"}}, false},
+		{"publish only without URL", Options{PublishOnly: true}, true},
+		{"invalid URL", Options{PublishURL: "kv.example.com"}, true},
+		{"invalid header", Options{PublishURL: "https://kv.example.com", PublishHeaders: []string{"Authorization"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
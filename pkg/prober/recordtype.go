@@ -0,0 +1,40 @@
+package prober
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// RecordTypeAnnotationKey carries the DNS record type hint written next to the
+// target annotation when a record type is configured.
+const RecordTypeAnnotationKey = "external-dns.alpha.kubernetes.io/record-type"
+
+// validateRecordType accepts "", A, AAAA and CNAME.
+func validateRecordType(t string) error {
+	switch strings.ToUpper(t) {
+	case "", "A", "AAAA", "CNAME":
+		return nil
+	}
+	return fmt.Errorf("unsupported record type %q (want A, AAAA or CNAME)", t)
+}
+
+// checkRecordFamily verifies targets match the address family of recordType:
+// IPv4 for A, IPv6 for AAAA. CNAME targets are not checked.
+func checkRecordFamily(targets []string, recordType string) error {
+	var want4 bool
+	switch recordType {
+	case "A":
+		want4 = true
+	case "AAAA":
+	default:
+		return nil
+	}
+	for _, t := range targets {
+		a, err := netip.ParseAddr(t)
+		if err != nil || a.Unmap().Is4() != want4 {
+			return fmt.Errorf("target %q does not match record type %s", t, recordType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunner_Tick_RecordTypeAnnotation(t *testing.T) {
+	var down atomic.Value
+	down.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		if host == down.Load().(string) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var patches atomic.Int32
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("with-target", map[string]string{
+			"kubernetes.io/ingress.class": "public-nginx",
+			"new.example.com/target":      "10.0.0.1,10.0.0.2",
+		}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches.Add(1)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	runner, err := New(Options{
+		Client:        k8s,
+		AnnotationKey: "new.example.com/target",
+		RecordType:    "a",
+		IPs:           []string{"10.0.0.1", "10.0.0.2"},
+		HTTPClient:    newRoutedHTTPClient(server),
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	steps := []struct {
+		name           string
+		down           string
+		expectedTarget string
+		expectedPatch  int32
+	}{
+		// the target is already current but the hint is missing
+		{name: "adds hint", expectedTarget: "10.0.0.1,10.0.0.2", expectedPatch: 1},
+		{name: "no-op", expectedTarget: "10.0.0.1,10.0.0.2", expectedPatch: 0},
+		{name: "target change", down: "10.0.0.2", expectedTarget: "10.0.0.1", expectedPatch: 1},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			down.Store(step.down)
+			before := patches.Load()
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := patches.Load() - before; got != step.expectedPatch {
+				t.Errorf("Expected %d patches, got %d", step.expectedPatch, got)
+			}
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "with-target"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if got.Annotations["new.example.com/target"] != step.expectedTarget || got.Annotations[RecordTypeAnnotationKey] != "A" {
+				t.Errorf("Unexpected annotations %v", got.Annotations)
+			}
+		})
+	}
+}
+
+func TestNew_RecordTypeFamily(t *testing.T) {
+	tests := []struct {
+		name        string
+		recordType  string
+		ips         []string
+		expectError bool
+	}{
+		{name: "A with IPv4", recordType: "A", ips: []string{"10.0.0.1"}},
+		{name: "A with IPv6", recordType: "A", ips: []string{"10.0.0.1", "2001:db8::1"}, expectError: true},
+		{name: "AAAA with IPv6", recordType: "AAAA", ips: []string{"2001:db8::1"}},
+		{name: "AAAA with IPv4", recordType: "AAAA", ips: []string{"10.0.0.1"}, expectError: true},
+		{name: "CNAME", recordType: "CNAME", ips: []string{"10.0.0.1", "2001:db8::1"}},
+		{name: "unsupported", recordType: "MX", ips: []string{"10.0.0.1"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Options{IPs: tt.ips, RecordType: tt.recordType})
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
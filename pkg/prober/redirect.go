@@ -0,0 +1,48 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects matches the default policy of net/http.
+const maxRedirects = 10
+
+// probeClient returns the client for a probe over scheme. With
+// redirectToHTTPSHealthy, plain HTTP probes stop at a redirect to https so
+// that it can be inspected; other redirects are followed as before.
+func (r *Runner) probeClient(scheme string) *http.Client {
+	if !r.redirectToHTTPSHealthy || strings.ToLower(scheme) != "http" {
+		return r.httpClient
+	}
+	c := *r.httpClient
+	next := c.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme == "https" && via[len(via)-1].URL.Scheme == "http" {
+			return http.ErrUseLastResponse
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	return &c
+}
+
+// httpsRedirect returns the target of resp when it is a 301 or 302 to an
+// https URL.
+func httpsRedirect(resp *http.Response) (string, bool) {
+	if resp.StatusCode != http.StatusMovedPermanently && resp.StatusCode != http.StatusFound {
+		return "", false
+	}
+	// resolved against the request URL, so a relative Location stays on http
+	loc, err := resp.Location()
+	if err != nil || loc.Scheme != "https" {
+		return "", false
+	}
+	return loc.String(), true
+}
@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunner_HealthyIPs_RedirectToHTTPSHealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		switch {
+		case r.URL.Path == "/ok":
+			w.WriteHeader(http.StatusOK)
+		case host == "10.0.0.1":
+			http.Redirect(w, r, "https://www.example.com/", http.StatusMovedPermanently)
+		case host == "10.0.0.2":
+			http.Redirect(w, r, "https://www.example.com/login", http.StatusFound)
+		case host == "10.0.0.3":
+			// stays on http and is followed as usual
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case host == "10.0.0.4":
+			http.Redirect(w, r, "https://www.example.com/", http.StatusTemporaryRedirect)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		enabled          bool
+		disableRedirects bool
+		expected         []string
+	}{
+		{name: "disabled", expected: []string{"10.0.0.3"}},
+		{name: "enabled", enabled: true, expected: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{name: "enabled without following redirects", enabled: true, disableRedirects: true, expected: []string{"10.0.0.1", "10.0.0.2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRoutedHTTPClient(server)
+			if tt.disableRedirects {
+				client.CheckRedirect = func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				}
+			}
+			runner := &Runner{
+				ips:                    []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"},
+				httpClient:             client,
+				urlScheme:              "http",
+				httpPath:               "/",
+				timeout:                time.Second,
+				redirectToHTTPSHealthy: tt.enabled,
+			}
+			healthy, _, _ := runner.HealthyIPs(context.Background())
+			if !slices.Equal(healthy, tt.expected) {
+				t.Errorf("Expected healthy %v, got %v", tt.expected, healthy)
+			}
+		})
+	}
+}
+
+func TestRunner_ProbeClient_KeepsSharedClient(t *testing.T) {
+	client := &http.Client{}
+	runner := &Runner{httpClient: client, redirectToHTTPSHealthy: true}
+	if got := runner.probeClient("https"); got != client {
+		t.Error("Expected https probes to use the shared client")
+	}
+	if got := runner.probeClient("http"); got == client || client.CheckRedirect != nil {
+		t.Error("Expected http probes to use a copy without modifying the shared client")
+	}
+}
+
+func TestOptions_ValidateRedirectToHTTPSHealthy(t *testing.T) {
+	opts := Options{IPs: []string{"10.0.0.1"}, RedirectToHTTPSHealthy: true, ProbeMode: ProbeModeTCP, ProbePorts: []string{"80"}}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected HTTPS redirects as healthy to be rejected outside the http probe mode")
+	}
+}
@@ -0,0 +1,192 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// currentIPs returns the target list in effect for the next probe cycle.
+func (r *Runner) currentIPs() []string {
+	r.ipsMu.RLock()
+	defer r.ipsMu.RUnlock()
+	return r.ips
+}
+
+// parseIPList splits s on commas and whitespace, skipping blank entries and
+// lines starting with '#'.
+func parseIPList(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, f := range strings.FieldsFunc(line, func(c rune) bool { return c == ',' || c == ' ' || c == '\t' }) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// readIPsFile loads and validates the target entries from path.
+func readIPsFile(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseIPList(string(b))
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s contains no IPs", path)
+	}
+	ts, err := parseTargets(entries)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, ip := range ts.ips {
+		if !validIP(ip) {
+			return nil, fmt.Errorf("%s: invalid IP %q", path, ip)
+		}
+	}
+	return entries, nil
+}
+
+// ReloadIPs re-reads the IPs file and swaps the target list in for subsequent
+// ticks. Targets the file added are probed right away, see probeAddedTargets.
+// An invalid file leaves the current list in place.
+func (r *Runner) ReloadIPs(ctx context.Context) error {
+	if r.ipsFile == "" {
+		return fmt.Errorf("no IPs file configured")
+	}
+	entries, err := readIPsFile(r.ipsFile)
+	if err != nil {
+		return err
+	}
+	ts, err := parseTargets(entries)
+	if err != nil {
+		return err
+	}
+	if ts, err = r.prepareTargets(ctx, ts); err != nil {
+		return err
+	}
+
+	old := r.setTargets(ts)
+	if slices.Equal(old, ts.ips) {
+		return nil
+	}
+	log.FromContext(ctx).Info("reloaded target IPs", "file", r.ipsFile, "old", strings.Join(old, ","), "new", strings.Join(ts.ips, ","))
+	return r.probeAddedTargets(ctx, old)
+}
+
+// probeAddedTargets probes the targets missing from old right away and
+// writes them together with the cached results of the unchanged targets,
+// which are only re-probed on the next regular tick. Removed targets drop out
+// of the healthy set. Without a completed tick there is nothing cached and the
+// next tick probes everything.
+func (r *Runner) probeAddedTargets(ctx context.Context, old []string) error {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+
+	ips := r.currentIPs()
+	var added []string
+	for _, ip := range ips {
+		if !slices.Contains(old, ip) {
+			added = append(added, ip)
+		}
+	}
+	r.mu.Lock()
+	observed, cached := r.observed, slices.Clone(r.lastHealthy)
+	r.mu.Unlock()
+	if !observed || len(added) == 0 {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+	logger.Info("probing targets added by reload", "added", strings.Join(added, ","))
+	probeCtx, cancel := context.WithTimeout(ctx, r.timeout*time.Duration(len(added))+r.probeStagger*time.Duration(len(added)-1))
+	defer cancel()
+	addedHealthy, failures, latencies, _ := r.probeIPs(probeCtx, added)
+
+	healthy := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if slices.Contains(addedHealthy, ip) || (!slices.Contains(added, ip) && slices.Contains(cached, ip)) {
+			healthy = append(healthy, ip)
+		}
+	}
+	r.mu.Lock()
+	for ip := range r.lastErrors {
+		if !slices.Contains(ips, ip) || slices.Contains(added, ip) {
+			delete(r.lastErrors, ip)
+		}
+	}
+	if r.lastErrors == nil && len(failures) > 0 {
+		r.lastErrors = make(map[string]string, len(failures))
+	}
+	for ip, err := range failures {
+		r.lastErrors[ip] = classifyProbeError(err)
+	}
+	r.setHealthy(ctx, healthy)
+	r.mu.Unlock()
+	if len(healthy) == 0 {
+		// left to the next tick's no-healthy handling
+		return nil
+	}
+	r.trackFamilies(logger, healthy)
+	return r.writeHealthy(ctx, healthy, latencies)
+}
+
+// watchIPsFile reloads the IPs file on SIGHUP and whenever its directory
+// changes (which also covers ConfigMap volume symlink swaps).
+func (r *Runner) watchIPsFile(ctx context.Context) {
+	logger := log.FromContext(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Error(err, "unable to watch IPs file; reload only on SIGHUP", "file", r.ipsFile)
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(filepath.Dir(r.ipsFile)); err != nil {
+			logger.Error(err, "unable to watch IPs file; reload only on SIGHUP", "file", r.ipsFile)
+		} else {
+			events = watcher.Events
+		}
+	}
+
+	reload := func(trigger string) {
+		if err := r.ReloadIPs(ctx); err != nil {
+			logger.Error(err, "failed to reload target IPs; keeping current list", "file", r.ipsFile, "trigger", trigger)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload("sighup")
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if ev.Has(fsnotify.Chmod) {
+				continue
+			}
+			reload("fsnotify")
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseIPList(t *testing.T) {
+	got := parseIPList("# targets\n10.0.0.1, 10.0.0.2\n\n10.0.0.3\t10.0.0.4\n")
+	expected := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRunner_ReloadIPs(t *testing.T) {
+	var mu sync.Mutex
+	probed := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		probed[host] = true
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	probedIPs := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := make([]string, 0, len(probed))
+		for ip := range probed {
+			out = append(out, ip)
+		}
+		sort.Strings(out)
+		probed = map[string]bool{}
+		return out
+	}
+
+	path := filepath.Join(t.TempDir(), "ips")
+	if err := os.WriteFile(path, []byte("10.0.0.1,10.0.0.2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := New(Options{IPsFile: path, HTTPClient: newRoutedHTTPClient(server), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	_ = runner.tick(ctx)
+	if got := probedIPs(); !slices.Equal(got, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("Expected initial targets to be probed, got %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("10.0.0.3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.ReloadIPs(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_ = runner.tick(ctx)
+	if got := probedIPs(); !slices.Equal(got, []string{"10.0.0.3"}) {
+		t.Errorf("Expected reloaded targets to be probed, got %v", got)
+	}
+}
+
+func TestRunner_ReloadIPs_InvalidKeepsCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips")
+	if err := os.WriteFile(path, []byte("10.0.0.1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner, err := New(Options{IPsFile: path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, content := range []string{"", "# nothing\n", "10.0.0.2,not-an-ip"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := runner.ReloadIPs(context.Background()); err == nil {
+			t.Errorf("Expected error reloading %q, got none", content)
+		}
+		if got := runner.currentIPs(); !slices.Equal(got, []string{"10.0.0.1"}) {
+			t.Errorf("Expected current targets to be kept, got %v", got)
+		}
+	}
+}
+
+func TestRunner_WatchIPsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips")
+	if err := os.WriteFile(path, []byte("10.0.0.1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner, err := New(Options{IPsFile: path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.watchIPsFile(ctx)
+
+	// give the watcher a moment to register before changing the file
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("10.0.0.5\n10.0.0.6"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if slices.Equal(runner.currentIPs(), []string{"10.0.0.5", "10.0.0.6"}) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("Expected watcher to reload targets, got %v", runner.currentIPs())
+}
+
+func TestRunner_ReloadIPs_ProbesOnlyAddedTargets(t *testing.T) {
+	var mu sync.Mutex
+	var probed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		probed = append(probed, host)
+		mu.Unlock()
+		if host == "10.0.0.4" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	probedIPs := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := probed
+		probed = nil
+		sort.Strings(out)
+		return out
+	}
+
+	path := filepath.Join(t.TempDir(), "ips")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10.0.0.1,10.0.0.2\n")
+
+	const key = "new.example.com/target"
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:        k8s,
+		AnnotationKey: key,
+		IPsFile:       path,
+		HTTPClient:    newRoutedHTTPClient(server),
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	annotation := func() string {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations[key]
+	}
+
+	// before the first tick nothing is cached, so a reload probes nothing
+	write("10.0.0.1,10.0.0.2,10.0.0.5\n")
+	if err := runner.ReloadIPs(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := probedIPs(); len(got) != 0 {
+		t.Errorf("Expected no out-of-cycle probes before the first tick, got %v", got)
+	}
+
+	write("10.0.0.1,10.0.0.2\n")
+	if err := runner.ReloadIPs(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	probedIPs()
+
+	write("10.0.0.2,10.0.0.3,10.0.0.4\n")
+	if err := runner.ReloadIPs(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := probedIPs(); !slices.Equal(got, []string{"10.0.0.3", "10.0.0.4"}) {
+		t.Errorf("Expected only the added targets to be probed on reload, got %v", got)
+	}
+	if got := annotation(); got != "10.0.0.2,10.0.0.3" {
+		t.Errorf("Expected the cached and newly healthy targets to be written, got %q", got)
+	}
+	st := runner.Status()
+	if !slices.Equal(st.Healthy, []string{"10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("Unexpected healthy set in status: %v", st.Healthy)
+	}
+	if st.Errors["10.0.0.4"] != ErrorTypeHTTPStatus {
+		t.Errorf("Expected the failed added target in status, got %v", st.Errors)
+	}
+
+	if err := runner.tick(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := probedIPs(); !slices.Equal(got, []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}) {
+		t.Errorf("Expected the next tick to probe every target, got %v", got)
+	}
+}
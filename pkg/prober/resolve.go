@@ -0,0 +1,83 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// dnsServerAddress returns server as ip:port, defaulting to DefaultDNSPort.
+// The server must be an IP address so it can be reached without resolving.
+func dnsServerAddress(server string) (string, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, DefaultDNSPort
+	}
+	if _, err := netip.ParseAddr(host); err != nil {
+		return "", fmt.Errorf("invalid DNS server %q: want an IP address with optional port", server)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// targetResolver returns the resolver used for hostname targets: DNSServer
+// when set, the system resolver otherwise.
+func (o *Options) targetResolver() dnsLookup {
+	if o.DNSServer == "" {
+		return net.DefaultResolver
+	}
+	// validate has already checked the address
+	addr, _ := dnsServerAddress(o.DNSServer)
+	return newServerResolver(addr)
+}
+
+// resolveHostnames replaces hostname targets with the addresses they resolve
+// to. Resolved addresses inherit the entry's labels and probe address; an
+// address already present is kept once. IP targets pass through unchanged.
+func (r *Runner) resolveHostnames(ctx context.Context, ts targetSet) (targetSet, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	out := targetSet{ips: make([]string, 0, len(ts.ips))}
+	seen := make(map[string]struct{}, len(ts.ips))
+	add := func(ip, entry string) {
+		if _, dup := seen[ip]; dup {
+			return
+		}
+		seen[ip] = struct{}{}
+		out.ips = append(out.ips, ip)
+		if l, ok := ts.labels[entry]; ok {
+			if out.labels == nil {
+				out.labels = map[string]map[string]string{}
+			}
+			out.labels[ip] = l
+		}
+		if p, ok := ts.probeAddrs[entry]; ok {
+			if out.probeAddrs == nil {
+				out.probeAddrs = map[string]string{}
+			}
+			out.probeAddrs[ip] = p
+		}
+	}
+
+	for _, entry := range ts.ips {
+		if _, err := netip.ParseAddr(entry); err == nil {
+			add(entry, entry)
+			continue
+		}
+		ips, err := r.targetResolver.LookupIP(ctx, "ip", entry)
+		if err != nil {
+			return targetSet{}, fmt.Errorf("resolving target %q: %w", entry, err)
+		}
+		if len(ips) == 0 {
+			return targetSet{}, fmt.Errorf("target %q resolved to no addresses", entry)
+		}
+		for _, ip := range ips {
+			add(ip.String(), entry)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package prober
+
+import (
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startStubDNS serves A records from answers over UDP and counts the queries
+// it receives.
+func startStubDNS(t *testing.T, answers map[string][]string) (string, *atomic.Int32) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+			queries.Add(1)
+			q := msg.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: msg.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeSuccess},
+				Questions: msg.Questions,
+			}
+			ips, ok := answers[q.Name.String()]
+			if !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+			}
+			if q.Type == dnsmessage.TypeA {
+				for _, ip := range ips {
+					var a [4]byte
+					copy(a[:], net.ParseIP(ip).To4())
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.AResource{A: a},
+					})
+				}
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(out, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestNew_ResolveTargetsViaDNSServer(t *testing.T) {
+	server, queries := startStubDNS(t, map[string][]string{
+		"web.example.": {"10.0.0.1", "10.0.0.2"},
+	})
+
+	runner, err := New(Options{
+		IPs:            []string{"web.example;region=eu", "10.0.0.2", "10.0.0.9"},
+		ResolveTargets: true,
+		DNSServer:      server,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.9"}; !slices.Equal(runner.ips, want) {
+		t.Errorf("Expected targets %v, got %v", want, runner.ips)
+	}
+	if got := runner.targetLabel("10.0.0.1", RegionLabel); got != "eu" {
+		t.Errorf("Expected resolved target to inherit region label, got %q", got)
+	}
+	if queries.Load() == 0 {
+		t.Error("Expected the configured DNS server to be queried")
+	}
+}
+
+func TestNew_ResolveTargetsUnknownHost(t *testing.T) {
+	server, _ := startStubDNS(t, nil)
+
+	_, err := New(Options{
+		IPs:            []string{"missing.example"},
+		ResolveTargets: true,
+		DNSServer:      server,
+	})
+	if err == nil {
+		t.Error("Expected an unresolvable target to fail")
+	}
+}
+
+func TestOptions_ValidateDNSServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "ip", opts: Options{ResolveTargets: true, DNSServer: "10.0.0.53"}},
+		{name: "ip and port", opts: Options{ResolveTargets: true, DNSServer: "10.0.0.53:5353"}},
+		{name: "ipv6", opts: Options{ResolveTargets: true, DNSServer: "[fd00::53]:53"}},
+		{name: "hostname", opts: Options{ResolveTargets: true, DNSServer: "dns.internal"}, expectError: true},
+		{name: "without resolve-targets", opts: Options{DNSServer: "10.0.0.53"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Target resources whose annotations the prober manages.
+const (
+	TargetResourceIngress   = "ingress"
+	TargetResourceService   = "service"
+	TargetResourceGateway   = "gateway"
+	TargetResourceHTTPRoute = "httproute"
+)
+
+// targetKind describes how to list and patch one target resource.
+type targetKind struct {
+	gvk schema.GroupVersionKind
+	// plural is the API resource name, as used in RBAC rules.
+	plural    string
+	newObject func() client.Object
+	newList   func() client.ObjectList
+}
+
+var targetKinds = map[string]targetKind{
+	TargetResourceIngress: {
+		gvk:       networkingv1.SchemeGroupVersion.WithKind("Ingress"),
+		plural:    "ingresses",
+		newObject: func() client.Object { return &networkingv1.Ingress{} },
+		newList:   func() client.ObjectList { return &networkingv1.IngressList{} },
+	},
+	TargetResourceService: {
+		gvk:       corev1.SchemeGroupVersion.WithKind("Service"),
+		plural:    "services",
+		newObject: func() client.Object { return &corev1.Service{} },
+		newList:   func() client.ObjectList { return &corev1.ServiceList{} },
+	},
+	TargetResourceGateway: {
+		gvk:       gatewayv1.SchemeGroupVersion.WithKind("Gateway"),
+		plural:    "gateways",
+		newObject: func() client.Object { return &gatewayv1.Gateway{} },
+		newList:   func() client.ObjectList { return &gatewayv1.GatewayList{} },
+	},
+	TargetResourceHTTPRoute: {
+		gvk:       gatewayv1.SchemeGroupVersion.WithKind("HTTPRoute"),
+		plural:    "httproutes",
+		newObject: func() client.Object { return &gatewayv1.HTTPRoute{} },
+		newList:   func() client.ObjectList { return &gatewayv1.HTTPRouteList{} },
+	},
+}
+
+func validateTargetResource(s string) error {
+	if _, ok := targetKinds[s]; ok || s == "" {
+		return nil
+	}
+	return fmt.Errorf("unsupported target resource %q (want %s, %s, %s or %s)", s,
+		TargetResourceIngress, TargetResourceService, TargetResourceGateway, TargetResourceHTTPRoute)
+}
+
+// kindOf returns the description of resource, defaulting to Ingresses.
+func kindOf(resource string) targetKind {
+	if k, ok := targetKinds[resource]; ok {
+		return k
+	}
+	return targetKinds[TargetResourceIngress]
+}
+
+// newTargetObject returns an empty object of the given target resource.
+func newTargetObject(resource string) client.Object {
+	return kindOf(resource).newObject()
+}
+
+// newTarget returns an empty object of the configured target resource.
+func (r *Runner) newTarget() client.Object {
+	return newTargetObject(r.targetResource)
+}
+
+// listTargets lists every object of the configured target resource. With
+// ingressController set, it also refreshes the matching IngressClasses.
+func (r *Runner) listTargets(ctx context.Context) ([]client.Object, error) {
+	list := kindOf(r.targetResource).newList()
+	if err := r.k8sOp(ctx, "list", func(ctx context.Context) error { return r.k8s.List(ctx, list) }); err != nil {
+		return nil, err
+	}
+	if r.ingressController != "" {
+		if err := r.resolveIngressClasses(ctx); err != nil {
+			return nil, err
+		}
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		objs = append(objs, item.(client.Object))
+	}
+	return objs, nil
+}
+
+// applyTarget returns a server-side apply object for obj carrying only
+// annotations.
+func (r *Runner) applyTarget(obj client.Object, annotations map[string]string) client.Object {
+	kind := kindOf(r.targetResource)
+	out := kind.newObject()
+	out.GetObjectKind().SetGroupVersionKind(kind.gvk)
+	out.SetNamespace(obj.GetNamespace())
+	out.SetName(obj.GetName())
+	out.SetAnnotations(annotations)
+	return out
+}
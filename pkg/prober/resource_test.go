@@ -0,0 +1,195 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func newService(name string, annotations map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+}
+
+func TestRunner_Tick_ServiceTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	const key = "external-dns.alpha.kubernetes.io/target"
+	for _, strategy := range []string{PatchStrategyMerge, PatchStrategyApply} {
+		t.Run(strategy, func(t *testing.T) {
+			var mu sync.Mutex
+			var calls []recordedPatch
+			k8s := newApplyingClient(&mu, &calls,
+				newService("lb", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+				newService("other", map[string]string{"kubernetes.io/ingress.class": "internal"}),
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			)
+
+			runner := &Runner{
+				k8s:                       k8s,
+				targetResource:            TargetResourceService,
+				patchStrategy:             strategy,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             key,
+				ips:                       []string{"10.0.0.1", "10.0.0.2"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			lb := &corev1.Service{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "lb"}, lb); err != nil {
+				t.Fatalf("failed to get Service: %v", err)
+			}
+			if got := lb.Annotations[key]; got != "10.0.0.1,10.0.0.2" {
+				t.Errorf("Expected Service to be annotated, got %q", got)
+			}
+
+			other := &corev1.Service{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "other"}, other); err != nil {
+				t.Fatalf("failed to get Service: %v", err)
+			}
+			if _, ok := other.Annotations[key]; ok {
+				t.Errorf("Expected Service of another class to stay untouched, got %v", other.Annotations)
+			}
+
+			ing := newIngress("web", nil)
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, ing); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if _, ok := ing.Annotations[key]; ok {
+				t.Errorf("Expected Ingress to stay untouched in service mode, got %v", ing.Annotations)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_GatewayAPITargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	const key = "external-dns.alpha.kubernetes.io/target"
+	meta := func(name, class string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "default", Name: name, Annotations: map[string]string{"kubernetes.io/ingress.class": class}}
+	}
+	tests := []struct {
+		resource  string
+		name      string
+		newObj    func() client.Object
+		otherName string
+		newOther  func() client.Object
+	}{
+		{TargetResourceGateway, "gw", func() client.Object { return &gatewayv1.Gateway{} }, "route", func() client.Object { return &gatewayv1.HTTPRoute{} }},
+		{TargetResourceHTTPRoute, "route", func() client.Object { return &gatewayv1.HTTPRoute{} }, "gw", func() client.Object { return &gatewayv1.Gateway{} }},
+	}
+	for _, tt := range tests {
+		for _, strategy := range []string{PatchStrategyMerge, PatchStrategyApply} {
+			t.Run(tt.resource+"/"+strategy, func(t *testing.T) {
+				var mu sync.Mutex
+				var calls []recordedPatch
+				k8s := newApplyingClient(&mu, &calls,
+					&gatewayv1.Gateway{ObjectMeta: meta("gw", "public-nginx")},
+					&gatewayv1.Gateway{ObjectMeta: meta("gw-other", "internal")},
+					&gatewayv1.HTTPRoute{ObjectMeta: meta("route", "public-nginx")},
+					&gatewayv1.HTTPRoute{ObjectMeta: meta("route-other", "internal")},
+					newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+				)
+
+				runner := &Runner{
+					k8s:                       k8s,
+					targetResource:            tt.resource,
+					patchStrategy:             strategy,
+					ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+					ingressClasses:            []string{"public-nginx"},
+					annotationKey:             key,
+					ips:                       []string{"10.0.0.1", "10.0.0.2"},
+					httpClient:                newRoutedHTTPClient(server),
+					urlScheme:                 "http",
+					httpPath:                  "/",
+					timeout:                   time.Second,
+				}
+				if err := runner.tick(context.Background()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				annotations := func(obj client.Object, name string) map[string]string {
+					t.Helper()
+					if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, obj); err != nil {
+						t.Fatalf("failed to get %s: %v", name, err)
+					}
+					return obj.GetAnnotations()
+				}
+				if got := annotations(tt.newObj(), tt.name)[key]; got != "10.0.0.1,10.0.0.2" {
+					t.Errorf("Expected %s to be annotated, got %q", tt.resource, got)
+				}
+				if _, ok := annotations(tt.newObj(), tt.name+"-other")[key]; ok {
+					t.Errorf("Expected %s of another class to stay untouched", tt.resource)
+				}
+				if _, ok := annotations(tt.newOther(), tt.otherName)[key]; ok {
+					t.Errorf("Expected objects of other resources to stay untouched in %s mode", tt.resource)
+				}
+				if _, ok := annotations(newIngress("web", nil), "web")[key]; ok {
+					t.Errorf("Expected Ingress to stay untouched in %s mode", tt.resource)
+				}
+			})
+		}
+	}
+}
+
+func TestRunner_Cleanup_ServiceTargets(t *testing.T) {
+	const key = "external-dns.alpha.kubernetes.io/target"
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newService("lb", map[string]string{"kubernetes.io/ingress.class": "public-nginx", key: "10.0.0.1"}),
+	).Build()
+	runner := &Runner{
+		k8s:               k8s,
+		targetResource:    TargetResourceService,
+		annotationKey:     key,
+		cleanupOnShutdown: true,
+	}
+	runner.markManaged(types.NamespacedName{Namespace: "default", Name: "lb"})
+	runner.cleanup(context.Background())
+
+	lb := &corev1.Service{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "lb"}, lb); err != nil {
+		t.Fatalf("failed to get Service: %v", err)
+	}
+	if _, ok := lb.Annotations[key]; ok {
+		t.Errorf("Expected cleanup to remove the annotation, got %v", lb.Annotations)
+	}
+}
+
+func TestOptions_ValidateTargetResource(t *testing.T) {
+	for _, resource := range []string{"", TargetResourceIngress, TargetResourceService, TargetResourceGateway, TargetResourceHTTPRoute} {
+		opts := Options{IPs: []string{"10.0.0.1"}, TargetResource: resource}
+		if err := opts.validate(); err != nil {
+			t.Errorf("Unexpected error for %q: %v", resource, err)
+		}
+	}
+	opts := Options{IPs: []string{"10.0.0.1"}, TargetResource: "tcproute"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected an unknown target resource to be rejected")
+	}
+}
@@ -0,0 +1,972 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// errTickDeadlineExceeded is returned when probing did not finish within the tick deadline.
+var errTickDeadlineExceeded = errors.New("tick deadline exceeded")
+
+// patchConflictRetries bounds how often a conflicting patch is recomputed and
+// retried within one tick.
+const patchConflictRetries = 3
+
+// Runner periodically probes the configured IPs and writes the healthy ones
+// into an annotation on matching Ingresses. It implements manager.Runnable.
+type Runner struct {
+	k8s                       client.Client
+	targetResource            string
+	ingressClassAnnotationKey string
+	ingressClasses            []string
+	ingressController         string
+	writeTimestamp            bool
+	writeCount                bool
+	requireApproval           bool
+	annotationKey             string
+	annotationKeyV6           string
+	shadowAnnotationKey       string
+	shadowOnly                bool
+	annotationPrefix          string
+	recordType                string
+	requireCurrentValue       string
+	compareAsSet              bool
+	targetSeparator           string
+	cleanupOnShutdown         bool
+	readinessGate             bool
+	removeAnnotationKeys      []string
+	ipsMu                     sync.RWMutex
+	ips                       []string
+	labels                    map[string]map[string]string
+	probeAddrs                map[string]string
+	ipsFile                   string
+	ipsConfigMap              *configMapRef
+	stateConfigMap            *types.NamespacedName
+	healthConfigMap           *types.NamespacedName
+	normalizeIPs              bool
+	allowDuplicateTargets     bool
+	allowedCIDRs              []netip.Prefix
+	cidrMode                  string
+	regionKeyTemplate         *template.Template
+	httpClient                *http.Client
+	dialContext               dialContextFunc
+	probeMode                 string
+	dnsQueryName              string
+	dnsRecordType             string
+	dnsExpect                 string
+	dnsPort                   string
+	newResolver               func(server string) dnsLookup
+	resolveTargets            bool
+	targetResolver            dnsLookup
+	grpcPort                  string
+	grpcService               string
+	probePorts                []string
+	portsMode                 string
+	insecureSkipVerify        bool
+	tlsSettings               tlsSettings
+	urlScheme                 string
+	urlSchemes                []string
+	schemeMatch               string
+	httpPath                  string
+	httpPaths                 []string
+	normalizePath             bool
+	trailingSlash             string
+	redirectToHTTPSHealthy    bool
+	pathQuorum                int
+	hostHeader                string
+	hostFromIngress           bool
+	probeMethod               string
+	probeBody                 func() io.Reader
+	probeContentType          string
+	basicAuth                 *basicAuth
+	bearerToken               *bearerToken
+	expectHeaders             []expectedHeader
+	expectTrailers            []expectedHeader
+	healthExpr                *healthExpr
+	expectJSON                *jsonExpectation
+	expectStatus              statusCodes
+	expectBody                string
+	maxLatency                time.Duration
+	drainResponses            bool
+	probeStagger              time.Duration
+	probeConcurrency          int
+	hostLimit                 *hostLimiter
+	dead                      *deadIPs
+	linkLocalZone             string
+	metadataLabels            []string
+	patchConcurrency          int
+	patchStrategy             string
+	fieldManager              string
+	stopAfterHealthy          int
+	usePartialResults         bool
+	writeFastest              int
+	singleTarget              bool
+	singleTargetPolicy        string
+	orderPolicy               string
+	interval                  time.Duration
+	maxInterval               time.Duration
+	timeout                   time.Duration
+	tickDeadline              time.Duration
+	k8sOpTimeout              time.Duration
+	valueTemplate             *template.Template
+	webhook                   *webhookNotifier
+	publisher                 *publisher
+	publishOnly               bool
+	adminToken                string
+	breaker                   *patchBreaker
+	cooldown                  *patchCooldown
+	lastUpdates               *updateTracker
+	audit                     *auditLog
+	failHealthzOnPatchErrors  int
+	livenessStaleFactor       int
+	skipInitialTick           bool
+	unhealthyMode             string
+	fallbackTargets           []string
+	clearGracePeriod          time.Duration
+
+	// with endpoint discovery ips holds the discovered endpoints and
+	// writeIPs the configured IPs written while any of them is healthy
+	discoverSelector  labels.Selector
+	discoverNamespace string
+	discoverWrite     string
+	writeIPs          []string
+
+	// paused skips annotation updates while probing continues; toggled via the status server.
+	paused atomic.Bool
+	// tracer records tick and probe spans; nil traces nothing.
+	tracer trace.Tracer
+	// updateWindow limits when annotations are updated; nil allows any time.
+	updateWindow *updateWindow
+	// forced holds operator overrides of probe results, set via the status server.
+	forced forcedStates
+	// tickMu serializes ticks triggered by the interval and on demand.
+	tickMu sync.Mutex
+	// downSince and lastDownLog suppress repeated no-healthy-IP logs; only touched from tick.
+	downSince           time.Time
+	lastDownLog         time.Time
+	logSuppressInterval time.Duration
+	// familyDownSince holds since when the family written to each key has had
+	// no healthy IP, with a separate IPv6 key; guarded by tickMu.
+	familyDownSince map[string]time.Time
+	// consecutiveFailures counts whole-cycle failures for backoff; only touched from Start.
+	consecutiveFailures int
+	randInt63n          func(int64) int64
+	// controllerClasses are the IngressClasses run by ingressController and
+	// defaultClass the default IngressClass; refreshed by listTargets.
+	controllerClasses map[string]struct{}
+	defaultClass      string
+	// lastSingle is the IP written by the previous single-target tick; only touched from tick.
+	lastSingle string
+
+	// mu guards the probe state below, which is read by Status.
+	mu sync.Mutex
+	// lastHealthy is the healthy set seen by the previous tick; observed is false until the first tick.
+	lastHealthy  []string
+	observed     bool
+	lastTick     time.Time
+	lastErrors   map[string]string
+	notReady     string
+	patchErrors  map[string]string
+	healthWindow int
+	windows      map[string]*resultWindow
+	// patchErrorTicks counts consecutive ticks with failed patches.
+	patchErrorTicks int
+	// lastTickDone is when Start last completed a tick and tickInterval the
+	// interval it then waited for; both feed LivezCheck.
+	lastTickDone time.Time
+	tickInterval time.Duration
+	// deferred is the latest healthy set held back outside the update window.
+	deferred []string
+	// managed holds the Ingresses managed this session, tracked for cleanup on shutdown.
+	managed map[types.NamespacedName]struct{}
+}
+
+// New builds a Runner from opts.
+func New(opts Options) (*Runner, error) {
+	opts.setDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	valueTemplate, err := parseValueTemplate(opts.AnnotationValueTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IPsFile != "" {
+		if opts.IPs, err = readIPsFile(opts.IPsFile); err != nil {
+			return nil, err
+		}
+	}
+	var ipsConfigMap *configMapRef
+	if opts.IPsConfigMap != "" {
+		ref, err := parseConfigMapRef(opts.IPsConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		ipsConfigMap = &ref
+	}
+	var stateConfigMap *types.NamespacedName
+	if opts.StateConfigMap != "" {
+		ref, err := parseNamespacedRef(opts.StateConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		stateConfigMap = &ref
+	}
+	var healthConfigMap *types.NamespacedName
+	if opts.HealthConfigMap != "" {
+		ref, err := parseNamespacedRef(opts.HealthConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		healthConfigMap = &ref
+	}
+	targets, err := parseTargets(opts.IPs)
+	if err != nil {
+		return nil, err
+	}
+	tlsSettings, err := opts.tlsSettings()
+	if err != nil {
+		return nil, err
+	}
+	allowedCIDRs, err := parseCIDRs(opts.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := newAuditLog(opts.AuditLogFile)
+	if err != nil {
+		return nil, err
+	}
+	window, err := newUpdateWindow(opts.UpdateWindow)
+	if err != nil {
+		return nil, err
+	}
+	probeBody := []byte(opts.ProbeBody)
+	if opts.ProbeBodyFile != "" {
+		if probeBody, err = os.ReadFile(opts.ProbeBodyFile); err != nil {
+			return nil, fmt.Errorf("reading probe body file: %w", err)
+		}
+	}
+	auth, err := newBasicAuth(opts.ProbeBasicAuthUser, opts.ProbeBasicAuthPass, opts.ProbeBasicAuthPassFile)
+	if err != nil {
+		return nil, err
+	}
+	bearer, err := newBearerToken(opts.ProbeBearerTokenFile)
+	if err != nil {
+		return nil, err
+	}
+	expectHeaders, err := parseExpectHeaders(opts.ExpectHeaders)
+	if err != nil {
+		return nil, err
+	}
+	expectTrailers, err := parseExpectHeaders(opts.ExpectTrailers)
+	if err != nil {
+		return nil, err
+	}
+	healthExpr, err := compileHealthExpr(opts.HealthExpr)
+	if err != nil {
+		return nil, err
+	}
+	expectStatus, err := parseStatusCodes(opts.ExpectStatus)
+	if err != nil {
+		return nil, err
+	}
+	expectJSON, err := newJSONExpectation(opts.ExpectJSONPath, opts.ExpectJSONValue)
+	if err != nil {
+		return nil, err
+	}
+	var regionKeyTemplate *template.Template
+	if opts.RegionAnnotationTemplate != "" {
+		if regionKeyTemplate, err = parseRegionKeyTemplate(opts.RegionAnnotationTemplate); err != nil {
+			return nil, err
+		}
+	}
+	r := &Runner{
+		k8s:                       opts.Client,
+		targetResource:            opts.TargetResource,
+		ingressClassAnnotationKey: opts.IngressClassAnnotationKey,
+		ingressClasses:            splitClasses(opts.IngressClass),
+		ingressController:         opts.IngressController,
+		writeTimestamp:            opts.WriteTimestampAnnotation,
+		writeCount:                opts.WriteCountAnnotation,
+		requireApproval:           opts.RequireApproval,
+		annotationKey:             opts.AnnotationKey,
+		annotationKeyV6:           opts.AnnotationKeyV6,
+		shadowAnnotationKey:       opts.ShadowAnnotationKey,
+		shadowOnly:                opts.ShadowOnly,
+		annotationPrefix:          opts.AnnotationPrefix,
+		recordType:                strings.ToUpper(opts.RecordType),
+		requireCurrentValue:       opts.RequireCurrentValue,
+		compareAsSet:              opts.CompareAsSet,
+		targetSeparator:           opts.TargetSeparator,
+		cleanupOnShutdown:         opts.CleanupOnShutdown,
+		readinessGate:             opts.ReadinessGate,
+		removeAnnotationKeys:      opts.RemoveAnnotationKeys,
+		ipsFile:                   opts.IPsFile,
+		ipsConfigMap:              ipsConfigMap,
+		stateConfigMap:            stateConfigMap,
+		healthConfigMap:           healthConfigMap,
+		normalizeIPs:              !opts.DisableIPNormalization,
+		allowDuplicateTargets:     opts.AllowDuplicateTargets,
+		allowedCIDRs:              allowedCIDRs,
+		cidrMode:                  opts.CIDRMode,
+		regionKeyTemplate:         regionKeyTemplate,
+		httpClient:                opts.httpClient(),
+		dialContext:               opts.dialContext(),
+		probeMode:                 opts.ProbeMode,
+		dnsQueryName:              opts.DNSQueryName,
+		dnsRecordType:             opts.DNSRecordType,
+		dnsExpect:                 opts.DNSExpect,
+		dnsPort:                   opts.DNSPort,
+		newResolver:               newServerResolver,
+		resolveTargets:            opts.ResolveTargets,
+		targetResolver:            opts.targetResolver(),
+		grpcPort:                  opts.GRPCPort,
+		grpcService:               opts.GRPCService,
+		probePorts:                opts.ProbePorts,
+		portsMode:                 opts.PortsMode,
+		insecureSkipVerify:        opts.InsecureSkipVerify,
+		tlsSettings:               tlsSettings,
+		urlScheme:                 splitSchemes(opts.Scheme)[0],
+		urlSchemes:                splitSchemes(opts.Scheme),
+		schemeMatch:               opts.SchemeMatch,
+		httpPath:                  opts.HTTPPath,
+		httpPaths:                 opts.HTTPPaths,
+		normalizePath:             opts.NormalizePath,
+		trailingSlash:             opts.TrailingSlash,
+		redirectToHTTPSHealthy:    opts.RedirectToHTTPSHealthy,
+		pathQuorum:                opts.PathQuorum,
+		hostHeader:                opts.HostHeader,
+		hostFromIngress:           opts.HostFromIngress,
+		probeMethod:               opts.ProbeMethod,
+		probeBody:                 newBodyFactory(probeBody),
+		probeContentType:          opts.ProbeContentType,
+		basicAuth:                 auth,
+		bearerToken:               bearer,
+		expectHeaders:             expectHeaders,
+		expectTrailers:            expectTrailers,
+		healthExpr:                healthExpr,
+		expectJSON:                expectJSON,
+		expectStatus:              expectStatus,
+		expectBody:                opts.ExpectBody,
+		maxLatency:                opts.MaxLatency,
+		drainResponses:            opts.DrainBody,
+		probeStagger:              opts.ProbeStagger,
+		probeConcurrency:          opts.ProbeConcurrency,
+		hostLimit:                 newHostLimiter(opts.PerHostConcurrency),
+		dead:                      newDeadIPs(opts.DeadAfter, opts.DeadCooldown),
+		linkLocalZone:             opts.LinkLocalZone,
+		metadataLabels:            opts.MetadataLabels,
+		patchConcurrency:          opts.PatchConcurrency,
+		patchStrategy:             opts.PatchStrategy,
+		fieldManager:              opts.FieldManager,
+		stopAfterHealthy:          opts.StopAfterHealthy,
+		usePartialResults:         opts.UsePartialResults,
+		writeFastest:              opts.WriteFastest,
+		singleTarget:              opts.SingleTarget,
+		singleTargetPolicy:        opts.SingleTargetPolicy,
+		orderPolicy:               opts.OrderPolicy,
+		interval:                  opts.Interval,
+		maxInterval:               opts.MaxInterval,
+		timeout:                   opts.Timeout,
+		tickDeadline:              opts.TickDeadline,
+		k8sOpTimeout:              opts.K8sOpTimeout,
+		logSuppressInterval:       opts.LogSuppressInterval,
+		valueTemplate:             valueTemplate,
+		adminToken:                opts.AdminToken,
+		breaker:                   newPatchBreaker(opts.PatchBreakerThreshold, opts.PatchBreakerCooldown),
+		cooldown:                  newPatchCooldown(opts.AnnotationCooldown),
+		lastUpdates:               newUpdateTracker(),
+		audit:                     audit,
+		updateWindow:              window,
+		failHealthzOnPatchErrors:  opts.FailHealthzOnPatchErrors,
+		livenessStaleFactor:       opts.LivenessStaleFactor,
+		skipInitialTick:           opts.SkipInitialTick,
+		unhealthyMode:             opts.UnhealthyMode,
+		fallbackTargets:           opts.FallbackTargets,
+		clearGracePeriod:          opts.ClearGracePeriod,
+		webhook:                   newWebhookNotifier(opts.WebhookURL, opts.WebhookTimeout),
+		publisher:                 newPublisher(opts.PublishURL, opts.PublishHeaders),
+		publishOnly:               opts.PublishOnly,
+		randInt63n:                rand.Int63n,
+		healthWindow:              opts.HealthWindow,
+	}
+	if opts.TracerProvider != nil {
+		r.tracer = opts.TracerProvider.Tracer(tracerName)
+	}
+	if opts.RandSeed != 0 {
+		r.randInt63n = seededInt63n(opts.RandSeed)
+	}
+	r.paused.Store(opts.StartPaused)
+	if targets, err = r.prepareTargets(context.Background(), targets); err != nil {
+		return nil, err
+	}
+	if opts.DiscoverSelector != "" {
+		// validate has already checked the selector
+		r.discoverSelector, _ = labels.Parse(opts.DiscoverSelector)
+		r.discoverNamespace = opts.DiscoverNamespace
+		r.discoverWrite = opts.DiscoverWrite
+		r.writeIPs = targets.ips
+		return r, nil
+	}
+	r.setTargets(targets)
+	return r, nil
+}
+
+// Start runs a probe cycle immediately, unless skipInitialTick is set, and
+// then on every interval until ctx is done.
+// Consecutive failing cycles back the interval off up to maxInterval.
+func (r *Runner) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	logger.Info("runner started")
+
+	if r.ipsFile != "" {
+		go r.watchIPsFile(ctx)
+	}
+	if r.stateConfigMap != nil {
+		r.loadState(ctx)
+	}
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	current := r.interval
+	r.markTickDone(current)
+	step := func() {
+		next := r.nextInterval(r.tick(ctx))
+		r.markTickDone(next)
+		if next != current {
+			logger.Info("adjusting probe interval", "interval", next.String(), "consecutive_failures", r.consecutiveFailures)
+			t.Reset(next)
+			current = next
+		}
+		r.persistState(ctx)
+	}
+
+	// run immediately at startup unless told to wait for the first interval
+	if !r.skipInitialTick {
+		step()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if r.cleanupOnShutdown {
+				r.cleanup(ctx)
+			}
+			// ctx is already cancelled; give the final save its own deadline
+			saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+			r.persistState(saveCtx)
+			cancel()
+			return nil
+		case <-t.C:
+			step()
+		}
+	}
+}
+
+// tick runs one probe cycle and returns an error when the whole cycle failed.
+// Scheduled and on-demand ticks are serialized.
+func (r *Runner) tick(ctx context.Context) (err error) {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	defer r.lastUpdates.observe()
+	ctx, span := r.startSpan(ctx, "tick")
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	logger := log.FromContext(ctx)
+	if r.ipsConfigMap != nil {
+		if err := r.reloadIPsConfigMap(ctx); err != nil {
+			logger.Error(err, "failed to load target IPs from ConfigMap; keeping current list", "configmap", r.ipsConfigMap.String())
+		}
+	}
+	if r.discoverSelector != nil {
+		r.refreshDiscoveredTargets(ctx)
+	}
+	r.reloadBearerToken(ctx)
+	// Use a reasonable timeout for the entire health check operation
+	// Allow enough time for all IPs to be checked with some buffer
+	n := len(r.currentIPs())
+	timeout := r.timeout * time.Duration(max(1, n))
+	// staggered probe starts push the last probe out by stagger*(n-1)
+	timeout += r.probeStagger * time.Duration(max(0, n-1))
+	if r.tickDeadline > 0 {
+		timeout = r.tickDeadline
+	}
+	tickTimeoutSeconds.Set(timeout.Seconds())
+	probeTimeoutSeconds.Set(r.timeout.Seconds())
+	logger.Info("starting health check", "timeout", timeout.String(), "ips_count", n)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			tickDeadlineExceeded.Inc()
+			logger.Info("tick deadline exceeded; remaining work cancelled", "timeout", timeout.String())
+		}
+	}()
+
+	healthyIPs, failures, latencies, partial := r.probeAllTimed(ctx)
+	span.SetAttributes(attribute.Int("ips_count", n), attribute.Int("healthy_count", len(healthyIPs)))
+	if partial {
+		if !r.usePartialResults || len(healthyIPs) == 0 {
+			// a partial probe round would count unprobed IPs as failures
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ctx.Err()
+			}
+			r.setNotReady(notReadyDeadline)
+			return errTickDeadlineExceeded
+		}
+		logger.Info("acting on partial probe results", "healthy", strings.Join(healthyIPs, ","), "probed", len(latencies), "ips_count", n)
+	}
+	r.recordHealthy(ctx, healthyIPs, failures)
+	r.recordHealthConfigMap(ctx, failures, latencies)
+	r.trackFamilies(logger, healthyIPs)
+	if len(healthyIPs) == 0 {
+		r.setNotReady(notReadyNoHealthy)
+		r.logNoHealthy(logger)
+		return r.handleNoHealthy(ctx)
+	}
+	r.logRecovered(logger)
+	if r.discoverSelector != nil && r.discoverWrite == DiscoverWriteIPs {
+		logger.Info("discovered endpoints healthy; writing configured IPs", "healthy_endpoints", strings.Join(healthyIPs, ","))
+		healthyIPs = r.writeIPs
+	}
+	return r.writeHealthy(ctx, healthyIPs, latencies)
+}
+
+// writeHealthy publishes healthyIPs when a publish URL is set and writes them
+// to the matching objects unless annotation updates are paused, only
+// published, there is no client or the patch breaker is open.
+func (r *Runner) writeHealthy(ctx context.Context, healthyIPs []string, latencies map[string]time.Duration) error {
+	logger := log.FromContext(ctx)
+	if r.paused.Load() {
+		r.setNotReady("")
+		logger.Info("paused; skipping annotation updates", "healthy", strings.Join(healthyIPs, ","))
+		return nil
+	}
+
+	if r.publisher != nil {
+		err := r.publish(ctx, healthyIPs)
+		if r.publishOnly {
+			r.setNotReady("")
+			return err
+		}
+	}
+
+	if r.k8s == nil {
+		r.setNotReady("")
+		logger.Info("probe-only mode; healthy IPs", "healthy", strings.Join(healthyIPs, ","))
+		return nil
+	}
+
+	if r.breaker.open() {
+		r.setNotReady("")
+		logger.Info("patch breaker open; skipping annotation updates", "healthy", strings.Join(healthyIPs, ","))
+		return nil
+	}
+
+	if !r.updateWindow.open() {
+		r.setNotReady("")
+		r.setDeferred(healthyIPs)
+		logger.Info("outside the update window; deferring annotation updates", "window", r.updateWindow.String(), "healthy", strings.Join(healthyIPs, ","))
+		return nil
+	}
+	r.setDeferred(nil)
+
+	// served from the manager's informer cache, see WatchTargets
+	objs, err := r.listTargets(ctx)
+	if err != nil {
+		logger.Error(err, "failed to list target objects", "resource", r.targetResource)
+		r.setNotReady(fmt.Sprintf("%s (%s)", notReadyListError, kindOf(r.targetResource).plural))
+		return err
+	}
+	r.setNotReady("")
+	r.lastUpdates.retain(objs)
+
+	if !r.allowDuplicateTargets {
+		// fallback targets do not pass through prepareTargets
+		healthyIPs = uniqueIPs(healthyIPs)
+	}
+	if r.writeFastest > 0 && r.writeFastest < len(healthyIPs) {
+		healthyIPs = fastestIPs(healthyIPs, latencies, r.writeFastest)
+		logger.Info("writing only the fastest healthy IPs", "count", r.writeFastest, "selected", strings.Join(healthyIPs, ","))
+	}
+	if r.singleTarget {
+		healthyIPs = []string{r.selectSingleTarget(healthyIPs, latencies)}
+		logger.Info("writing a single target", "policy", r.singleTargetPolicy, "selected", healthyIPs[0])
+	} else if r.orderPolicy != "" {
+		healthyIPs = r.orderIPs(healthyIPs, latencies)
+	}
+
+	healthy := r.newHealthySets(healthyIPs)
+	updates := r.planUpdates(ctx, healthy, objs)
+	patchErrs := r.applyUpdates(ctx, healthy, updates)
+	r.recordPatchErrors(patchErrs)
+	if len(patchErrs) > 0 {
+		keys := make([]string, 0, len(patchErrs))
+		for k := range patchErrs {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		errs := make([]error, 0, len(keys))
+		for _, k := range keys {
+			errs = append(errs, fmt.Errorf("%s: %w", k, patchErrs[k]))
+		}
+		logger.Error(errors.Join(errs...), "failed to patch some objects", "failed", len(patchErrs), "attempted", len(updates), "objects", strings.Join(keys, ","))
+	}
+	return nil
+}
+
+// targetUpdate is a pending change to one Ingress or Service: the patch base
+// and the already modified object, plus what changed for logging and the
+// audit log.
+type targetUpdate struct {
+	obj     client.Object
+	patch   client.Patch
+	desired map[string]string
+	stale   []string
+	changes []annotationChange
+}
+
+// planUpdates returns the updates needed to bring the matching objects in
+// line with their healthy IPs. Objects that are already up to date are skipped.
+func (r *Runner) planUpdates(ctx context.Context, healthy *healthySets, objs []client.Object) []targetUpdate {
+	var updates []targetUpdate
+	for _, obj := range objs {
+		u, ok := r.planUpdate(ctx, healthy, obj)
+		if !ok {
+			continue
+		}
+		key := client.ObjectKeyFromObject(obj)
+		if wait := r.cooldown.remaining(key); wait > 0 {
+			log.FromContext(ctx).Info("annotation cooldown active; deferring update", "object", key.String(), "remaining", wait.Round(time.Second).String())
+			continue
+		}
+		updates = append(updates, u)
+	}
+	return updates
+}
+
+// planUpdate applies the desired annotations to obj in place and returns the
+// resulting update. It reports false when obj is not managed or already current.
+func (r *Runner) planUpdate(ctx context.Context, healthy *healthySets, obj client.Object) (targetUpdate, bool) {
+	if !r.matchesClass(obj) {
+		return targetUpdate{}, false
+	}
+	annotations := obj.GetAnnotations()
+	if !r.eligible(annotations) {
+		return targetUpdate{}, false
+	}
+	if annotations == nil {
+		// only Ingresses matched by spec.ingressClassName or the default
+		// IngressClass can come without annotations; they are still managed
+		annotations = map[string]string{}
+		obj.SetAnnotations(annotations)
+	}
+	key := client.ObjectKeyFromObject(obj)
+	r.markManaged(key)
+	r.lastUpdates.seed(key, annotations[r.proberKey(lastUpdatedAnnotationName)])
+	healthyIPs, err := healthy.forObject(ctx, obj)
+	if err != nil {
+		log.FromContext(ctx).Info("skipping object with target override", "object", key.String(), "error", err.Error())
+		return targetUpdate{}, false
+	}
+	desired, remove, err := r.desiredAnnotations(healthyIPs, obj)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to render annotation value", "object", key.String())
+		return targetUpdate{}, false
+	}
+	stale := slices.DeleteFunc(r.staleAnnotationKeys(annotations), func(k string) bool {
+		_, ok := desired[k]
+		return ok
+	})
+	for _, k := range remove {
+		if _, ok := annotations[k]; ok {
+			stale = append(stale, k)
+		}
+	}
+	changed := !annotationsMatch(annotations, desired, r.compareAsSet, r.separator())
+	if r.requireApproval {
+		if (changed || len(stale) > 0) && !r.approved(annotations, desired) {
+			return r.planProposal(ctx, obj, desired)
+		}
+		// approved, or nothing left to change: the proposal is settled
+		stale = append(stale, r.approvalKeys(annotations)...)
+	}
+	if !changed && len(stale) == 0 {
+		return targetUpdate{}, false
+	}
+	if changed && r.writeTimestamp {
+		// added after the comparison, so the timestamp alone never causes a patch
+		desired[r.proberKey(lastUpdatedAnnotationName)] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	// set and removal go out in a single merge patch
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	before := maps.Clone(annotations)
+	for k, v := range desired {
+		annotations[k] = v
+	}
+	if r.requireCurrentValue != "" || r.patchStrategy == PatchStrategyApply {
+		annotations[r.proberKey(managedAnnotationName)] = "true"
+	}
+	for _, k := range stale {
+		delete(annotations, k)
+	}
+	return targetUpdate{obj: obj, patch: patch, desired: desired, stale: stale, changes: annotationChanges(before, annotations)}, true
+}
+
+// applyUpdates sends the planned patches with at most patchConcurrency in
+// flight. A failed patch does not stop the others; the failures are returned
+// keyed by object.
+func (r *Runner) applyUpdates(ctx context.Context, healthy *healthySets, updates []targetUpdate) map[string]error {
+	sem := make(chan struct{}, max(1, r.patchConcurrency))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failures := map[string]error{}
+	for _, u := range updates {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(u targetUpdate) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := r.applyUpdate(ctx, healthy, u); err != nil {
+				mu.Lock()
+				failures[client.ObjectKeyFromObject(u.obj).String()] = err
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+	return failures
+}
+
+// applyUpdate patches a single object. On a conflict the object is re-fetched
+// and the patch recomputed, up to patchConflictRetries times. It returns the
+// error that made the update fail; a skipped update is not an error.
+func (r *Runner) applyUpdate(ctx context.Context, healthy *healthySets, u targetUpdate) error {
+	logger := log.FromContext(ctx)
+	key := client.ObjectKeyFromObject(u.obj)
+	if !r.breaker.allow() {
+		logger.Info("patch breaker open; skipping update", "object", key.String())
+		return nil
+	}
+	for attempt := 0; ; attempt++ {
+		err := r.sendPatch(ctx, u)
+		if err == nil {
+			r.breaker.record(nil)
+			r.cooldown.record(key)
+			r.lastUpdates.record(key)
+			r.auditChanges(ctx, key, u.changes)
+			logger.Info("updated annotation", "object", key.String(), "annotations", u.desired, "removed_keys", u.stale)
+			return nil
+		}
+		if !apierrors.IsConflict(err) || attempt >= patchConflictRetries {
+			logger.Error(err, "failed to patch annotation", "object", key.String(), "annotations", u.desired, "removed_keys", u.stale)
+			if r.breaker.record(err) {
+				logger.Info("patch breaker opened after repeated failures", "cooldown", r.breaker.cooldown.String())
+			}
+			return err
+		}
+		logger.Info("conflict patching object; retrying with a fresh copy", "object", key.String(), "attempt", attempt+1)
+		fresh := r.newTarget()
+		if err := r.k8s.Get(ctx, key, fresh); err != nil {
+			logger.Error(err, "failed to re-fetch object after conflict", "object", key.String())
+			r.breaker.record(err)
+			return err
+		}
+		var ok bool
+		if u, ok = r.planUpdate(ctx, healthy, fresh); !ok {
+			logger.Info("object no longer needs an update after conflict", "object", key.String())
+			r.breaker.release()
+			return nil
+		}
+	}
+}
+
+// logNoHealthy logs the no-healthy-IP condition when it starts and then at
+// most once per logSuppressInterval while it persists.
+func (r *Runner) logNoHealthy(logger logr.Logger) {
+	now := time.Now()
+	if r.downSince.IsZero() {
+		r.downSince, r.lastDownLog = now, now
+		logger.Info("no healthy IP; leaving annotations unchanged", "error", errNoHealthyIP.Error())
+		return
+	}
+	if r.logSuppressInterval > 0 && now.Sub(r.lastDownLog) < r.logSuppressInterval {
+		return
+	}
+	r.lastDownLog = now
+	logger.Info("still no healthy IP; leaving annotations unchanged", "error", errNoHealthyIP.Error(), "down_for", now.Sub(r.downSince).Round(time.Second).String())
+}
+
+// logRecovered logs the end of a no-healthy-IP period.
+func (r *Runner) logRecovered(logger logr.Logger) {
+	if r.downSince.IsZero() {
+		return
+	}
+	logger.Info("healthy IPs available again", "down_for", time.Since(r.downSince).Round(time.Second).String())
+	r.downSince, r.lastDownLog = time.Time{}, time.Time{}
+}
+
+// desiredAnnotations returns every annotation the prober wants set on ing:
+// the main key with all healthy IPs (or, with an IPv6 key, the IPv4 and IPv6
+// ones under separate keys), the record type hint, the healthy count and one
+// key per region when configured. The shadow key always receives the
+// rendered value; in shadow-only mode it is the only annotation. It also
+// returns the keys to delete, those of an address family cleared by the
+// unhealthy mode.
+func (r *Runner) desiredAnnotations(healthyIPs []string, obj client.Object) (map[string]string, []string, error) {
+	desired := map[string]string{}
+	if r.shadowAnnotationKey != "" {
+		value, err := r.renderValue(healthyIPs, obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired[r.shadowAnnotationKey] = value
+		if r.shadowOnly {
+			return desired, nil, nil
+		}
+	}
+	var remove []string
+	if r.annotationKeyV6 != "" {
+		var err error
+		if remove, err = r.addFamilyAnnotations(desired, healthyIPs, obj); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		value, err := r.renderValue(healthyIPs, obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		desired[r.annotationKey] = value
+	}
+	if r.recordType != "" {
+		desired[RecordTypeAnnotationKey] = r.recordType
+	}
+	if r.writeCount {
+		// compared on its own key, so it patches when the count changes
+		// without taking part in the comparison of the target value
+		desired[r.proberKey(countAnnotationName)] = strconv.Itoa(len(healthyIPs))
+	}
+	if err := r.addRegionAnnotations(desired, healthyIPs, obj); err != nil {
+		return nil, nil, err
+	}
+	return desired, remove, nil
+}
+
+// splitClasses parses a comma-separated list of ingress classes.
+func splitClasses(s string) []string {
+	var classes []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			classes = append(classes, c)
+		}
+	}
+	return classes
+}
+
+// annotationsMatch reports whether every desired annotation is already set.
+// With asSet, values split on sep match when they hold the same members in
+// any order.
+func annotationsMatch(current, desired map[string]string, asSet bool, sep string) bool {
+	for k, v := range desired {
+		cur, ok := current[k]
+		if !ok {
+			return false
+		}
+		if cur != v && (!asSet || !sameMembers(cur, v, sep)) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMembers compares two lists separated by sep ignoring order, whitespace
+// and duplicates.
+func sameMembers(a, b, sep string) bool {
+	members := func(s string) map[string]struct{} {
+		set := map[string]struct{}{}
+		for _, m := range strings.Split(s, sep) {
+			if m = strings.TrimSpace(m); m != "" {
+				set[m] = struct{}{}
+			}
+		}
+		return set
+	}
+	ma, mb := members(a), members(b)
+	if len(ma) != len(mb) {
+		return false
+	}
+	for m := range ma {
+		if _, ok := mb[m]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// eligible reports whether an Ingress may be patched. With requireCurrentValue
+// set, only Ingresses whose annotation is empty, equals the sentinel, or that
+// were previously marked as managed by the prober qualify.
+func (r *Runner) eligible(annotations map[string]string) bool {
+	if r.requireCurrentValue == "" {
+		return true
+	}
+	if annotations[r.proberKey(managedAnnotationName)] == "true" {
+		return true
+	}
+	current := annotations[r.annotationKey]
+	return current == "" || current == r.requireCurrentValue
+}
+
+// staleAnnotationKeys returns the configured stale keys present in annotations.
+// The managed annotation keys themselves are never treated as stale.
+func (r *Runner) staleAnnotationKeys(annotations map[string]string) []string {
+	var stale []string
+	for _, k := range r.removeAnnotationKeys {
+		if k == r.annotationKey || k == r.annotationKeyV6 || k == r.shadowAnnotationKey {
+			continue
+		}
+		if _, ok := annotations[k]; ok {
+			stale = append(stale, k)
+		}
+	}
+	return stale
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,970 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+var testScheme = func() *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	if err := gatewayv1.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	return s
+}()
+
+// newRoutedHTTPClient returns an HTTP client that dials srv regardless of the
+// requested address, so probes against arbitrary IPs land on the test server.
+func newRoutedHTTPClient(srv *httptest.Server) *http.Client {
+	d := &net.Dialer{}
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, network, srv.Listener.Addr().String())
+			},
+		},
+	}
+}
+
+// logCapture collects formatted log lines emitted through a logr.Logger.
+type logCapture struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *logCapture) logger() logr.Logger {
+	return funcr.New(func(prefix, args string) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.lines = append(c.lines, args)
+	}, funcr.Options{})
+}
+
+func (c *logCapture) contains(substr string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range c.lines {
+		if strings.Contains(l, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func newIngress(name string, annotations map[string]string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        name,
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestRunner_Tick_RemovesStaleAnnotationKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	managed := newIngress("managed", map[string]string{
+		"kubernetes.io/ingress.class": "public-nginx",
+		"old.example.com/target":      "9.9.9.9",
+		"keep.example.com/other":      "keep",
+	})
+	upToDate := newIngress("up-to-date", map[string]string{
+		"kubernetes.io/ingress.class": "public-nginx",
+		"new.example.com/target":      "10.0.0.1",
+		"old.example.com/target":      "9.9.9.9",
+	})
+	otherClass := newIngress("other-class", map[string]string{
+		"kubernetes.io/ingress.class": "private-nginx",
+		"old.example.com/target":      "9.9.9.9",
+	})
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(managed, upToDate, otherClass).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		removeAnnotationKeys:      []string{"old.example.com/target", "new.example.com/target"},
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+
+	runner.tick(context.Background())
+
+	tests := []struct {
+		name     string
+		expected map[string]string
+	}{
+		{
+			name: "managed",
+			expected: map[string]string{
+				"kubernetes.io/ingress.class": "public-nginx",
+				"new.example.com/target":      "10.0.0.1",
+				"keep.example.com/other":      "keep",
+			},
+		},
+		{
+			name: "up-to-date",
+			expected: map[string]string{
+				"kubernetes.io/ingress.class": "public-nginx",
+				"new.example.com/target":      "10.0.0.1",
+			},
+		},
+		{
+			name: "other-class",
+			expected: map[string]string{
+				"kubernetes.io/ingress.class": "private-nginx",
+				"old.example.com/target":      "9.9.9.9",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: tt.name}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if len(got.Annotations) != len(tt.expected) {
+				t.Errorf("Expected annotations %v, got %v", tt.expected, got.Annotations)
+			}
+			for k, v := range tt.expected {
+				if got.Annotations[k] != v {
+					t.Errorf("Expected annotation %q=%q, got %q", k, v, got.Annotations[k])
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_StaleAnnotationKeys(t *testing.T) {
+	runner := &Runner{
+		annotationKey:        "new.example.com/target",
+		removeAnnotationKeys: []string{"a", "b", "new.example.com/target"},
+	}
+
+	stale := runner.staleAnnotationKeys(map[string]string{"b": "1", "c": "2", "new.example.com/target": "x"})
+	if len(stale) != 1 || stale[0] != "b" {
+		t.Errorf("Expected stale keys [b], got %v", stale)
+	}
+
+	if stale := runner.staleAnnotationKeys(nil); len(stale) != 0 {
+		t.Errorf("Expected no stale keys for nil annotations, got %v", stale)
+	}
+}
+
+func TestRunner_Tick_WithoutClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.0.0.1", "10.0.0.2"},
+		httpClient: newRoutedHTTPClient(server),
+		urlScheme:  "http",
+		httpPath:   "/",
+		timeout:    time.Second,
+	}
+
+	capture := &logCapture{}
+	ctx := log.IntoContext(context.Background(), capture.logger())
+
+	runner.tick(ctx)
+
+	if !capture.contains(`"msg"="probe-only mode; healthy IPs" "healthy"="10.0.0.1,10.0.0.2"`) {
+		t.Errorf("Expected healthy IPs to be logged, got %v", capture.lines)
+	}
+}
+
+func TestRunner_Tick_SuppressesRepeatedNoHealthyLogs(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthy.Load() {
+			w.WriteHeader(200)
+			return
+		}
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:                 []string{"10.0.0.1"},
+		httpClient:          newRoutedHTTPClient(server),
+		urlScheme:           "http",
+		httpPath:            "/",
+		timeout:             time.Second,
+		logSuppressInterval: time.Hour,
+	}
+
+	capture := &logCapture{}
+	ctx := log.IntoContext(context.Background(), capture.logger())
+
+	for i := 0; i < 5; i++ {
+		runner.tick(ctx)
+	}
+	healthy.Store(true)
+	runner.tick(ctx)
+
+	var down, recovered int
+	for _, l := range capture.lines {
+		if strings.Contains(l, "no healthy IP; leaving annotations unchanged") {
+			down++
+		}
+		if strings.Contains(l, "healthy IPs available again") {
+			recovered++
+		}
+	}
+	if down != 1 {
+		t.Errorf("Expected the no-healthy-IP condition to be logged once, got %d: %v", down, capture.lines)
+	}
+	if recovered != 1 {
+		t.Errorf("Expected the recovery to be logged once, got %d: %v", recovered, capture.lines)
+	}
+}
+
+func TestRunner_Tick_RequireCurrentValue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	const key = "external-dns.alpha.kubernetes.io/target"
+	cls := map[string]string{"kubernetes.io/ingress.class": "public-nginx"}
+	with := func(kv ...string) map[string]string {
+		m := map[string]string{}
+		for k, v := range cls {
+			m[k] = v
+		}
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("sentinel", with(key, "auto")),
+		newIngress("manual", with(key, "1.2.3.4")),
+		newIngress("empty", with()),
+		newIngress("previously-managed", with(key, "10.0.0.9", ManagedAnnotationKey, "true")),
+	).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             key,
+		requireCurrentValue:       "auto",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		expectedValue string
+		expectManaged bool
+	}{
+		{name: "sentinel", expectedValue: "10.0.0.1", expectManaged: true},
+		{name: "manual", expectedValue: "1.2.3.4", expectManaged: false},
+		{name: "empty", expectedValue: "10.0.0.1", expectManaged: true},
+		{name: "previously-managed", expectedValue: "10.0.0.1", expectManaged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: tt.name}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if v := got.Annotations[key]; v != tt.expectedValue {
+				t.Errorf("Expected %q, got %q", tt.expectedValue, v)
+			}
+			if managed := got.Annotations[ManagedAnnotationKey] == "true"; managed != tt.expectManaged {
+				t.Errorf("Expected managed=%v, got annotations %v", tt.expectManaged, got.Annotations)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_PatchesManyIngressesConcurrently(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	const total = 50
+	var objs []client.Object
+	for i := 0; i < total; i++ {
+		objs = append(objs, newIngress(fmt.Sprintf("ing-%02d", i), map[string]string{
+			"kubernetes.io/ingress.class": "public-nginx",
+		}))
+	}
+	objs = append(objs, newIngress("current", map[string]string{
+		"kubernetes.io/ingress.class": "public-nginx",
+		"new.example.com/target":      "10.0.0.1",
+	}))
+
+	var (
+		mu                  sync.Mutex
+		inFlight, maxFlight int
+		patched             []string
+	)
+	failing := map[string]bool{"ing-07": true, "ing-33": true}
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			mu.Lock()
+			inFlight++
+			maxFlight = max(maxFlight, inFlight)
+			patched = append(patched, obj.GetName())
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+			time.Sleep(5 * time.Millisecond)
+			if failing[obj.GetName()] {
+				return errors.New("conflict")
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		patchConcurrency:          4,
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(patched) != total {
+		t.Errorf("Expected %d patch attempts, got %d", total, len(patched))
+	}
+	if maxFlight > 4 {
+		t.Errorf("Expected at most 4 patches in flight, saw %d", maxFlight)
+	}
+	if maxFlight < 2 {
+		t.Errorf("Expected patches to run concurrently, saw %d in flight", maxFlight)
+	}
+	for i := 0; i < total; i++ {
+		name := fmt.Sprintf("ing-%02d", i)
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		want := "10.0.0.1"
+		if failing[name] {
+			want = ""
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected annotation %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+}
+
+func TestRunner_Tick_RetriesPatchConflicts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		conflicts int
+		expected  string
+	}{
+		{name: "succeeds on retry", conflicts: 1, expected: "10.0.0.1"},
+		{name: "gives up after retries", conflicts: patchConflictRetries + 1, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"})
+			var attempts int
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(ing).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					attempts++
+					if attempts <= tt.conflicts {
+						return apierrors.NewConflict(networkingv1.Resource("ingresses"), obj.GetName(), errors.New("object was modified"))
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             "new.example.com/target",
+				ips:                       []string{"10.0.0.1"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if got.Annotations["new.example.com/target"] != tt.expected {
+				t.Errorf("Expected annotation %q, got %q", tt.expected, got.Annotations["new.example.com/target"])
+			}
+			if want := min(tt.conflicts+1, patchConflictRetries+1); attempts != want {
+				t.Errorf("Expected %d patch attempts, got %d", want, attempts)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_PartialPatchFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	class := map[string]string{"kubernetes.io/ingress.class": "public-nginx"}
+	var failing atomic.Bool
+	failing.Store(true)
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", class), newIngress("api", class), newIngress("broken", class),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if obj.GetName() == "broken" && failing.Load() {
+				return apierrors.NewForbidden(networkingv1.Resource("ingresses"), obj.GetName(), errors.New("denied by webhook"))
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		patchConcurrency:          3,
+		failHealthzOnPatchErrors:  2,
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	before := testutil.ToFloat64(patchFailures)
+	tick()
+	for _, name := range []string{"web", "api"} {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != "10.0.0.1" {
+			t.Errorf("Expected %s to be patched despite the failure, got %q", name, got.Annotations["new.example.com/target"])
+		}
+	}
+	st := runner.Status()
+	if len(st.PatchErrors) != 1 || !strings.Contains(st.PatchErrors["default/broken"], "denied by webhook") {
+		t.Errorf("Expected a patch error for default/broken, got %v", st.PatchErrors)
+	}
+	if got := testutil.ToFloat64(patchFailures) - before; got != 1 {
+		t.Errorf("Expected patch_failures_total to grow by 1, got %v", got)
+	}
+	if err := runner.HealthzCheck(nil); err != nil {
+		t.Errorf("Expected healthz to pass after one failing tick, got %v", err)
+	}
+
+	tick()
+	if err := runner.HealthzCheck(nil); err == nil {
+		t.Error("Expected healthz to fail after two consecutive failing ticks")
+	}
+
+	failing.Store(false)
+	tick()
+	if err := runner.HealthzCheck(nil); err != nil {
+		t.Errorf("Expected healthz to recover after a clean tick, got %v", err)
+	}
+	if st := runner.Status(); len(st.PatchErrors) != 0 {
+		t.Errorf("Expected no patch errors after a clean tick, got %v", st.PatchErrors)
+	}
+}
+
+func TestRunner_HealthzCheck_Disabled(t *testing.T) {
+	runner := &Runner{patchErrorTicks: 10}
+	if err := runner.HealthzCheck(nil); err != nil {
+		t.Errorf("Expected healthz to pass without a threshold, got %v", err)
+	}
+}
+
+func TestRunner_Tick_CompareAsSet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		current      string
+		compareAsSet bool
+		expectPatch  bool
+	}{
+		{name: "reordered, exact comparison", current: "10.0.0.2,10.0.0.1", expectPatch: true},
+		{name: "reordered, set comparison", current: "10.0.0.2,10.0.0.1", compareAsSet: true},
+		{name: "reordered with spaces, set comparison", current: "10.0.0.2, 10.0.0.1", compareAsSet: true},
+		{name: "different members, set comparison", current: "10.0.0.2,10.0.0.3", compareAsSet: true, expectPatch: true},
+		{name: "missing member, set comparison", current: "10.0.0.2", compareAsSet: true, expectPatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := newIngress("web", map[string]string{
+				"kubernetes.io/ingress.class": "public-nginx",
+				"new.example.com/target":      tt.current,
+			})
+			var patches int
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(ing).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patches++
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             "new.example.com/target",
+				compareAsSet:              tt.compareAsSet,
+				ips:                       []string{"10.0.0.1", "10.0.0.2"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := patches > 0; got != tt.expectPatch {
+				t.Errorf("Expected patch=%v, got %d patches", tt.expectPatch, patches)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_TargetSeparator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		separator    string
+		current      string
+		compareAsSet bool
+		expectPatch  bool
+		expected     string
+	}{
+		{name: "newline, missing value", separator: "\n", expectPatch: true, expected: "10.0.0.1\n10.0.0.2"},
+		{name: "newline, current", separator: "\n", current: "10.0.0.1\n10.0.0.2", expected: "10.0.0.1\n10.0.0.2"},
+		{name: "newline, reordered, set comparison", separator: "\n", current: "10.0.0.2\n10.0.0.1\n", compareAsSet: true, expected: "10.0.0.2\n10.0.0.1\n"},
+		{name: "newline, comma-separated, set comparison", separator: "\n", current: "10.0.0.2,10.0.0.1", compareAsSet: true, expectPatch: true, expected: "10.0.0.1\n10.0.0.2"},
+		{name: "custom, reordered, set comparison", separator: " | ", current: "10.0.0.2 | 10.0.0.1", compareAsSet: true, expected: "10.0.0.2 | 10.0.0.1"},
+		{name: "custom, reordered, exact comparison", separator: " | ", current: "10.0.0.2 | 10.0.0.1", expectPatch: true, expected: "10.0.0.1 | 10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{"kubernetes.io/ingress.class": "public-nginx"}
+			if tt.current != "" {
+				annotations["new.example.com/target"] = tt.current
+			}
+			var patches int
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(newIngress("web", annotations)).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patches++
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			tmpl, err := parseValueTemplate(DefaultAnnotationValueTemplate)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             "new.example.com/target",
+				valueTemplate:             tmpl,
+				targetSeparator:           tt.separator,
+				compareAsSet:              tt.compareAsSet,
+				ips:                       []string{"10.0.0.1", "10.0.0.2"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := patches > 0; got != tt.expectPatch {
+				t.Errorf("Expected patch=%v, got %d patches", tt.expectPatch, patches)
+			}
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if v := got.Annotations["new.example.com/target"]; v != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, v)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateTargetSeparator(t *testing.T) {
+	for _, sep := range []string{",", "\n", "; ", " | "} {
+		opts := Options{IPs: []string{"10.0.0.1"}, TargetSeparator: sep}
+		if err := opts.validate(); err != nil {
+			t.Errorf("Unexpected error for %q: %v", sep, err)
+		}
+	}
+	for _, sep := range []string{".", ":", "a", "0"} {
+		opts := Options{IPs: []string{"10.0.0.1"}, TargetSeparator: sep}
+		if err := opts.validate(); err == nil {
+			t.Errorf("Expected separator %q to be rejected", sep)
+		}
+	}
+}
+
+func TestRunner_Tick_Deadline(t *testing.T) {
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ips := make([]string, 10)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.0.%d", i+1)
+	}
+	runner := &Runner{
+		ips:          ips,
+		httpClient:   newRoutedHTTPClient(server),
+		urlScheme:    "http",
+		httpPath:     "/",
+		timeout:      time.Second,
+		tickDeadline: 300 * time.Millisecond,
+	}
+	before := testutil.ToFloat64(tickDeadlineExceeded)
+
+	start := time.Now()
+	err := runner.tick(context.Background())
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, errTickDeadlineExceeded) {
+		t.Errorf("Expected errTickDeadlineExceeded, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected the tick to stop at its deadline, took %s", elapsed)
+	}
+	if n := probes.Load(); n >= int32(len(ips)) {
+		t.Errorf("Expected remaining probes to be cancelled, got %d probes", n)
+	}
+	if got := testutil.ToFloat64(tickDeadlineExceeded) - before; got != 1 {
+		t.Errorf("Expected tick_deadline_exceeded_total to increase by 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(tickTimeoutSeconds); got != 0.3 {
+		t.Errorf("Expected tick_timeout_seconds 0.3, got %v", got)
+	}
+}
+
+func TestRunner_Tick_PartialResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host != "10.0.0.1" {
+			// hang until the tick deadline cuts the probe short
+			<-r.Context().Done()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		usePartial bool
+		expected   string
+		expectErr  error
+	}{
+		{name: "skipped by default", expected: "keep", expectErr: errTickDeadlineExceeded},
+		{name: "used when enabled", usePartial: true, expected: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx", "new.example.com/target": "keep"}),
+			).Build()
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             "new.example.com/target",
+				ips:                       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+				tickDeadline:              200 * time.Millisecond,
+				usePartialResults:         tt.usePartial,
+			}
+			if err := runner.tick(context.Background()); !errors.Is(err, tt.expectErr) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if v := got.Annotations["new.example.com/target"]; v != tt.expected {
+				t.Errorf("Expected annotation %q, got %q", tt.expected, v)
+			}
+			// the probe cut short by the deadline is not a failure
+			if st := runner.Status(); tt.usePartial && len(st.Errors) != 0 {
+				t.Errorf("Expected no probe errors for unprobed IPs, got %v", st.Errors)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_MultipleIngressClasses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	classes := map[string]string{
+		"nginx":   "public-nginx",
+		"haproxy": "public-haproxy",
+		"private": "private-nginx",
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, cls := range classes {
+		builder = builder.WithObjects(newIngress(name, map[string]string{"kubernetes.io/ingress.class": cls}))
+	}
+	builder = builder.WithObjects(newIngress("unclassed", map[string]string{"other": "x"}))
+	k8s := builder.Build()
+
+	runner, err := New(Options{
+		Client:                  k8s,
+		AnnotationKey:           "new.example.com/target",
+		AnnotationValueTemplate: `{{ .IngressClass }}={{ join .IPs "," }}`,
+		IngressClass:            "public-nginx, public-haproxy",
+		IPs:                     []string{"10.0.0.1"},
+		HTTPClient:              newRoutedHTTPClient(server),
+		Timeout:                 time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"nginx":     "public-nginx=10.0.0.1",
+		"haproxy":   "public-haproxy=10.0.0.1",
+		"private":   "",
+		"unclassed": "",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if got.Annotations["new.example.com/target"] != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, got.Annotations["new.example.com/target"])
+		}
+	}
+}
+
+func TestRunner_Start_SkipInitialTick(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip=%t", skip), func(t *testing.T) {
+			var probes atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				probes.Add(1)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			interval := 300 * time.Millisecond
+			runner := &Runner{
+				ips:             []string{"10.0.0.1"},
+				httpClient:      newRoutedHTTPClient(server),
+				urlScheme:       "http",
+				httpPath:        "/",
+				timeout:         time.Second,
+				interval:        interval,
+				maxInterval:     interval,
+				skipInitialTick: skip,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = runner.Start(ctx)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			time.Sleep(interval / 2)
+			want := int32(1)
+			if skip {
+				want = 0
+			}
+			if got := probes.Load(); got != want {
+				t.Fatalf("Expected %d probes before the first interval, got %d", want, got)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for probes.Load() <= want {
+				if time.Now().After(deadline) {
+					t.Fatal("Expected a probe once the first interval elapsed")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_WriteTimestampAnnotation(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" && down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:                   k8s,
+		AnnotationKey:            "new.example.com/target",
+		IPs:                      []string{"10.0.0.1", "10.0.0.2"},
+		HTTPClient:               newRoutedHTTPClient(server),
+		WriteTimestampAnnotation: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	const key = "ingress-target-prober/last-updated"
+	get := func() *networkingv1.Ingress {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got
+	}
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tick()
+	if _, err := time.Parse(time.RFC3339, get().Annotations[key]); err != nil {
+		t.Fatalf("Expected an RFC3339 timestamp on change, got %v", get().Annotations)
+	}
+
+	// mark the timestamp so a rewrite is visible within the same second
+	ing := get()
+	ing.Annotations[key] = "2000-01-01T00:00:00Z"
+	if err := k8s.Update(context.Background(), ing); err != nil {
+		t.Fatalf("failed to update Ingress: %v", err)
+	}
+	tick()
+	if got := get().Annotations[key]; got != "2000-01-01T00:00:00Z" {
+		t.Errorf("Expected a no-op tick to keep the timestamp, got %q", got)
+	}
+
+	down.Store(true)
+	tick()
+	got := get().Annotations
+	if got["new.example.com/target"] != "10.0.0.1" {
+		t.Fatalf("Expected the target value to change, got %v", got)
+	}
+	if ts, err := time.Parse(time.RFC3339, got[key]); err != nil || ts.Year() == 2000 {
+		t.Errorf("Expected a fresh timestamp on change, got %q", got[key])
+	}
+}
@@ -0,0 +1,81 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// SchemeMatchAll requires every probe scheme to pass; SchemeMatchAny is
+// satisfied by a single one.
+const (
+	SchemeMatchAll = "all"
+	SchemeMatchAny = "any"
+)
+
+// splitSchemes parses a comma-separated scheme list such as "http,https".
+func splitSchemes(s string) []string {
+	var schemes []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			schemes = append(schemes, p)
+		}
+	}
+	return schemes
+}
+
+// validateSchemes checks the probe schemes and how they are combined; an
+// empty Scheme means DefaultScheme. Only HTTP probes can check more than one
+// scheme.
+func (o *Options) validateSchemes() error {
+	schemes := splitSchemes(o.Scheme)
+	if o.Scheme != "" && len(schemes) == 0 {
+		return fmt.Errorf("at least one scheme is required")
+	}
+	seen := make(map[string]bool, len(schemes))
+	for _, s := range schemes {
+		if s != "http" && s != "https" {
+			return fmt.Errorf("unsupported scheme %q (want http or https)", s)
+		}
+		if seen[s] {
+			return fmt.Errorf("scheme %q listed twice", s)
+		}
+		seen[s] = true
+	}
+	if len(schemes) > 1 && o.ProbeMode != "" && o.ProbeMode != ProbeModeHTTP {
+		return fmt.Errorf("multiple schemes require the http probe mode")
+	}
+	switch o.SchemeMatch {
+	case "", SchemeMatchAll, SchemeMatchAny:
+	default:
+		return fmt.Errorf("unsupported scheme match %q (want %s or %s)", o.SchemeMatch, SchemeMatchAll, SchemeMatchAny)
+	}
+	return nil
+}
+
+// probeHTTP probes ip on path over every configured scheme, each on its own
+// port. With SchemeMatchAll (the default) the IP is healthy only when all
+// schemes pass, with SchemeMatchAny when at least one does. An empty host
+// sends the address probed.
+func (r *Runner) probeHTTP(ctx context.Context, logger logr.Logger, ip, path, host string) error {
+	if len(r.urlSchemes) < 2 {
+		return r.probeURL(ctx, logger, ip, r.urlScheme, path, host)
+	}
+	var firstErr error
+	for _, scheme := range r.urlSchemes {
+		err := r.probeURL(ctx, logger, ip, scheme, path, host)
+		if r.schemeMatch == SchemeMatchAny {
+			if err == nil {
+				return nil
+			}
+		} else if err != nil {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,102 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunner_HealthyIPs_Schemes(t *testing.T) {
+	// 10.0.0.1 serves both schemes, 10.0.0.2 only http, 10.0.0.3 only https
+	serving := func(scheme string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, _ := net.SplitHostPort(r.Host)
+			if (scheme == "https" && host == "10.0.0.2") || (scheme == "http" && host == "10.0.0.3") {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	plain := httptest.NewServer(serving("http"))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(serving("https"))
+	defer secure.Close()
+
+	d := &net.Dialer{}
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				srv := plain
+				if _, port, _ := net.SplitHostPort(addr); port == "443" {
+					srv = secure
+				}
+				return d.DialContext(ctx, network, srv.Listener.Addr().String())
+			},
+		},
+	}
+
+	tests := []struct {
+		scheme  string
+		match   string
+		healthy []string
+	}{
+		{scheme: "http", healthy: []string{"10.0.0.1", "10.0.0.2"}},
+		{scheme: "https", healthy: []string{"10.0.0.1", "10.0.0.3"}},
+		{scheme: "http,https", healthy: []string{"10.0.0.1"}},
+		{scheme: "http,https", match: SchemeMatchAll, healthy: []string{"10.0.0.1"}},
+		{scheme: "https, http", match: SchemeMatchAny, healthy: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme+"/"+tt.match, func(t *testing.T) {
+			schemes := splitSchemes(tt.scheme)
+			runner := &Runner{
+				ips:         []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+				httpClient:  httpClient,
+				urlScheme:   schemes[0],
+				urlSchemes:  schemes,
+				schemeMatch: tt.match,
+				httpPath:    "/",
+				timeout:     time.Second,
+			}
+			healthy, _, err := runner.HealthyIPs(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(healthy, tt.healthy) {
+				t.Errorf("Expected %v, got %v", tt.healthy, healthy)
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateSchemes(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "both", opts: Options{Scheme: "http,https", SchemeMatch: SchemeMatchAny}},
+		{name: "unsupported", opts: Options{Scheme: "ftp"}, expectError: true},
+		{name: "duplicate", opts: Options{Scheme: "https,HTTPS"}, expectError: true},
+		{name: "empty list", opts: Options{Scheme: ","}, expectError: true},
+		{name: "unsupported match", opts: Options{Scheme: "http,https", SchemeMatch: "most"}, expectError: true},
+		{name: "tcp mode", opts: Options{Scheme: "http,https", ProbeMode: ProbeModeTCP}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
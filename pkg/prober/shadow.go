@@ -0,0 +1,41 @@
+package prober
+
+import "fmt"
+
+func (o *Options) validateShadow() error {
+	if o.ShadowOnly && o.ShadowAnnotationKey == "" {
+		return fmt.Errorf("shadow-only mode requires a shadow annotation key")
+	}
+	if k := o.ShadowAnnotationKey; k != "" && (k == o.AnnotationKey || k == o.AnnotationKeyV6) {
+		return fmt.Errorf("shadow annotation key %q must differ from the target annotation keys", k)
+	}
+	return nil
+}
+
+// valueKeys returns the annotation keys that carry the rendered target
+// value: the main key (and IPv6 key) unless in shadow-only mode, plus the
+// shadow key. Shutdown cleanup and removal on no healthy IP act on these.
+func (r *Runner) valueKeys() []string {
+	var keys []string
+	if !r.shadowOnly {
+		keys = append(keys, r.annotationKey)
+		if r.annotationKeyV6 != "" {
+			keys = append(keys, r.annotationKeyV6)
+		}
+	}
+	if r.shadowAnnotationKey != "" {
+		keys = append(keys, r.shadowAnnotationKey)
+	}
+	return keys
+}
+
+// writesRecordType reports whether the record type hint is managed; in
+// shadow-only mode nothing beside the shadow key is written.
+func (r *Runner) writesRecordType() bool {
+	return r.recordType != "" && !r.shadowOnly
+}
+
+// writesCount reports whether the healthy-count annotation is managed.
+func (r *Runner) writesCount() bool {
+	return r.writeCount && !r.shadowOnly
+}
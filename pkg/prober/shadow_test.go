@@ -0,0 +1,134 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_ShadowAnnotation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	const (
+		key    = "external-dns.alpha.kubernetes.io/target"
+		shadow = "ingress-target-prober/shadow-target"
+	)
+	tests := []struct {
+		name          string
+		current       map[string]string
+		shadowOnly    bool
+		expectPrimary string
+		expectShadow  string
+	}{
+		{
+			name:          "shadow alongside the main key",
+			expectPrimary: "10.0.0.1,10.0.0.2",
+			expectShadow:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:          "shadow added while the main key is current",
+			current:       map[string]string{key: "10.0.0.1,10.0.0.2"},
+			expectPrimary: "10.0.0.1,10.0.0.2",
+			expectShadow:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:          "shadow only keeps a manual main key",
+			current:       map[string]string{key: "192.0.2.10"},
+			shadowOnly:    true,
+			expectPrimary: "192.0.2.10",
+			expectShadow:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:         "shadow only without a main key",
+			shadowOnly:   true,
+			expectShadow: "10.0.0.1,10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{"kubernetes.io/ingress.class": "public-nginx"}
+			for k, v := range tt.current {
+				annotations[k] = v
+			}
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(newIngress("web", annotations)).Build()
+			runner := &Runner{
+				k8s:                       k8s,
+				ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+				ingressClasses:            []string{"public-nginx"},
+				annotationKey:             key,
+				shadowAnnotationKey:       shadow,
+				shadowOnly:                tt.shadowOnly,
+				recordType:                "A",
+				cleanupOnShutdown:         true,
+				ips:                       []string{"10.0.0.1", "10.0.0.2"},
+				httpClient:                newRoutedHTTPClient(server),
+				urlScheme:                 "http",
+				httpPath:                  "/",
+				timeout:                   time.Second,
+			}
+			get := func() map[string]string {
+				t.Helper()
+				ing := &networkingv1.Ingress{}
+				if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, ing); err != nil {
+					t.Fatalf("failed to get Ingress: %v", err)
+				}
+				return ing.Annotations
+			}
+
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := get()
+			if got[shadow] != tt.expectShadow {
+				t.Errorf("Expected shadow %q, got %q", tt.expectShadow, got[shadow])
+			}
+			if got[key] != tt.expectPrimary {
+				t.Errorf("Expected main annotation %q, got %q", tt.expectPrimary, got[key])
+			}
+			if _, ok := got[RecordTypeAnnotationKey]; ok == tt.shadowOnly {
+				t.Errorf("Expected the record type hint only outside shadow-only mode, got %v", got)
+			}
+
+			runner.cleanup(context.Background())
+			got = get()
+			if _, ok := got[shadow]; ok {
+				t.Errorf("Expected the shadow annotation to be removed on shutdown, got %v", got)
+			}
+			if tt.shadowOnly && got[key] != tt.expectPrimary {
+				t.Errorf("Expected shutdown to leave the main annotation in shadow-only mode, got %q", got[key])
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateShadow(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "shadow key", opts: Options{ShadowAnnotationKey: "ingress-target-prober/shadow-target"}},
+		{name: "shadow only", opts: Options{ShadowAnnotationKey: "ingress-target-prober/shadow-target", ShadowOnly: true}},
+		{name: "shadow only without key", opts: Options{ShadowOnly: true}, wantErr: true},
+		{name: "shadow key equals main key", opts: Options{AnnotationKey: "example.com/target", ShadowAnnotationKey: "example.com/target"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
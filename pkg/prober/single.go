@@ -0,0 +1,52 @@
+package prober
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Single-target selection policies.
+const (
+	SingleTargetFirst      = "first"
+	SingleTargetFastest    = "fastest"
+	SingleTargetRandom     = "random"
+	SingleTargetRoundRobin = "round-robin"
+)
+
+// validateSingleTargetPolicy accepts "" (first) and the known policies.
+func validateSingleTargetPolicy(p string) error {
+	switch p {
+	case "", SingleTargetFirst, SingleTargetFastest, SingleTargetRandom, SingleTargetRoundRobin:
+		return nil
+	}
+	return fmt.Errorf("unsupported single target policy %q (want %s, %s, %s or %s)", p, SingleTargetFirst, SingleTargetFastest, SingleTargetRandom, SingleTargetRoundRobin)
+}
+
+// selectSingleTarget picks the one healthy IP to write according to the
+// single-target policy. Round-robin moves on to the healthy IP following the
+// one written by the previous tick, in configured order, so the rotation
+// survives IPs dropping in and out. healthy must not be empty.
+func (r *Runner) selectSingleTarget(healthy []string, latencies map[string]time.Duration) string {
+	var ip string
+	switch r.singleTargetPolicy {
+	case SingleTargetFastest:
+		ip = fastestIPs(healthy, latencies, 1)[0]
+	case SingleTargetRandom:
+		ip = healthy[r.randInt63n(int64(len(healthy)))]
+	case SingleTargetRoundRobin:
+		ip = healthy[0]
+		if prev := slices.Index(r.currentIPs(), r.lastSingle); prev >= 0 {
+			for _, candidate := range r.currentIPs()[prev+1:] {
+				if slices.Contains(healthy, candidate) {
+					ip = candidate
+					break
+				}
+			}
+		}
+	default:
+		ip = healthy[0]
+	}
+	r.lastSingle = ip
+	return ip
+}
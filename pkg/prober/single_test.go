@@ -0,0 +1,112 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_SelectSingleTarget(t *testing.T) {
+	healthy := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	latencies := map[string]time.Duration{"10.0.0.1": 30 * time.Millisecond, "10.0.0.2": 20 * time.Millisecond, "10.0.0.3": 10 * time.Millisecond}
+
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{policy: SingleTargetFirst, expected: []string{"10.0.0.1", "10.0.0.1"}},
+		{policy: SingleTargetFastest, expected: []string{"10.0.0.3", "10.0.0.3"}},
+		{policy: SingleTargetRandom, expected: []string{"10.0.0.2", "10.0.0.3"}},
+		{policy: SingleTargetRoundRobin, expected: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			draws := []int64{1, 2}
+			runner := &Runner{
+				ips:                healthy,
+				singleTargetPolicy: tt.policy,
+				randInt63n: func(n int64) int64 {
+					d := draws[0]
+					draws = draws[1:]
+					return d % n
+				},
+			}
+			for i, want := range tt.expected {
+				if got := runner.selectSingleTarget(healthy, latencies); got != want {
+					t.Errorf("Selection %d: expected %s, got %s", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_SingleTargetRoundRobin(t *testing.T) {
+	down := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); down[host] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		singleTarget:              true,
+		singleTargetPolicy:        SingleTargetRoundRobin,
+		ips:                       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+	}
+	tick := func() string {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations["new.example.com/target"]
+	}
+
+	if got := tick(); got != "10.0.0.1" {
+		t.Errorf("Expected the first IP, got %q", got)
+	}
+	// an unhealthy IP is skipped by the rotation
+	down["10.0.0.2"] = true
+	if got := tick(); got != "10.0.0.3" {
+		t.Errorf("Expected the rotation to skip the unhealthy IP, got %q", got)
+	}
+	delete(down, "10.0.0.2")
+	if got := tick(); got != "10.0.0.1" {
+		t.Errorf("Expected the rotation to wrap around, got %q", got)
+	}
+	if got := tick(); got != "10.0.0.2" {
+		t.Errorf("Expected the recovered IP next, got %q", got)
+	}
+}
+
+func TestOptions_ValidateSingleTargetPolicy(t *testing.T) {
+	opts := Options{IPs: []string{"10.0.0.1"}, SingleTarget: true, SingleTargetPolicy: "weighted"}
+	if err := opts.validate(); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialContextFunc matches net.Dialer.DialContext.
+type dialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// parseSOCKS5URL accepts host:port or a socks5:// URL with optional
+// user:password credentials.
+func parseSOCKS5URL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("socks5://" + s)
+	}
+	if err != nil || u.Scheme != "socks5" || u.Host == "" {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q", s)
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q: %w", s, err)
+	}
+	return u, nil
+}
+
+// dialContext returns the dialer shared by HTTP and TCP probes. It applies
+// DialTimeout and TCPKeepAlive, binds to ProbeSourceIP, resolves HostMap
+// names and, with ProbeSOCKS5 set, tunnels through that proxy.
+func (o *Options) dialContext() dialContextFunc {
+	dial := o.baseDialContext()
+	// validate has already checked the host map
+	if hosts, _ := parseHostMap(o.HostMap); len(hosts) > 0 {
+		return mapHosts(dial, hosts)
+	}
+	return dial
+}
+
+// baseDialContext returns the dialer without host mapping.
+func (o *Options) baseDialContext() dialContextFunc {
+	dialer := &net.Dialer{Timeout: o.DialTimeout, KeepAlive: o.TCPKeepAlive}
+	if o.ProbeSourceIP != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(o.ProbeSourceIP)}
+	}
+	if o.ProbeSOCKS5 == "" {
+		return dialer.DialContext
+	}
+	// validate has already checked the proxy address
+	u, _ := parseSOCKS5URL(o.ProbeSOCKS5)
+	var auth *proxy.Auth
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+	}
+	socks, _ := proxy.SOCKS5("tcp", u.Host, auth, dialer)
+	return socks.(proxy.ContextDialer).DialContext
+}
@@ -0,0 +1,85 @@
+package prober
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateTracker remembers when each managed object's annotations were last
+// updated successfully and exports the age as seconds_since_last_update.
+// A nil *updateTracker tracks nothing.
+type updateTracker struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	last map[types.NamespacedName]time.Time
+}
+
+func newUpdateTracker() *updateTracker {
+	return &updateTracker{now: time.Now, last: map[types.NamespacedName]time.Time{}}
+}
+
+// record marks key as updated now.
+func (t *updateTracker) record(key types.NamespacedName) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last[key] = t.now()
+}
+
+// seed starts tracking key from the time in its last-updated annotation, so
+// objects already current at startup are not missing from the gauge. Keys
+// with a recorded update are left alone.
+func (t *updateTracker) seed(key types.NamespacedName, lastUpdated string) {
+	if t == nil || lastUpdated == "" {
+		return
+	}
+	at, err := time.Parse(time.RFC3339, lastUpdated)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.last[key]; !ok {
+		t.last[key] = at
+	}
+}
+
+// retain forgets objects that no longer exist and drops their series.
+func (t *updateTracker) retain(objs []client.Object) {
+	if t == nil {
+		return
+	}
+	keep := make(map[types.NamespacedName]struct{}, len(objs))
+	for _, obj := range objs {
+		keep[client.ObjectKeyFromObject(obj)] = struct{}{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.last {
+		if _, ok := keep[key]; !ok {
+			delete(t.last, key)
+			secondsSinceLastUpdate.DeleteLabelValues(key.String())
+		}
+	}
+}
+
+// observe sets the gauge for every tracked object. It runs at the end of
+// each tick, whether or not the tick patched anything.
+func (t *updateTracker) observe() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for key, at := range t.last {
+		secondsSinceLastUpdate.WithLabelValues(key.String()).Set(math.Max(0, now.Sub(at).Seconds()))
+	}
+}
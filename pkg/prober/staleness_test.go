@@ -0,0 +1,94 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_SecondsSinceLastUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("stale-web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newUpdateTracker()
+	tracker.now = func() time.Time { return now }
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		lastUpdates:               tracker,
+	}
+	gauge := secondsSinceLastUpdate.WithLabelValues("default/stale-web")
+	tick := func() {
+		t.Helper()
+		if err := runner.tick(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tick()
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Errorf("Expected 0 right after the update, got %v", got)
+	}
+
+	now = now.Add(45 * time.Second)
+	tick()
+	if got := testutil.ToFloat64(gauge); got != 45 {
+		t.Errorf("Expected 45 after a tick without changes, got %v", got)
+	}
+	now = now.Add(30 * time.Second)
+	tick()
+	if got := testutil.ToFloat64(gauge); got != 75 {
+		t.Errorf("Expected the gauge to keep increasing, got %v", got)
+	}
+
+	runner.ips = []string{"10.0.0.1", "10.0.0.2"}
+	now = now.Add(15 * time.Second)
+	tick()
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Errorf("Expected the gauge to reset on update, got %v", got)
+	}
+}
+
+func TestUpdateTracker_SeedAndRetain(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newUpdateTracker()
+	tracker.now = func() time.Time { return now }
+	key := types.NamespacedName{Namespace: "default", Name: "seeded-web"}
+
+	tracker.seed(key, "not a timestamp")
+	tracker.seed(key, now.Add(-time.Minute).Format(time.RFC3339))
+	tracker.seed(key, now.Add(-time.Hour).Format(time.RFC3339))
+	tracker.observe()
+	if got := testutil.ToFloat64(secondsSinceLastUpdate.WithLabelValues(key.String())); got != 60 {
+		t.Errorf("Expected the first valid last-updated annotation to seed the gauge, got %v", got)
+	}
+
+	tracker.retain([]client.Object{newIngress("other-web", nil)})
+	tracker.observe()
+	if _, ok := tracker.last[key]; ok {
+		t.Error("Expected a deleted object to be forgotten")
+	}
+	if secondsSinceLastUpdate.DeleteLabelValues(key.String()) {
+		t.Error("Expected the series of a deleted object to be dropped")
+	}
+}
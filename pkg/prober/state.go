@@ -0,0 +1,121 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// stateKey is the ConfigMap data key holding the persisted state.
+const stateKey = "state.json"
+
+// persistedState is the probe state carried across restarts.
+type persistedState struct {
+	// Healthy is the last known healthy set.
+	Healthy []string `json:"healthy"`
+	// ConsecutiveFailures drives the backed-off interval.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// Results holds each IP's health window, oldest first.
+	Results map[string][]bool `json:"results,omitempty"`
+	SavedAt time.Time         `json:"savedAt"`
+}
+
+// parseNamespacedRef parses a "namespace/name" ConfigMap reference.
+func parseNamespacedRef(s string) (types.NamespacedName, error) {
+	ns, name, ok := strings.Cut(s, "/")
+	if !ok || ns == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("invalid ConfigMap reference %q (want namespace/name)", s)
+	}
+	return types.NamespacedName{Namespace: ns, Name: name}, nil
+}
+
+// loadState restores the state saved by a previous run. A missing ConfigMap
+// or corrupt state is logged and the Runner starts fresh.
+func (r *Runner) loadState(ctx context.Context) {
+	logger := log.FromContext(ctx).WithValues("configmap", r.stateConfigMap.String())
+	cm := &corev1.ConfigMap{}
+	if err := r.k8s.Get(ctx, *r.stateConfigMap, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("no persisted state found; starting fresh")
+		} else {
+			logger.Error(err, "failed to load persisted state; starting fresh")
+		}
+		return
+	}
+	var st persistedState
+	if err := json.Unmarshal([]byte(cm.Data[stateKey]), &st); err != nil {
+		logger.Error(err, "ignoring corrupt persisted state; starting fresh")
+		return
+	}
+
+	r.consecutiveFailures = st.ConsecutiveFailures
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastHealthy = st.Healthy
+	r.observed = st.Healthy != nil
+	if r.healthWindow > 0 && len(st.Results) > 0 {
+		r.windows = make(map[string]*resultWindow, len(st.Results))
+		for ip, results := range st.Results {
+			w := newResultWindow(r.healthWindow)
+			for _, ok := range results {
+				w.add(ok)
+			}
+			r.windows[ip] = w
+		}
+	}
+	logger.Info("restored persisted state", "healthy", strings.Join(st.Healthy, ","), "consecutive_failures", st.ConsecutiveFailures, "saved_at", st.SavedAt)
+}
+
+// saveState writes the current state to the state ConfigMap, creating it if needed.
+func (r *Runner) saveState(ctx context.Context) error {
+	st := persistedState{ConsecutiveFailures: r.consecutiveFailures, SavedAt: time.Now().UTC()}
+	r.mu.Lock()
+	st.Healthy = append([]string{}, r.lastHealthy...)
+	if len(r.windows) > 0 {
+		st.Results = make(map[string][]bool, len(r.windows))
+		for ip, w := range r.windows {
+			st.Results[ip] = w.values()
+		}
+	}
+	r.mu.Unlock()
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.k8s.Get(ctx, *r.stateConfigMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.stateConfigMap.Namespace, Name: r.stateConfigMap.Name},
+			Data:       map[string]string{stateKey: string(data)},
+		}
+		return r.k8s.Create(ctx, cm)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[stateKey] = string(data)
+	return r.k8s.Update(ctx, cm)
+}
+
+// persistState saves the state when a state ConfigMap is configured, logging
+// failures; the next save retries.
+func (r *Runner) persistState(ctx context.Context) {
+	if r.stateConfigMap == nil {
+		return
+	}
+	if err := r.saveState(ctx); err != nil {
+		log.FromContext(ctx).Error(err, "failed to persist state", "configmap", r.stateConfigMap.String())
+	}
+}
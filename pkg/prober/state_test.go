@@ -0,0 +1,104 @@
+package prober
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testStateRef = types.NamespacedName{Namespace: "prober", Name: "state"}
+
+func TestRunner_SaveAndLoadState(t *testing.T) {
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	saved := &Runner{k8s: k8s, stateConfigMap: &testStateRef, ips: []string{"10.0.0.1", "10.0.0.2"}, healthWindow: 3}
+	saved.consecutiveFailures = 2
+	saved.mu.Lock()
+	saved.recordWindow([]string{"10.0.0.1"})
+	saved.recordWindow([]string{"10.0.0.1", "10.0.0.2"})
+	saved.lastHealthy = []string{"10.0.0.1", "10.0.0.2"}
+	saved.mu.Unlock()
+
+	// the first save creates the ConfigMap, the second updates it
+	for i := 0; i < 2; i++ {
+		if err := saved.saveState(context.Background()); err != nil {
+			t.Fatalf("save %d: unexpected error: %v", i, err)
+		}
+	}
+
+	loaded := &Runner{k8s: k8s, stateConfigMap: &testStateRef, ips: []string{"10.0.0.1", "10.0.0.2"}, healthWindow: 3}
+	loaded.loadState(context.Background())
+
+	if loaded.consecutiveFailures != 2 {
+		t.Errorf("Expected 2 consecutive failures, got %d", loaded.consecutiveFailures)
+	}
+	st := loaded.Status()
+	if !slices.Equal(st.Healthy, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("Expected restored healthy set, got %v", st.Healthy)
+	}
+	if st.SuccessRatio["10.0.0.1"] != 1 || st.SuccessRatio["10.0.0.2"] != 0.5 {
+		t.Errorf("Expected restored success ratios, got %v", st.SuccessRatio)
+	}
+	if got := loaded.windows["10.0.0.2"].values(); !slices.Equal(got, []bool{false, true}) {
+		t.Errorf("Expected window order to be preserved, got %v", got)
+	}
+}
+
+func TestRunner_LoadState_Recovery(t *testing.T) {
+	tests := []struct {
+		name string
+		cm   *corev1.ConfigMap
+	}{
+		{name: "absent"},
+		{name: "corrupt", cm: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prober", Name: "state"},
+			Data:       map[string]string{stateKey: "{not json"},
+		}},
+		{name: "missing key", cm: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prober", Name: "state"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(testScheme)
+			if tt.cm != nil {
+				builder = builder.WithObjects(tt.cm)
+			}
+			k8s := builder.Build()
+			runner := &Runner{k8s: k8s, stateConfigMap: &testStateRef, ips: []string{"10.0.0.1"}, healthWindow: 3}
+
+			runner.loadState(context.Background())
+			if runner.consecutiveFailures != 0 || runner.observed || len(runner.windows) != 0 {
+				t.Errorf("Expected fresh state, got failures=%d observed=%v windows=%v", runner.consecutiveFailures, runner.observed, runner.windows)
+			}
+
+			// saving afterwards overwrites whatever was there
+			if err := runner.saveState(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			cm := &corev1.ConfigMap{}
+			if err := k8s.Get(context.Background(), testStateRef, cm); err != nil {
+				t.Fatalf("failed to get state ConfigMap: %v", err)
+			}
+			if cm.Data[stateKey] == "" || cm.Data[stateKey] == "{not json" {
+				t.Errorf("Expected valid state to be written, got %q", cm.Data[stateKey])
+			}
+		})
+	}
+}
+
+func TestParseStateConfigMapRef(t *testing.T) {
+	if ref, err := parseNamespacedRef("prober/state"); err != nil || ref != testStateRef {
+		t.Errorf("Unexpected result %v, %v", ref, err)
+	}
+	for _, s := range []string{"", "state", "/state", "prober/", "a/b/c"} {
+		if _, err := parseNamespacedRef(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package prober
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Status is a point-in-time snapshot of the Runner's probe results.
+type Status struct {
+	// Healthy is the healthy set from the most recent tick.
+	Healthy []string `json:"healthy"`
+	// LastTick is when the most recent tick finished probing; zero before the first tick.
+	LastTick time.Time `json:"lastTick,omitempty"`
+	// SuccessRatio is the per-IP share of successful probes over the health window.
+	SuccessRatio map[string]float64 `json:"successRatio,omitempty"`
+	// Errors maps each IP that failed the most recent tick to its error type.
+	Errors map[string]string `json:"errors,omitempty"`
+	// Paused is set while annotation updates are paused.
+	Paused bool `json:"paused"`
+	// PatchErrors maps each object whose patch failed in the most recent tick to the error.
+	PatchErrors map[string]string `json:"patchErrors,omitempty"`
+	// PatchBreaker is the state of the patch circuit breaker.
+	PatchBreaker string `json:"patchBreaker,omitempty"`
+	// Deferred is the latest healthy set held back outside the update window.
+	Deferred []string `json:"deferred,omitempty"`
+	// Overrides maps each IP with an active operator override to it.
+	Overrides map[string]StateOverride `json:"overrides,omitempty"`
+	// Metadata maps each IP to its target labels listed in MetadataLabels.
+	Metadata map[string]map[string]string `json:"metadata,omitempty"`
+}
+
+// Status returns a snapshot of the current probe state. It is safe for concurrent use.
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := Status{
+		Healthy:      append([]string{}, r.lastHealthy...),
+		LastTick:     r.lastTick,
+		Paused:       r.paused.Load(),
+		PatchBreaker: r.breaker.State(),
+		Overrides:    r.forced.active(),
+	}
+	if len(r.deferred) > 0 {
+		st.Deferred = append([]string{}, r.deferred...)
+	}
+	if len(r.lastErrors) > 0 {
+		st.Errors = make(map[string]string, len(r.lastErrors))
+		for ip, typ := range r.lastErrors {
+			st.Errors[ip] = typ
+		}
+	}
+	if len(r.patchErrors) > 0 {
+		st.PatchErrors = make(map[string]string, len(r.patchErrors))
+		for key, msg := range r.patchErrors {
+			st.PatchErrors[key] = msg
+		}
+	}
+	if len(r.windows) > 0 {
+		st.SuccessRatio = make(map[string]float64, len(r.windows))
+		for ip, w := range r.windows {
+			st.SuccessRatio[ip] = w.ratio()
+		}
+	}
+	for _, ip := range r.currentIPs() {
+		if meta := r.metadata(ip); meta != nil {
+			if st.Metadata == nil {
+				st.Metadata = map[string]map[string]string{}
+			}
+			st.Metadata[ip] = meta
+		}
+	}
+	return st
+}
+
+// StatusHandler serves the Runner's Status as JSON on GET /status, its
+// readiness, with the reason when not ready, on GET /readyz, its liveness on
+// GET /livez, runs an on-demand probe cycle on POST /probe, pauses or resumes
+// annotation updates on POST /pause and POST /resume and forces an IP's probe
+// result on POST /override/{ip}.
+func (r *Runner) StatusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Status())
+	})
+	mux.HandleFunc("POST /probe", r.serveProbe)
+	mux.HandleFunc("POST /pause", r.servePause(true))
+	mux.HandleFunc("POST /resume", r.servePause(false))
+	mux.HandleFunc("POST /override/{ip}", r.serveOverride)
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := r.ReadyzCheck(req); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "not ready: %s\n", err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := r.LivezCheck(req); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "not live: %s\n", err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// ProbeResult is the response of POST /probe: the Status after the triggered
+// tick and the tick's error, if any.
+type ProbeResult struct {
+	Status
+	Error string `json:"error,omitempty"`
+}
+
+// serveProbe runs a tick immediately and responds with the result. When an
+// admin token is configured the request must carry it as a bearer token.
+func (r *Runner) serveProbe(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(w, req) {
+		return
+	}
+	log.FromContext(req.Context()).Info("on-demand probe requested", "remote", req.RemoteAddr)
+	res := ProbeResult{}
+	if err := r.tick(req.Context()); err != nil {
+		res.Error = err.Error()
+	}
+	res.Status = r.Status()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// servePause returns a handler that pauses or resumes annotation updates and
+// responds with the resulting Status.
+func (r *Runner) servePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !r.authorized(w, req) {
+			return
+		}
+		if r.paused.Swap(paused) != paused {
+			log.FromContext(req.Context()).Info("annotation updates toggled", "paused", paused, "remote", req.RemoteAddr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Status())
+	}
+}
+
+// authorized checks the admin bearer token when one is configured and
+// responds with 401 when it is missing or wrong.
+func (r *Runner) authorized(w http.ResponseWriter, req *http.Request) bool {
+	if r.adminToken == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(r.adminToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Readiness reasons reported by ReadyzCheck.
+const (
+	notReadyNoTick    = "no tick completed yet"
+	notReadyNoHealthy = "no healthy IP"
+	notReadyListError = "failed to list target objects"
+	notReadyDeadline  = "tick deadline exceeded"
+)
+
+// ReadyzCheck is a healthz.Checker reporting ready once a tick completed with
+// at least one healthy IP. Its error names the reason the Runner is not ready.
+// Without readiness gating it always succeeds.
+func (r *Runner) ReadyzCheck(_ *http.Request) error {
+	if !r.readinessGate {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastTick.IsZero() {
+		return errors.New(notReadyNoTick)
+	}
+	if r.notReady != "" {
+		return errors.New(r.notReady)
+	}
+	return nil
+}
+
+// setNotReady records why the most recent tick left the Runner not ready; an
+// empty reason marks it ready.
+func (r *Runner) setNotReady(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notReady = reason
+}
+
+// HealthzCheck is a healthz.Checker that fails once patches failed in
+// failHealthzOnPatchErrors consecutive ticks. With the threshold unset it
+// always succeeds.
+func (r *Runner) HealthzCheck(_ *http.Request) error {
+	if r.failHealthzOnPatchErrors <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.patchErrorTicks >= r.failHealthzOnPatchErrors {
+		return fmt.Errorf("patches failed in %d consecutive ticks", r.patchErrorTicks)
+	}
+	return nil
+}
+
+// recordPatchErrors remembers the patch failures of a tick that reached the
+// patch phase and counts consecutive ticks with failures.
+func (r *Runner) recordPatchErrors(errs map[string]error) {
+	patchFailures.Add(float64(len(errs)))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patchErrors = make(map[string]string, len(errs))
+	for key, err := range errs {
+		r.patchErrors[key] = err.Error()
+	}
+	if len(errs) == 0 {
+		r.patchErrorTicks = 0
+		return
+	}
+	r.patchErrorTicks++
+}
+
+// recordHealthy remembers the healthy set and, when it differs from the
+// previous tick, notifies the webhook in the background.
+func (r *Runner) recordHealthy(ctx context.Context, healthy []string, failures map[string]error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastTick = time.Now()
+	r.lastErrors = make(map[string]string, len(failures))
+	for ip, err := range failures {
+		r.lastErrors[ip] = classifyProbeError(err)
+	}
+	r.recordWindow(healthy)
+	r.setHealthy(ctx, healthy)
+}
+
+// setHealthy remembers the healthy set and, when it differs from the previous
+// one, notifies the webhook in the background. Callers must hold r.mu.
+func (r *Runner) setHealthy(ctx context.Context, healthy []string) {
+	if r.observed && slices.Equal(r.lastHealthy, healthy) {
+		return
+	}
+	old := r.lastHealthy
+	r.lastHealthy = append([]string{}, healthy...)
+	r.observed = true
+
+	if r.webhook == nil {
+		return
+	}
+	payload := WebhookPayload{Old: old, New: r.lastHealthy, Timestamp: time.Now().UTC()}
+	// detach from the tick deadline so delivery never holds up annotation updates
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := r.webhook.send(ctx, payload); err != nil {
+			log.FromContext(ctx).Error(err, "failed to deliver webhook", "url", r.webhook.url)
+		}
+	}()
+}
@@ -0,0 +1,320 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunner_StatusHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:          []string{"10.0.0.1"},
+		httpClient:   newRoutedHTTPClient(server),
+		urlScheme:    "http",
+		httpPath:     "/",
+		timeout:      time.Second,
+		healthWindow: 3,
+	}
+	_ = runner.tick(context.Background())
+
+	rec := httptest.NewRecorder()
+	runner.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var st Status
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if len(st.Healthy) != 1 || st.Healthy[0] != "10.0.0.1" {
+		t.Errorf("Expected healthy [10.0.0.1], got %v", st.Healthy)
+	}
+	if st.LastTick.IsZero() {
+		t.Errorf("Expected lastTick to be set")
+	}
+	if st.SuccessRatio["10.0.0.1"] != 1 {
+		t.Errorf("Expected success ratio 1, got %v", st.SuccessRatio)
+	}
+}
+
+func TestRunner_Readyz_Reasons(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	listFails := true
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if listFails {
+				return errors.New("apiserver unavailable")
+			}
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+	runner := &Runner{
+		k8s:           k8s,
+		ips:           []string{"10.0.0.1"},
+		httpClient:    newRoutedHTTPClient(server),
+		urlScheme:     "http",
+		httpPath:      "/",
+		timeout:       time.Second,
+		readinessGate: true,
+	}
+	handler := runner.StatusHandler()
+
+	readyz := func() (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code, rec.Body.String()
+	}
+	steps := []struct {
+		name         string
+		prepare      func()
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "before first tick", prepare: func() {}, expectedCode: http.StatusServiceUnavailable, expectedBody: "not ready: no tick completed yet\n"},
+		{name: "no healthy IP", prepare: func() { _ = runner.tick(context.Background()) }, expectedCode: http.StatusServiceUnavailable, expectedBody: "not ready: no healthy IP\n"},
+		{name: "list failure", prepare: func() {
+			healthy.Store(true)
+			_ = runner.tick(context.Background())
+		}, expectedCode: http.StatusServiceUnavailable, expectedBody: "not ready: failed to list target objects (ingresses)\n"},
+		{name: "list failure for another resource", prepare: func() {
+			runner.targetResource = TargetResourceService
+			_ = runner.tick(context.Background())
+		}, expectedCode: http.StatusServiceUnavailable, expectedBody: "not ready: failed to list target objects (services)\n"},
+		{name: "ready", prepare: func() {
+			listFails = false
+			_ = runner.tick(context.Background())
+		}, expectedCode: http.StatusOK, expectedBody: "ok\n"},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.prepare()
+			code, body := readyz()
+			if code != step.expectedCode || body != step.expectedBody {
+				t.Errorf("Expected %d %q, got %d %q", step.expectedCode, step.expectedBody, code, body)
+			}
+		})
+	}
+}
+
+func TestRunner_Readyz_ReasonOnStatusServer(t *testing.T) {
+	runner := &Runner{readinessGate: true}
+	rec := httptest.NewRecorder()
+	runner.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), notReadyNoTick) {
+		t.Errorf("Expected the status server's /readyz to carry the reason, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRunner_ReadyzCheck_WithoutGate(t *testing.T) {
+	runner := &Runner{}
+	if err := runner.ReadyzCheck(nil); err != nil {
+		t.Errorf("Expected ready without readiness gating, got %v", err)
+	}
+}
+
+func TestRunner_ProbeEndpoint(t *testing.T) {
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		token        string
+		auth         string
+		expectedCode int
+	}{
+		{name: "open", expectedCode: http.StatusOK},
+		{name: "valid token", token: "s3cret", auth: "Bearer s3cret", expectedCode: http.StatusOK},
+		{name: "wrong token", token: "s3cret", auth: "Bearer guess", expectedCode: http.StatusUnauthorized},
+		{name: "missing token", token: "s3cret", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{
+				ips:        []string{"10.0.0.1", "10.0.0.2"},
+				httpClient: newRoutedHTTPClient(server),
+				urlScheme:  "http",
+				httpPath:   "/",
+				timeout:    time.Second,
+				adminToken: tt.token,
+			}
+			before := probes.Load()
+
+			req := httptest.NewRequest(http.MethodPost, "/probe", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			runner.StatusHandler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				if probes.Load() != before {
+					t.Error("Expected no probes for an unauthorized request")
+				}
+				return
+			}
+			if got := probes.Load() - before; got != 2 {
+				t.Errorf("Expected 2 probes, got %d", got)
+			}
+			var res ProbeResult
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(res.Healthy) != 2 || res.Error != "" || res.LastTick.IsZero() {
+				t.Errorf("Unexpected result %+v", res)
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_Serialized(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.0.0.1"},
+		httpClient: newRoutedHTTPClient(server),
+		urlScheme:  "http",
+		httpPath:   "/",
+		timeout:    time.Second,
+	}
+	done := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			_ = runner.tick(context.Background())
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	if maxInFlight.Load() != 1 {
+		t.Errorf("Expected ticks to run one at a time, saw %d concurrent probes", maxInFlight.Load())
+	}
+}
+
+func TestRunner_PauseResume(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var patches atomic.Int32
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches.Add(1)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	runner, err := New(Options{
+		Client:        k8s,
+		AnnotationKey: "new.example.com/target",
+		IPs:           []string{"10.0.0.1"},
+		HTTPClient:    newRoutedHTTPClient(server),
+		Timeout:       time.Second,
+		AdminToken:    "s3cret",
+		StartPaused:   true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	handler := runner.StatusHandler()
+	post := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if patches.Load() != 0 {
+		t.Fatalf("Expected no patches while started paused, got %d", patches.Load())
+	}
+	if st := runner.Status(); !st.Paused || len(st.Healthy) != 1 {
+		t.Errorf("Expected probing to continue while paused, got %+v", st)
+	}
+
+	if code := post("/resume", "guess"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", code)
+	}
+	if code := post("/resume", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200 from /resume, got %d", code)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if patches.Load() != 1 {
+		t.Errorf("Expected updates to resume, got %d patches", patches.Load())
+	}
+
+	if code := post("/pause", "s3cret"); code != http.StatusOK {
+		t.Fatalf("Expected 200 from /pause, got %d", code)
+	}
+	ing := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, ing); err != nil {
+		t.Fatal(err)
+	}
+	ing.Annotations["new.example.com/target"] = "1.1.1.1"
+	if err := k8s.Update(context.Background(), ing); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if patches.Load() != 1 {
+		t.Errorf("Expected no patches after /pause, got %d", patches.Load())
+	}
+}
@@ -0,0 +1,222 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RegionLabel is the target label used to group IPs into per-region annotations.
+const RegionLabel = "region"
+
+// targetSet is the parsed form of the configured target entries.
+type targetSet struct {
+	// ips are the addresses written to annotations, in configured order.
+	ips []string
+	// labels holds per-address labels; addresses without labels are absent.
+	labels map[string]map[string]string
+	// probeAddrs maps an address to the distinct host[:port] it is probed at.
+	probeAddrs map[string]string
+}
+
+// parseTarget splits a target entry of the form
+// "ADDR[@PROBE_HOST[:PORT]][;key=value...]" into the annotated address, the
+// optional probe address and its labels.
+func parseTarget(entry string) (string, string, map[string]string, error) {
+	parts := strings.Split(entry, ";")
+	addr, probeAddr, _ := strings.Cut(strings.TrimSpace(parts[0]), "@")
+	if addr == "" {
+		return "", "", nil, fmt.Errorf("target %q has no address", entry)
+	}
+	if strings.Contains(parts[0], "@") && probeAddr == "" {
+		return "", "", nil, fmt.Errorf("target %q has an empty probe address", entry)
+	}
+	if len(parts) == 1 {
+		return addr, probeAddr, nil, nil
+	}
+	labels := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		k, v, ok := strings.Cut(p, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return "", "", nil, fmt.Errorf("target %q has malformed label %q (want key=value)", entry, p)
+		}
+		labels[k] = v
+	}
+	return addr, probeAddr, labels, nil
+}
+
+// parseTargets parses target entries into a targetSet. Repeated addresses are
+// kept; see unique.
+func parseTargets(entries []string) (targetSet, error) {
+	ts := targetSet{ips: make([]string, 0, len(entries))}
+	seen := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		addr, probeAddr, l, err := parseTarget(e)
+		if err != nil {
+			return targetSet{}, err
+		}
+		ts.ips = append(ts.ips, addr)
+		if _, dup := seen[addr]; dup {
+			// a repeated address keeps the first entry's labels and probe address
+			continue
+		}
+		seen[addr] = struct{}{}
+		if len(l) > 0 {
+			if ts.labels == nil {
+				ts.labels = map[string]map[string]string{}
+			}
+			ts.labels[addr] = l
+		}
+		if probeAddr != "" {
+			if ts.probeAddrs == nil {
+				ts.probeAddrs = map[string]string{}
+			}
+			ts.probeAddrs[addr] = probeAddr
+		}
+	}
+	return ts, nil
+}
+
+// canonicalIP returns the canonical netip form of addr, unmapping IPv4-mapped
+// IPv6 addresses, so "::ffff:1.2.3.4" and "1.2.3.4" compare equal. Values that
+// are not IP addresses are returned unchanged.
+func canonicalIP(addr string) string {
+	a, err := netip.ParseAddr(addr)
+	if err != nil {
+		return addr
+	}
+	return a.Unmap().String()
+}
+
+// canonical returns ts with every address in canonical form. Addresses that
+// collapse onto an earlier one keep the first entry's labels and probe
+// address; unique drops them.
+func (ts targetSet) canonical() targetSet {
+	out := targetSet{ips: make([]string, 0, len(ts.ips))}
+	seen := make(map[string]struct{}, len(ts.ips))
+	for _, ip := range ts.ips {
+		c := canonicalIP(ip)
+		out.ips = append(out.ips, c)
+		if _, dup := seen[c]; dup {
+			continue
+		}
+		seen[c] = struct{}{}
+		if l, ok := ts.labels[ip]; ok {
+			if out.labels == nil {
+				out.labels = map[string]map[string]string{}
+			}
+			out.labels[c] = l
+		}
+		if p, ok := ts.probeAddrs[ip]; ok {
+			if out.probeAddrs == nil {
+				out.probeAddrs = map[string]string{}
+			}
+			out.probeAddrs[c] = p
+		}
+	}
+	return out
+}
+
+// unique returns ts with repeated addresses dropped, preserving the order of
+// first occurrence, and the dropped duplicates.
+func (ts targetSet) unique() (targetSet, []string) {
+	out := targetSet{ips: make([]string, 0, len(ts.ips)), labels: ts.labels, probeAddrs: ts.probeAddrs}
+	var dups []string
+	seen := make(map[string]struct{}, len(ts.ips))
+	for _, ip := range ts.ips {
+		if _, dup := seen[ip]; dup {
+			dups = append(dups, ip)
+			continue
+		}
+		seen[ip] = struct{}{}
+		out.ips = append(out.ips, ip)
+	}
+	return out, dups
+}
+
+// uniqueIPs returns ips without repeated addresses, preserving order.
+func uniqueIPs(ips []string) []string {
+	ts, dups := targetSet{ips: ips}.unique()
+	if len(dups) == 0 {
+		return ips
+	}
+	return ts.ips
+}
+
+// prepareTargets resolves hostname targets and normalizes ts when enabled,
+// drops duplicate targets unless allowed, and applies the CIDR allowlist,
+// logging skipped targets. It fails when no target is left.
+func (r *Runner) prepareTargets(ctx context.Context, ts targetSet) (targetSet, error) {
+	logger := log.FromContext(ctx)
+	if r.resolveTargets {
+		var err error
+		if ts, err = r.resolveHostnames(ctx, ts); err != nil {
+			return targetSet{}, err
+		}
+	}
+	if r.normalizeIPs {
+		ts = ts.canonical()
+	}
+	if !r.allowDuplicateTargets {
+		var dups []string
+		if ts, dups = ts.unique(); len(dups) > 0 {
+			logger.Info("dropping duplicate targets", "duplicates", strings.Join(dups, ","))
+		}
+	}
+	n := len(ts.ips)
+	ts, skipped, err := filterTargets(ts, r.allowedCIDRs, r.cidrMode)
+	if err != nil {
+		return targetSet{}, err
+	}
+	if len(skipped) > 0 {
+		logger.Info("skipping targets outside the allowed CIDRs", "skipped", strings.Join(skipped, ","))
+	}
+	if n > 0 && len(ts.ips) == 0 {
+		return targetSet{}, fmt.Errorf("no targets inside the allowed CIDRs")
+	}
+	if err := checkRecordFamily(ts.ips, r.recordType); err != nil {
+		return targetSet{}, err
+	}
+	return ts, nil
+}
+
+// setTargets swaps in a new target set and returns the previous address list.
+func (r *Runner) setTargets(ts targetSet) []string {
+	r.ipsMu.Lock()
+	defer r.ipsMu.Unlock()
+	old := r.ips
+	r.ips = ts.ips
+	r.labels = ts.labels
+	r.probeAddrs = ts.probeAddrs
+	return old
+}
+
+// targetLabel returns the value of label for ip, or "" when unset.
+func (r *Runner) targetLabel(ip, label string) string {
+	r.ipsMu.RLock()
+	defer r.ipsMu.RUnlock()
+	return r.labels[ip][label]
+}
+
+// probeAddress returns the host:port to probe for ip. Targets configured with
+// a separate probe address use it (with defaultPort when it has no port);
+// otherwise ip itself is probed on defaultPort. IPv6 link-local hosts without
+// a zone get the configured link-local zone.
+func (r *Runner) probeAddress(ip, defaultPort string) string {
+	r.ipsMu.RLock()
+	probeAddr := r.probeAddrs[ip]
+	r.ipsMu.RUnlock()
+
+	if probeAddr == "" {
+		return net.JoinHostPort(withZone(ip, r.linkLocalZone), defaultPort)
+	}
+	if host, port, err := net.SplitHostPort(probeAddr); err == nil {
+		return net.JoinHostPort(withZone(host, r.linkLocalZone), port)
+	}
+	return net.JoinHostPort(withZone(strings.Trim(probeAddr, "[]"), r.linkLocalZone), defaultPort)
+}
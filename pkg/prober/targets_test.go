@@ -0,0 +1,320 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		entry             string
+		expectedAddr      string
+		expectedProbeAddr string
+		expectedLabels    map[string]string
+		expectError       bool
+	}{
+		{entry: "10.0.0.1", expectedAddr: "10.0.0.1"},
+		{entry: "10.0.0.1;region=eu", expectedAddr: "10.0.0.1", expectedLabels: map[string]string{"region": "eu"}},
+		{entry: "10.0.0.1; region = eu ;provider=aws", expectedAddr: "10.0.0.1", expectedLabels: map[string]string{"region": "eu", "provider": "aws"}},
+		{entry: "10.0.0.1;region", expectError: true},
+		{entry: "10.0.0.1;=eu", expectError: true},
+		{entry: ";region=eu", expectError: true},
+		{entry: "203.0.113.10@10.0.0.5:8080", expectedAddr: "203.0.113.10", expectedProbeAddr: "10.0.0.5:8080"},
+		{entry: "203.0.113.10@10.0.0.5;region=eu", expectedAddr: "203.0.113.10", expectedProbeAddr: "10.0.0.5", expectedLabels: map[string]string{"region": "eu"}},
+		{entry: "203.0.113.10@", expectError: true},
+		{entry: "@10.0.0.5:8080", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			addr, probeAddr, labels, err := parseTarget(tt.entry)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got none", tt.entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if addr != tt.expectedAddr {
+				t.Errorf("Expected address %q, got %q", tt.expectedAddr, addr)
+			}
+			if probeAddr != tt.expectedProbeAddr {
+				t.Errorf("Expected probe address %q, got %q", tt.expectedProbeAddr, probeAddr)
+			}
+			if len(labels) != len(tt.expectedLabels) {
+				t.Errorf("Expected labels %v, got %v", tt.expectedLabels, labels)
+			}
+			for k, v := range tt.expectedLabels {
+				if labels[k] != v {
+					t.Errorf("Expected label %s=%q, got %q", k, v, labels[k])
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_RegionAnnotations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 10.0.2.2 (us) is down
+		if strings.HasPrefix(r.Host, "10.0.2.2") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+
+	runner, err := New(Options{
+		Client:                   k8s,
+		IPs:                      []string{"10.0.1.1;region=eu", "10.0.2.1;region=us", "10.0.1.2;region=eu", "10.0.2.2;region=us", "10.0.3.1"},
+		RegionAnnotationTemplate: "external-dns.alpha.kubernetes.io/target-{{ .Region }}",
+		HTTPClient:               newRoutedHTTPClient(server),
+		Timeout:                  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	expected := map[string]string{
+		"external-dns.alpha.kubernetes.io/target":    "10.0.1.1,10.0.2.1,10.0.1.2,10.0.3.1",
+		"external-dns.alpha.kubernetes.io/target-eu": "10.0.1.1,10.0.1.2",
+		"external-dns.alpha.kubernetes.io/target-us": "10.0.2.1",
+	}
+	for k, v := range expected {
+		if got.Annotations[k] != v {
+			t.Errorf("Expected annotation %s=%q, got %q", k, v, got.Annotations[k])
+		}
+	}
+}
+
+func TestNew_InvalidRegionAnnotationTemplate(t *testing.T) {
+	if _, err := New(Options{IPs: []string{"10.0.0.1;region=eu"}, RegionAnnotationTemplate: "{{ .Region"}); err == nil {
+		t.Errorf("Expected error for unparsable region template, got none")
+	}
+}
+
+func TestRunner_ProbeAddress(t *testing.T) {
+	ts, err := parseTargets([]string{"203.0.113.10@10.0.0.5:8080", "203.0.113.11@10.0.0.6", "203.0.113.12@[fd00::1]", "203.0.113.13"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	runner := &Runner{}
+	runner.setTargets(ts)
+
+	tests := map[string]string{
+		"203.0.113.10": "10.0.0.5:8080",
+		"203.0.113.11": "10.0.0.6:443",
+		"203.0.113.12": "[fd00::1]:443",
+		"203.0.113.13": "203.0.113.13:443",
+	}
+	for ip, expected := range tests {
+		if got := runner.probeAddress(ip, "443"); got != expected {
+			t.Errorf("probeAddress(%q) = %q, expected %q", ip, got, expected)
+		}
+	}
+}
+
+func TestRunner_Tick_SeparateProbeAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var dialed []string
+	d := &net.Dialer{}
+	httpClient := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				mu.Lock()
+				dialed = append(dialed, addr)
+				mu.Unlock()
+				return d.DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		},
+	}
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:     k8s,
+		IPs:        []string{"203.0.113.10@10.0.0.5:8080"},
+		HTTPClient: httpClient,
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(dialed) != 1 || dialed[0] != "10.0.0.5:8080" {
+		t.Errorf("Expected probe to dial 10.0.0.5:8080, got %v", dialed)
+	}
+	got := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	if v := got.Annotations["external-dns.alpha.kubernetes.io/target"]; v != "203.0.113.10" {
+		t.Errorf("Expected annotation to carry the public address, got %q", v)
+	}
+}
+
+func TestCanonicalIP(t *testing.T) {
+	tests := map[string]string{
+		"1.2.3.4":            "1.2.3.4",
+		"::ffff:1.2.3.4":     "1.2.3.4",
+		"::FFFF:102:304":     "1.2.3.4",
+		"2001:DB8:0:0::1":    "2001:db8::1",
+		"not-an-ip.example":  "not-an-ip.example",
+		"2001:db8::1%eth0":   "2001:db8::1%eth0",
+		"0000:0000::ffff:a0": "::ffff:a0",
+	}
+	for in, want := range tests {
+		if got := canonicalIP(in); got != want {
+			t.Errorf("canonicalIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunner_Tick_NormalizesMappedIPs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		disable  bool
+		expected string
+	}{
+		{name: "normalized", expected: "1.2.3.4,2001:db8::1"},
+		{name: "disabled", disable: true, expected: "::ffff:1.2.3.4,1.2.3.4,2001:DB8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).Build()
+			runner, err := New(Options{
+				Client:                 k8s,
+				AnnotationKey:          "new.example.com/target",
+				IPs:                    []string{"::ffff:1.2.3.4", "1.2.3.4", "2001:DB8::1"},
+				DisableIPNormalization: tt.disable,
+				HTTPClient:             newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if got.Annotations["new.example.com/target"] != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got.Annotations["new.example.com/target"])
+			}
+		})
+	}
+}
+
+func TestRunner_Tick_DuplicateTargets(t *testing.T) {
+	var mu sync.Mutex
+	probes := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		mu.Lock()
+		probes[host]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		allow    bool
+		expected string
+		probes   int
+	}{
+		{name: "deduplicated", expected: "10.0.0.2,10.0.0.1", probes: 1},
+		{name: "allowed", allow: true, expected: "10.0.0.2,10.0.0.1,10.0.0.2", probes: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			clear(probes)
+			mu.Unlock()
+			k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+			).Build()
+			runner, err := New(Options{
+				Client:                k8s,
+				AnnotationKey:         "new.example.com/target",
+				IPs:                   []string{"10.0.0.2;region=a", "10.0.0.1", "10.0.0.2;region=b"},
+				AllowDuplicateTargets: tt.allow,
+				HTTPClient:            newRoutedHTTPClient(server),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := runner.targetLabel("10.0.0.2", RegionLabel); got != "a" {
+				t.Errorf("Expected the first entry's labels, got region %q", got)
+			}
+			if err := runner.tick(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := &networkingv1.Ingress{}
+			if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+				t.Fatalf("failed to get Ingress: %v", err)
+			}
+			if got.Annotations["new.example.com/target"] != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got.Annotations["new.example.com/target"])
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if probes["10.0.0.2"] != tt.probes || probes["10.0.0.1"] != 1 {
+				t.Errorf("Expected 10.0.0.2 to be probed %d times, got %v", tt.probes, probes)
+			}
+		})
+	}
+}
+
+func TestUniqueIPs(t *testing.T) {
+	if got := strings.Join(uniqueIPs([]string{"10.0.0.3", "10.0.0.1", "10.0.0.3", "10.0.0.1", "10.0.0.2"}), ","); got != "10.0.0.3,10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected first occurrences in order, got %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	ProbeModeTCP = "tcp"
+
+	// PortsModeAll requires every probe port to accept connections; PortsModeAny
+	// is satisfied by a single one.
+	PortsModeAll = "all"
+	PortsModeAny = "any"
+)
+
+// validateProbePorts checks that every entry is a TCP port number.
+func validateProbePorts(ports []string) error {
+	for _, p := range ports {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid probe port %q", p)
+		}
+	}
+	return nil
+}
+
+// probeTCP dials every probe port on ip in parallel, within the per-host
+// limit. With PortsModeAll the IP is healthy only when all ports accept a
+// connection, with PortsModeAny when at least one does. Without configured
+// ports the scheme's port is dialed.
+func (r *Runner) probeTCP(ctx context.Context, logger logr.Logger, ip string) error {
+	ports := r.probePorts
+	if len(ports) == 0 {
+		ports = []string{portForScheme(r.urlScheme)}
+	}
+
+	errs := make([]error, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i int, port string) {
+			defer wg.Done()
+			addr := r.probeAddress(ip, port)
+			release, err := r.hostLimit.acquire(ctx, addr)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer release()
+			dial := r.dialContext
+			if dial == nil {
+				dial = (&net.Dialer{}).DialContext
+			}
+			dctx := ctx
+			if r.timeout > 0 {
+				var cancel context.CancelFunc
+				dctx, cancel = context.WithTimeout(ctx, r.timeout)
+				defer cancel()
+			}
+			conn, err := dial(dctx, "tcp", addr)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			_ = conn.Close()
+		}(i, port)
+	}
+	wg.Wait()
+
+	var open, closed []string
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			open = append(open, ports[i])
+			continue
+		}
+		closed = append(closed, ports[i])
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	healthy := len(closed) == 0
+	if r.portsMode == PortsModeAny {
+		healthy = len(open) > 0
+	}
+	if healthy {
+		logger.Info("IP marked as healthy", "ip", ip, "open_ports", open, "closed_ports", closed)
+		return nil
+	}
+	typ := classifyError(firstErr)
+	logger.Info("IP marked as unhealthy due to closed ports", "ip", ip, "open_ports", open, "closed_ports", closed, "ports_mode", r.portsMode, "error_type", typ)
+	return newProbeError(typ, firstErr)
+}
@@ -0,0 +1,96 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenPorts opens n loopback listeners and returns their ports.
+func listenPorts(t *testing.T, n int) []string {
+	t.Helper()
+	var ports []string
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		t.Cleanup(func() { _ = l.Close() })
+		ports = append(ports, strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
+	}
+	return ports
+}
+
+// closedPort returns a loopback port nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	_ = l.Close()
+	return port
+}
+
+func TestRunner_HealthyIPs_TCPMode(t *testing.T) {
+	open := listenPorts(t, 2)
+	closed := closedPort(t)
+
+	tests := []struct {
+		name          string
+		ports         []string
+		mode          string
+		expectHealthy bool
+	}{
+		{name: "all open, mode all", ports: open, mode: PortsModeAll, expectHealthy: true},
+		{name: "one closed, mode all", ports: append([]string{closed}, open...), mode: PortsModeAll, expectHealthy: false},
+		{name: "one closed, mode any", ports: append([]string{closed}, open...), mode: PortsModeAny, expectHealthy: true},
+		{name: "all closed, mode any", ports: []string{closed}, mode: PortsModeAny, expectHealthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{
+				ips:        []string{"127.0.0.1"},
+				probeMode:  ProbeModeTCP,
+				probePorts: tt.ports,
+				portsMode:  tt.mode,
+				timeout:    time.Second,
+			}
+			healthy, failures := runner.probeAll(context.Background())
+			if (len(healthy) == 1) != tt.expectHealthy {
+				t.Errorf("Expected healthy=%v, got %v (%v)", tt.expectHealthy, healthy, failures)
+			}
+			if !tt.expectHealthy {
+				if got := classifyProbeError(failures["127.0.0.1"]); got != ErrorTypeConnect {
+					t.Errorf("Expected error type %q, got %q", ErrorTypeConnect, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateTCPMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "defaults", opts: Options{ProbeMode: ProbeModeTCP}},
+		{name: "ports any", opts: Options{ProbeMode: ProbeModeTCP, ProbePorts: []string{"80", "443"}, PortsMode: PortsModeAny}},
+		{name: "bad port", opts: Options{ProbeMode: ProbeModeTCP, ProbePorts: []string{"http"}}, expectError: true},
+		{name: "port out of range", opts: Options{ProbeMode: ProbeModeTCP, ProbePorts: []string{"70000"}}, expectError: true},
+		{name: "bad mode", opts: Options{ProbeMode: ProbeModeTCP, PortsMode: "most"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			if err := tt.opts.validate(); tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
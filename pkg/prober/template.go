@@ -0,0 +1,129 @@
+package prober
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultAnnotationValueTemplate renders the healthy IPs joined by the target
+// separator.
+const DefaultAnnotationValueTemplate = `{{ join .IPs .Separator }}`
+
+// DefaultTargetSeparator joins the healthy IPs in the annotation value.
+const DefaultTargetSeparator = ","
+
+// validateTargetSeparator rejects separators that could be mistaken for part
+// of an address, which would make the current value impossible to split back.
+func validateTargetSeparator(sep string) error {
+	if strings.ContainsAny(sep, "0123456789abcdefABCDEF.:%[]") {
+		return fmt.Errorf("target separator %q must not contain characters used in IP addresses", sep)
+	}
+	return nil
+}
+
+// TemplateData is the data passed to the annotation value template.
+type TemplateData struct {
+	// IPs are the healthy IPs in probe order.
+	IPs []string
+	// SortedIPs are the healthy IPs sorted lexically.
+	SortedIPs []string
+	// Namespace and Name identify the Ingress or Service being annotated.
+	Namespace string
+	Name      string
+	// IngressClass is the class the object was matched by.
+	IngressClass string
+	// Separator is the configured target separator.
+	Separator string
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parseValueTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("annotation-value").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation value template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RegionTemplateData is passed to the region annotation key template.
+type RegionTemplateData struct {
+	Region string
+}
+
+func parseRegionKeyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("region-annotation").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid region annotation template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// addRegionAnnotations groups the healthy IPs by their region label and adds
+// one annotation per region to desired. Regions without healthy IPs are left
+// untouched, matching how the main annotation is handled.
+func (r *Runner) addRegionAnnotations(desired map[string]string, healthyIPs []string, obj client.Object) error {
+	if r.regionKeyTemplate == nil {
+		return nil
+	}
+	byRegion := map[string][]string{}
+	for _, ip := range healthyIPs {
+		if region := r.targetLabel(ip, RegionLabel); region != "" {
+			byRegion[region] = append(byRegion[region], ip)
+		}
+	}
+	for region, ips := range byRegion {
+		var key strings.Builder
+		if err := r.regionKeyTemplate.Execute(&key, RegionTemplateData{Region: region}); err != nil {
+			return err
+		}
+		value, err := r.renderValue(ips, obj)
+		if err != nil {
+			return err
+		}
+		desired[key.String()] = value
+	}
+	return nil
+}
+
+// renderValue produces the annotation value for obj from the healthy IPs.
+func (r *Runner) renderValue(healthyIPs []string, obj client.Object) (string, error) {
+	if r.valueTemplate == nil {
+		return strings.Join(healthyIPs, r.separator()), nil
+	}
+	sorted := append([]string(nil), healthyIPs...)
+	sort.Strings(sorted)
+
+	data := TemplateData{
+		IPs:          healthyIPs,
+		SortedIPs:    sorted,
+		Namespace:    obj.GetNamespace(),
+		Name:         obj.GetName(),
+		IngressClass: r.className(obj),
+		Separator:    r.separator(),
+	}
+	var b strings.Builder
+	if err := r.valueTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// separator returns the configured target separator, defaulting to a comma.
+func (r *Runner) separator() string {
+	if r.targetSeparator == "" {
+		return DefaultTargetSeparator
+	}
+	return r.targetSeparator
+}
@@ -0,0 +1,80 @@
+package prober
+
+import (
+	"testing"
+)
+
+func TestRunner_RenderValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		ips      []string
+		expected string
+	}{
+		{
+			name:     "default template keeps probe order",
+			template: DefaultAnnotationValueTemplate,
+			ips:      []string{"10.0.0.2", "10.0.0.1"},
+			expected: "10.0.0.2,10.0.0.1",
+		},
+		{
+			name:     "single IP",
+			template: DefaultAnnotationValueTemplate,
+			ips:      []string{"10.0.0.1"},
+			expected: "10.0.0.1",
+		},
+		{
+			name:     "JSON with sorted IPs and metadata",
+			template: `{"ingress":{{ json .Name }},"targets":{{ json .SortedIPs }}}`,
+			ips:      []string{"10.0.0.2", "10.0.0.1"},
+			expected: `{"ingress":"web","targets":["10.0.0.1","10.0.0.2"]}`,
+		},
+		{
+			name:     "key=ip pairs",
+			template: `{{ range $i, $ip := .SortedIPs }}{{ if $i }};{{ end }}{{ $.Namespace }}={{ $ip }}{{ end }}`,
+			ips:      []string{"10.0.0.2", "10.0.0.1"},
+			expected: "default=10.0.0.1;default=10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseValueTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			runner := &Runner{valueTemplate: tmpl, ingressClassAnnotationKey: "kubernetes.io/ingress.class"}
+
+			got, err := runner.renderValue(tt.ips, newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunner_RenderValue_DoesNotReorderInput(t *testing.T) {
+	tmpl, err := parseValueTemplate(`{{ join .SortedIPs "," }}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	runner := &Runner{valueTemplate: tmpl}
+
+	ips := []string{"10.0.0.2", "10.0.0.1"}
+	if _, err := runner.renderValue(ips, newIngress("web", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ips[0] != "10.0.0.2" {
+		t.Errorf("Expected input slice to be left untouched, got %v", ips)
+	}
+}
+
+func TestNew_InvalidAnnotationValueTemplate(t *testing.T) {
+	_, err := New(Options{IPs: []string{"10.0.0.1"}, AnnotationValueTemplate: "{{ .IPs "})
+	if err == nil {
+		t.Errorf("Expected error for unparsable template, got none")
+	}
+}
@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsSettings pins the TLS versions and cipher suites offered by HTTPS and
+// gRPC probes. The zero value leaves crypto/tls defaults in place.
+type tlsSettings struct {
+	minVersion   uint16
+	maxVersion   uint16
+	cipherSuites []uint16
+}
+
+// parseTLSVersion accepts "1.0" through "1.3", optionally prefixed with
+// "TLS" as in "TLS1.2". Empty means the crypto/tls default.
+func parseTLSVersion(s string) (uint16, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(s), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q (want 1.0, 1.1, 1.2 or 1.3)", s)
+	}
+	return v, nil
+}
+
+// parseCipherSuites maps IANA cipher suite names, as listed by
+// tls.CipherSuites and tls.InsecureCipherSuites, to their IDs.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := map[string]uint16{}
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[cs.Name] = cs.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (o *Options) tlsSettings() (tlsSettings, error) {
+	var s tlsSettings
+	var err error
+	if s.minVersion, err = parseTLSVersion(o.TLSMinVersion); err != nil {
+		return tlsSettings{}, err
+	}
+	if s.maxVersion, err = parseTLSVersion(o.TLSMaxVersion); err != nil {
+		return tlsSettings{}, err
+	}
+	if s.minVersion != 0 && s.maxVersion != 0 && s.minVersion > s.maxVersion {
+		return tlsSettings{}, fmt.Errorf("TLS min version %s is above max version %s", o.TLSMinVersion, o.TLSMaxVersion)
+	}
+	if s.cipherSuites, err = parseCipherSuites(o.TLSCipherSuites); err != nil {
+		return tlsSettings{}, err
+	}
+	if len(s.cipherSuites) > 0 && s.minVersion == tls.VersionTLS13 {
+		// crypto/tls does not make TLS 1.3 suites configurable
+		return tlsSettings{}, fmt.Errorf("TLS cipher suites only apply up to TLS 1.2, but the min version is 1.3")
+	}
+	return s, nil
+}
+
+// apply sets the pinned versions and cipher suites on cfg.
+func (s tlsSettings) apply(cfg *tls.Config) *tls.Config {
+	cfg.MinVersion = s.minVersion
+	cfg.MaxVersion = s.maxVersion
+	cfg.CipherSuites = s.cipherSuites
+	return cfg
+}
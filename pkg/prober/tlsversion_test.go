@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner_Probe_TLSVersions(t *testing.T) {
+	var negotiated atomic.Uint32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		negotiated.Store(uint32(r.TLS.Version))
+		w.WriteHeader(http.StatusOK)
+	}))
+	// a legacy backend that stops at TLS 1.2 and offers a single suite
+	server.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError string
+	}{
+		{name: "defaults"},
+		{name: "pinned to 1.2", opts: Options{TLSMinVersion: "1.2", TLSMaxVersion: "1.2"}},
+		{name: "requires 1.3", opts: Options{TLSMinVersion: "1.3"}, expectError: ErrorTypeTLS},
+		{name: "allowed cipher suite", opts: Options{TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}},
+		{name: "disjoint cipher suites", opts: Options{TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}}, expectError: ErrorTypeTLS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			negotiated.Store(0)
+			opts := tt.opts
+			opts.IPs = []string{"10.0.0.1"}
+			opts.Timeout = time.Second
+			opts.InsecureSkipVerify = true
+			if err := opts.validate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			client := opts.httpClient()
+			d := &net.Dialer{}
+			client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, network, server.Listener.Addr().String())
+			}
+			runner := &Runner{ips: []string{"10.0.0.1"}, httpClient: client, urlScheme: "https", httpPath: "/"}
+
+			healthy, failures := runner.probeAll(context.Background())
+			if tt.expectError == "" {
+				if len(healthy) != 1 {
+					t.Fatalf("Expected the IP to be healthy, got %v", failures)
+				}
+				if v := negotiated.Load(); v != tls.VersionTLS12 {
+					t.Errorf("Expected TLS 1.2 to be negotiated, got %s", tls.VersionName(uint16(v)))
+				}
+				return
+			}
+			if len(healthy) != 0 {
+				t.Fatalf("Expected the handshake to fail, got healthy %v", healthy)
+			}
+			if got := classifyProbeError(failures["10.0.0.1"]); got != tt.expectError {
+				t.Errorf("Expected error type %q, got %q (%v)", tt.expectError, got, failures["10.0.0.1"])
+			}
+		})
+	}
+}
+
+func TestOptions_ValidateTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "versions", opts: Options{TLSMinVersion: "1.2", TLSMaxVersion: "TLS1.3"}},
+		{name: "cipher suites", opts: Options{TLSMaxVersion: "1.2", TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA"}}},
+		{name: "unknown version", opts: Options{TLSMinVersion: "1.4"}, wantErr: true},
+		{name: "min above max", opts: Options{TLSMinVersion: "1.3", TLSMaxVersion: "1.2"}, wantErr: true},
+		{name: "unknown cipher suite", opts: Options{TLSCipherSuites: []string{"TLS_NOPE"}}, wantErr: true},
+		{name: "cipher suites with TLS 1.3 only", opts: Options{TLSMinVersion: "1.3", TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
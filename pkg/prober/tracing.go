@@ -0,0 +1,60 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the Runner's spans.
+const tracerName = "github.com/b1r3k/ingress-target-prober/pkg/prober"
+
+// NewOTLPTracerProvider returns a TracerProvider exporting spans in batches
+// over OTLP/HTTP to endpoint, an http:// or https:// collector URL such as
+// "http://otel-collector:4318". Callers must Shutdown it to flush spans.
+func NewOTLPTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q (want http(s)://host:port)", endpoint)
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("ingress-target-prober"))),
+	), nil
+}
+
+// startSpan starts a span named name under ctx. Without a configured
+// TracerProvider it is a no-op.
+func (r *Runner) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := r.tracer
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endProbeSpan records the outcome of one IP's probe on span and ends it.
+func endProbeSpan(span trace.Span, err error, latency time.Duration) {
+	span.SetAttributes(attribute.Int64("latency_ms", latency.Milliseconds()))
+	if err != nil {
+		span.SetAttributes(attribute.String("status", "unhealthy"), attribute.String("error_type", classifyProbeError(err)))
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.String("status", "healthy"))
+	}
+	span.End()
+}
@@ -0,0 +1,106 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_Traces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "10.0.0.2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner, err := New(Options{
+		Client:         k8s,
+		AnnotationKey:  "new.example.com/target",
+		IPs:            []string{"10.0.0.1", "10.0.0.2"},
+		HTTPClient:     newRoutedHTTPClient(server),
+		TracerProvider: tp,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected a tick span and two probe spans, got %d spans", len(spans))
+	}
+	var tick sdktrace.ReadOnlySpan
+	probes := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		switch s.Name() {
+		case "tick":
+			tick = s
+		case "probe":
+			probes[attrValue(s, "ip").AsString()] = s
+		}
+	}
+	if tick == nil {
+		t.Fatal("Expected a tick span")
+	}
+	if got := attrValue(tick, "healthy_count").AsInt64(); got != 1 {
+		t.Errorf("Expected healthy_count 1 on the tick span, got %d", got)
+	}
+	for ip, want := range map[string]string{"10.0.0.1": "healthy", "10.0.0.2": "unhealthy"} {
+		s, ok := probes[ip]
+		if !ok {
+			t.Fatalf("Expected a probe span for %s", ip)
+		}
+		if s.Parent().SpanID() != tick.SpanContext().SpanID() || s.SpanContext().TraceID() != tick.SpanContext().TraceID() {
+			t.Errorf("Expected the probe span for %s to be a child of the tick span", ip)
+		}
+		if got := attrValue(s, "status").AsString(); got != want {
+			t.Errorf("Expected status %q for %s, got %q", want, ip, got)
+		}
+		if _, ok := attrLookup(s, "latency_ms"); !ok {
+			t.Errorf("Expected a latency_ms attribute for %s", ip)
+		}
+	}
+	if s := probes["10.0.0.2"]; s.Status().Code != codes.Error || attrValue(s, "error_type").AsString() != ErrorTypeHTTPStatus {
+		t.Errorf("Expected the failed probe span to carry the error, got %v %v", s.Status(), s.Attributes())
+	}
+}
+
+func attrLookup(s sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func attrValue(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	v, _ := attrLookup(s, key)
+	return v
+}
+
+func TestNewOTLPTracerProvider_InvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"otel-collector:4318", "grpc://otel-collector:4317", "http://"} {
+		if _, err := NewOTLPTracerProvider(context.Background(), endpoint); err == nil {
+			t.Errorf("NewOTLPTracerProvider(%q) expected an error", endpoint)
+		}
+	}
+}
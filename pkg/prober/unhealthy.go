@@ -0,0 +1,136 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Unhealthy modes: what a tick does when no target is healthy.
+const (
+	// UnhealthyModeKeep leaves the annotations unchanged (the default).
+	UnhealthyModeKeep = "keep"
+	// UnhealthyModeRemove deletes the target annotations.
+	UnhealthyModeRemove = "remove"
+	// UnhealthyModeFallback writes the fallback targets instead.
+	UnhealthyModeFallback = "fallback"
+)
+
+// validateUnhealthyMode checks the unhealthy mode, its fallback targets and
+// the grace period before it takes effect.
+func (o *Options) validateUnhealthyMode() error {
+	switch o.UnhealthyMode {
+	case "", UnhealthyModeKeep:
+		if o.ClearGracePeriod != 0 {
+			return fmt.Errorf("a clear grace period requires the %s or %s unhealthy mode", UnhealthyModeRemove, UnhealthyModeFallback)
+		}
+	case UnhealthyModeRemove:
+	case UnhealthyModeFallback:
+		if len(o.FallbackTargets) == 0 {
+			return fmt.Errorf("the %s unhealthy mode requires fallback targets", UnhealthyModeFallback)
+		}
+	default:
+		return fmt.Errorf("unsupported unhealthy mode %q (want %s, %s or %s)", o.UnhealthyMode, UnhealthyModeKeep, UnhealthyModeRemove, UnhealthyModeFallback)
+	}
+	if o.UnhealthyMode != UnhealthyModeFallback && len(o.FallbackTargets) > 0 {
+		return fmt.Errorf("fallback targets require the %s unhealthy mode", UnhealthyModeFallback)
+	}
+	for _, ip := range o.FallbackTargets {
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return fmt.Errorf("invalid fallback target %q", ip)
+		}
+	}
+	if o.ClearGracePeriod < 0 {
+		return fmt.Errorf("clear grace period must not be negative")
+	}
+	return nil
+}
+
+// handleNoHealthy applies the unhealthy mode once the healthy set has been
+// empty for the clear grace period; until then, and in the keep mode, the
+// annotations are left unchanged. It always returns errNoHealthyIP so the
+// tick still counts as failed.
+func (r *Runner) handleNoHealthy(ctx context.Context) error {
+	if r.unhealthyMode == "" || r.unhealthyMode == UnhealthyModeKeep {
+		return errNoHealthyIP
+	}
+	logger := log.FromContext(ctx)
+	if down := time.Since(r.downSince); down < r.clearGracePeriod {
+		logger.Info("no healthy IP within clear grace period; leaving annotations unchanged", "mode", r.unhealthyMode, "down_for", down.Round(time.Millisecond).String(), "grace_period", r.clearGracePeriod.String())
+		return errNoHealthyIP
+	}
+	switch r.unhealthyMode {
+	case UnhealthyModeFallback:
+		logger.Info("no healthy IP; writing fallback targets", "fallback", strings.Join(r.fallbackTargets, ","))
+		if err := r.writeHealthy(ctx, r.fallbackTargets, nil); err != nil {
+			return err
+		}
+	case UnhealthyModeRemove:
+		if err := r.removeTargetAnnotations(ctx); err != nil {
+			return err
+		}
+	}
+	// writeHealthy marks the Runner ready; it still has no healthy IP
+	r.setNotReady(notReadyNoHealthy)
+	return errNoHealthyIP
+}
+
+// removeTargetAnnotations deletes the target annotations (and the record
+// type hint) from every managed object that still carries them.
+func (r *Runner) removeTargetAnnotations(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	if r.k8s == nil || r.paused.Load() || r.breaker.open() || !r.updateWindow.open() {
+		logger.Info("annotation updates unavailable; not removing annotations", "paused", r.paused.Load(), "in_update_window", r.updateWindow.open())
+		return nil
+	}
+	objs, err := r.listTargets(ctx)
+	if err != nil {
+		logger.Error(err, "failed to list target objects", "resource", r.targetResource)
+		return err
+	}
+	keys := r.valueKeys()
+	if r.writesRecordType() {
+		keys = append(keys, RecordTypeAnnotationKey)
+	}
+	if r.writesCount() {
+		keys = append(keys, r.proberKey(countAnnotationName))
+	}
+	for _, obj := range objs {
+		annotations := obj.GetAnnotations()
+		if !r.matchesClass(obj) {
+			continue
+		}
+		if !r.eligible(annotations) {
+			continue
+		}
+		before := make(map[string]string, len(keys))
+		for _, k := range keys {
+			if v, ok := annotations[k]; ok {
+				before[k] = v
+			}
+		}
+		if len(before) == 0 {
+			continue
+		}
+		key := client.ObjectKeyFromObject(obj)
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		for k := range before {
+			delete(annotations, k)
+		}
+		err := r.k8sOp(ctx, "patch", func(ctx context.Context) error {
+			return r.k8s.Patch(ctx, obj, patch, client.FieldOwner(r.fieldManager))
+		})
+		if err != nil {
+			logger.Error(err, "failed to remove annotation", "object", key.String())
+			continue
+		}
+		r.auditChanges(ctx, key, annotationChanges(before, nil))
+		logger.Info("removed annotation; no healthy IP", "object", key.String(), "keys", keys)
+	}
+	return nil
+}
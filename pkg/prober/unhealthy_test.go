@@ -0,0 +1,161 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_ClearGracePeriod(t *testing.T) {
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if up {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{"kubernetes.io/ingress.class": "public-nginx"}),
+	).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		unhealthyMode:             UnhealthyModeRemove,
+		clearGracePeriod:          time.Minute,
+	}
+	annotation := func() (string, bool) {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		v, ok := got.Annotations["new.example.com/target"]
+		return v, ok
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a blip shorter than the grace period keeps the annotation
+	up = false
+	if err := runner.tick(context.Background()); !errors.Is(err, errNoHealthyIP) {
+		t.Fatalf("Expected errNoHealthyIP, got %v", err)
+	}
+	if v, _ := annotation(); v != "10.0.0.1,10.0.0.2" {
+		t.Fatalf("Expected the annotation to be retained during the grace period, got %q", v)
+	}
+	up = true
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a new outage starts its own grace period
+	up = false
+	_ = runner.tick(context.Background())
+	if v, _ := annotation(); v != "10.0.0.1,10.0.0.2" {
+		t.Fatalf("Expected the annotation to be retained at the start of a new outage, got %q", v)
+	}
+
+	runner.downSince = runner.downSince.Add(-time.Minute)
+	if err := runner.tick(context.Background()); !errors.Is(err, errNoHealthyIP) {
+		t.Fatalf("Expected errNoHealthyIP, got %v", err)
+	}
+	if v, ok := annotation(); ok {
+		t.Fatalf("Expected the annotation to be removed after the grace period, got %q", v)
+	}
+
+	up = true
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, _ := annotation(); v != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected the annotation to be restored on recovery, got %q", v)
+	}
+}
+
+func TestRunner_Tick_FallbackTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{
+			"kubernetes.io/ingress.class": "public-nginx",
+			"new.example.com/target":      "10.0.0.1",
+		}),
+	).Build()
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		readinessGate:             true,
+		unhealthyMode:             UnhealthyModeFallback,
+		fallbackTargets:           []string{"192.0.2.10", "192.0.2.11"},
+	}
+	if err := runner.tick(context.Background()); !errors.Is(err, errNoHealthyIP) {
+		t.Fatalf("Expected errNoHealthyIP, got %v", err)
+	}
+
+	got := &networkingv1.Ingress{}
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+		t.Fatalf("failed to get Ingress: %v", err)
+	}
+	if v := got.Annotations["new.example.com/target"]; v != "192.0.2.10,192.0.2.11" {
+		t.Errorf("Expected the fallback targets, got %q", v)
+	}
+	if err := runner.ReadyzCheck(nil); err == nil || err.Error() != notReadyNoHealthy {
+		t.Errorf("Expected not ready with %q, got %v", notReadyNoHealthy, err)
+	}
+}
+
+func TestOptions_ValidateUnhealthyMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "default", opts: Options{}},
+		{name: "remove with grace", opts: Options{UnhealthyMode: UnhealthyModeRemove, ClearGracePeriod: time.Minute}},
+		{name: "fallback", opts: Options{UnhealthyMode: UnhealthyModeFallback, FallbackTargets: []string{"192.0.2.10"}}},
+		{name: "fallback without targets", opts: Options{UnhealthyMode: UnhealthyModeFallback}, expectError: true},
+		{name: "invalid fallback target", opts: Options{UnhealthyMode: UnhealthyModeFallback, FallbackTargets: []string{"backup"}}, expectError: true},
+		{name: "targets without fallback", opts: Options{UnhealthyMode: UnhealthyModeRemove, FallbackTargets: []string{"192.0.2.10"}}, expectError: true},
+		{name: "grace with keep", opts: Options{ClearGracePeriod: time.Minute}, expectError: true},
+		{name: "negative grace", opts: Options{UnhealthyMode: UnhealthyModeRemove, ClearGracePeriod: -time.Second}, expectError: true},
+		{name: "unsupported", opts: Options{UnhealthyMode: "clear"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.IPs = []string{"10.0.0.1"}
+			if err := tt.opts.validate(); tt.expectError != (err != nil) {
+				t.Errorf("Unexpected error state: %v", err)
+			}
+		})
+	}
+}
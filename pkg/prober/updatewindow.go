@@ -0,0 +1,140 @@
+package prober
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// updateWindow limits annotation updates to recurring UTC time ranges. A nil
+// *updateWindow is always open.
+type updateWindow struct {
+	spec   string
+	ranges []windowRange
+	now    func() time.Time
+}
+
+// windowRange is one daily range [start, end) in minutes since midnight on
+// the days set in days, indexed by time.Weekday. A range with end before
+// start wraps past midnight and belongs to the day it starts on.
+type windowRange struct {
+	days       [7]bool
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// newUpdateWindow parses spec, a comma-separated list of
+// "[DAY[-DAY] ]HH:MM-HH:MM" ranges in UTC such as "Mon-Fri 09:00-17:00" or
+// "22:00-02:00". It returns nil for an empty spec.
+func newUpdateWindow(spec string) (*updateWindow, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	w := &updateWindow{spec: spec, now: time.Now}
+	for _, part := range strings.Split(spec, ",") {
+		wr, err := parseWindowRange(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid update window %q: %w", spec, err)
+		}
+		w.ranges = append(w.ranges, wr)
+	}
+	return w, nil
+}
+
+func parseWindowRange(s string) (windowRange, error) {
+	var wr windowRange
+	days, times, ok := strings.Cut(s, " ")
+	if !ok {
+		times = s
+		for d := range wr.days {
+			wr.days[d] = true
+		}
+	} else {
+		first, last, isRange := strings.Cut(strings.ToLower(days), "-")
+		if !isRange {
+			last = first
+		}
+		from, ok1 := weekdays[first]
+		to, ok2 := weekdays[last]
+		if !ok1 || !ok2 {
+			return wr, fmt.Errorf("unknown days %q (want e.g. Mon or Mon-Fri)", days)
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			wr.days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	from, to, ok := strings.Cut(strings.TrimSpace(times), "-")
+	if !ok {
+		return wr, fmt.Errorf("range %q has no end time (want HH:MM-HH:MM)", s)
+	}
+	var err error
+	if wr.start, err = parseClock(from); err != nil {
+		return wr, err
+	}
+	if wr.end, err = parseClock(to); err != nil {
+		return wr, err
+	}
+	if wr.start == wr.end {
+		return wr, fmt.Errorf("range %q is empty", s)
+	}
+	return wr, nil
+}
+
+// parseClock parses HH:MM, allowing 24:00, into minutes since midnight.
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t falls inside the range.
+func (wr windowRange) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if wr.start < wr.end {
+		return wr.days[day] && m >= wr.start && m < wr.end
+	}
+	// past midnight the range belongs to the previous day
+	return (wr.days[day] && m >= wr.start) || (wr.days[(day+6)%7] && m < wr.end)
+}
+
+// open reports whether annotation updates are currently allowed.
+func (w *updateWindow) open() bool {
+	if w == nil {
+		return true
+	}
+	t := w.now().UTC()
+	for _, wr := range w.ranges {
+		if wr.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// setDeferred records healthy as held back by the update window; nil clears it.
+func (r *Runner) setDeferred(healthy []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deferred = slices.Clone(healthy)
+}
+
+func (w *updateWindow) String() string {
+	if w == nil {
+		return ""
+	}
+	return w.spec
+}
@@ -0,0 +1,125 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewUpdateWindow(t *testing.T) {
+	for _, spec := range []string{"", "09:00-17:00", "Mon-Fri 09:00-17:00", "sat 22:00-02:00, Sun 00:00-24:00"} {
+		if _, err := newUpdateWindow(spec); err != nil {
+			t.Errorf("newUpdateWindow(%q) unexpected error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"09:00", "9-17", "Mon-Fri", "Funday 09:00-17:00", "Mon-Xyz 09:00-17:00", "09:00-09:00", "09:00-25:00"} {
+		if _, err := newUpdateWindow(spec); err == nil {
+			t.Errorf("newUpdateWindow(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestUpdateWindow_Open(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{spec: "09:00-17:00", at: at(1, 9, 0), want: true},
+		{spec: "09:00-17:00", at: at(1, 17, 0), want: false},
+		{spec: "Mon-Fri 09:00-17:00", at: at(5, 12, 0), want: true},
+		{spec: "Mon-Fri 09:00-17:00", at: at(6, 12, 0), want: false},
+		{spec: "Fri-Mon 09:00-17:00", at: at(7, 12, 0), want: true},
+		{spec: "Fri-Mon 09:00-17:00", at: at(3, 12, 0), want: false},
+		{spec: "Sat 22:00-02:00", at: at(6, 23, 30), want: true},
+		{spec: "Sat 22:00-02:00", at: at(7, 1, 30), want: true},
+		{spec: "Sat 22:00-02:00", at: at(6, 1, 30), want: false},
+		{spec: "Tue 02:00-03:00,Thu 02:00-03:00", at: at(4, 2, 15), want: true},
+		{spec: "09:00-17:00", at: time.Date(2024, 1, 1, 10, 0, 0, 0, time.FixedZone("UTC-8", -8*3600)), want: false},
+	}
+	for _, tt := range tests {
+		w, err := newUpdateWindow(tt.spec)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w.now = func() time.Time { return tt.at }
+		if got := w.open(); got != tt.want {
+			t.Errorf("%q at %s: open() = %v, want %v", tt.spec, tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+	if !(*updateWindow)(nil).open() {
+		t.Error("Expected a nil window to be open")
+	}
+}
+
+func TestRunner_Tick_UpdateWindow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k8s := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newIngress("web", map[string]string{
+			"kubernetes.io/ingress.class": "public-nginx",
+			"new.example.com/target":      "10.0.0.9",
+		}),
+	).Build()
+	window, err := newUpdateWindow("Mon-Fri 09:00-17:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC)
+	window.now = func() time.Time { return now }
+	runner := &Runner{
+		k8s:                       k8s,
+		ingressClassAnnotationKey: "kubernetes.io/ingress.class",
+		ingressClasses:            []string{"public-nginx"},
+		annotationKey:             "new.example.com/target",
+		ips:                       []string{"10.0.0.1", "10.0.0.2"},
+		httpClient:                newRoutedHTTPClient(server),
+		urlScheme:                 "http",
+		httpPath:                  "/",
+		timeout:                   time.Second,
+		updateWindow:              window,
+	}
+	annotation := func() string {
+		t.Helper()
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		return got.Annotations["new.example.com/target"]
+	}
+
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := annotation(); got != "10.0.0.9" {
+		t.Errorf("Expected the patch to be deferred outside the window, got %q", got)
+	}
+	if got := strings.Join(runner.Status().Deferred, ","); got != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected the deferred healthy set in the status, got %q", got)
+	}
+
+	now = now.Add(time.Hour)
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := annotation(); got != "10.0.0.1,10.0.0.2" {
+		t.Errorf("Expected the patch to be applied inside the window, got %q", got)
+	}
+	if st := runner.Status(); st.Deferred != nil {
+		t.Errorf("Expected nothing deferred inside the window, got %v", st.Deferred)
+	}
+}
@@ -0,0 +1,77 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultWebhookTimeout = 5 * time.Second
+
+	webhookAttempts   = 3
+	webhookRetryDelay = 500 * time.Millisecond
+)
+
+// WebhookPayload is POSTed to the webhook URL when the healthy set changes.
+type WebhookPayload struct {
+	Old       []string  `json:"old"`
+	New       []string  `json:"new"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type webhookNotifier struct {
+	url        string
+	httpClient *http.Client
+	retryDelay time.Duration
+}
+
+func newWebhookNotifier(url string, timeout time.Duration) *webhookNotifier {
+	if url == "" {
+		return nil
+	}
+	return &webhookNotifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+		retryDelay: webhookRetryDelay,
+	}
+}
+
+// send POSTs payload, retrying a bounded number of times on errors and non-2xx responses.
+func (w *webhookNotifier) send(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.retryDelay):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := w.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
@@ -0,0 +1,124 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner_Tick_WebhookOnTransition(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer target.Close()
+
+	payloads := make(chan WebhookPayload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %q", ct)
+		}
+		var p WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		payloads <- p
+	}))
+	defer receiver.Close()
+
+	runner := &Runner{
+		ips:        []string{"10.0.0.1"},
+		httpClient: newRoutedHTTPClient(target),
+		urlScheme:  "http",
+		httpPath:   "/",
+		timeout:    time.Second,
+		webhook:    newWebhookNotifier(receiver.URL, time.Second),
+	}
+	ctx := context.Background()
+
+	expectPayload := func(old, new []string) {
+		t.Helper()
+		select {
+		case p := <-payloads:
+			if !slices.Equal(p.Old, old) || !slices.Equal(p.New, new) {
+				t.Errorf("Expected transition %v -> %v, got %v -> %v", old, new, p.Old, p.New)
+			}
+			if p.Timestamp.IsZero() {
+				t.Errorf("Expected timestamp to be set")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected webhook call for transition %v -> %v", old, new)
+		}
+	}
+	expectNoPayload := func() {
+		t.Helper()
+		select {
+		case p := <-payloads:
+			t.Errorf("Expected no webhook call, got %+v", p)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	_ = runner.tick(ctx)
+	expectPayload(nil, []string{"10.0.0.1"})
+
+	_ = runner.tick(ctx)
+	expectNoPayload()
+
+	status.Store(http.StatusServiceUnavailable)
+	_ = runner.tick(ctx)
+	expectPayload([]string{"10.0.0.1"}, []string{})
+
+	_ = runner.tick(ctx)
+	expectNoPayload()
+}
+
+func TestWebhookNotifier_RetriesOnFailure(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	w := newWebhookNotifier(receiver.URL, time.Second)
+	w.retryDelay = time.Millisecond
+
+	if err := w.send(context.Background(), WebhookPayload{New: []string{"10.0.0.1"}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestWebhookNotifier_GivesUp(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	w := newWebhookNotifier(receiver.URL, time.Second)
+	w.retryDelay = time.Millisecond
+
+	if err := w.send(context.Background(), WebhookPayload{}); err == nil {
+		t.Errorf("Expected error after exhausting retries, got none")
+	}
+	if got := calls.Load(); got != webhookAttempts {
+		t.Errorf("Expected %d attempts, got %d", webhookAttempts, got)
+	}
+}
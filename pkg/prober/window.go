@@ -0,0 +1,74 @@
+package prober
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// DefaultHealthWindow is the number of ticks the success ratio is computed over.
+const DefaultHealthWindow = 10
+
+// resultWindow is a fixed-size ring buffer of probe outcomes for one IP.
+type resultWindow struct {
+	results []bool
+	next    int
+	filled  int
+}
+
+func newResultWindow(size int) *resultWindow {
+	return &resultWindow{results: make([]bool, size)}
+}
+
+func (w *resultWindow) add(ok bool) {
+	w.results[w.next] = ok
+	w.next = (w.next + 1) % len(w.results)
+	if w.filled < len(w.results) {
+		w.filled++
+	}
+}
+
+// values returns the results currently held, oldest first.
+func (w *resultWindow) values() []bool {
+	out := make([]bool, 0, w.filled)
+	start := (w.next - w.filled + len(w.results)) % len(w.results)
+	for i := 0; i < w.filled; i++ {
+		out = append(out, w.results[(start+i)%len(w.results)])
+	}
+	return out
+}
+
+// ratio returns the share of successful results currently held in the window.
+func (w *resultWindow) ratio() float64 {
+	if w.filled == 0 {
+		return 0
+	}
+	ok := 0
+	for i := 0; i < w.filled; i++ {
+		if w.results[i] {
+			ok++
+		}
+	}
+	return float64(ok) / float64(w.filled)
+}
+
+// recordWindow adds this tick's outcome for every configured IP to its window.
+// Callers must hold r.mu.
+func (r *Runner) recordWindow(healthy []string) {
+	if r.healthWindow <= 0 {
+		return
+	}
+	ips := r.currentIPs()
+	if r.windows == nil {
+		r.windows = make(map[string]*resultWindow, len(ips))
+	}
+	isHealthy := make(map[string]bool, len(healthy))
+	for _, ip := range healthy {
+		isHealthy[ip] = true
+	}
+	for _, ip := range ips {
+		w, ok := r.windows[ip]
+		if !ok {
+			w = newResultWindow(r.healthWindow)
+			r.windows[ip] = w
+		}
+		w.add(isHealthy[ip])
+		probeSuccessRatio.With(prometheus.Labels{"ip": ip}).Set(w.ratio())
+	}
+}
@@ -0,0 +1,72 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResultWindow_Ratio(t *testing.T) {
+	w := newResultWindow(4)
+	if got := w.ratio(); got != 0 {
+		t.Errorf("Expected empty window ratio 0, got %v", got)
+	}
+
+	for _, ok := range []bool{true, false, true, true} {
+		w.add(ok)
+	}
+	if got := w.ratio(); got != 0.75 {
+		t.Errorf("Expected ratio 0.75, got %v", got)
+	}
+
+	// oldest results fall out once the window is full
+	w.add(false)
+	w.add(false)
+	if got := w.ratio(); got != 0.5 {
+		t.Errorf("Expected ratio 0.5 after wrapping, got %v", got)
+	}
+}
+
+func TestRunner_Tick_SuccessRatioOverWindow(t *testing.T) {
+	// 10.1.0.1 is always healthy, 10.1.0.2 follows the per-tick schedule below
+	schedule := []bool{true, false, false, true, false}
+	tickNo := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "10.1.0.2") && !schedule[tickNo] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runner := &Runner{
+		ips:          []string{"10.1.0.1", "10.1.0.2"},
+		httpClient:   newRoutedHTTPClient(server),
+		urlScheme:    "http",
+		httpPath:     "/",
+		timeout:      time.Second,
+		healthWindow: 4,
+	}
+
+	for tickNo = range schedule {
+		_ = runner.tick(context.Background())
+	}
+
+	// the window holds the last 4 ticks: false, false, true, false
+	expected := map[string]float64{"10.1.0.1": 1, "10.1.0.2": 0.25}
+	st := runner.Status()
+	for ip, want := range expected {
+		if got := st.SuccessRatio[ip]; got != want {
+			t.Errorf("Expected status success ratio %v for %s, got %v", want, ip, got)
+		}
+		if got := testutil.ToFloat64(probeSuccessRatio.WithLabelValues(ip)); got != want {
+			t.Errorf("Expected probe_success_ratio %v for %s, got %v", want, ip, got)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package prober
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ZoneLabel is the target label matched against an object's zone, e.g.
+// "10.0.0.1;zone=eu-west-1a".
+const ZoneLabel = "zone"
+
+// ZoneAnnotationKey on an Ingress (or Service) restricts the IPs written to
+// it to targets labelled with the same zone.
+const ZoneAnnotationKey = corev1.LabelTopologyZone
+
+// sameZone narrows healthy to the targets in obj's zone. Objects without a
+// zone, and zones no healthy target is in, get every healthy IP.
+func (r *Runner) sameZone(ctx context.Context, obj client.Object, healthy []string) []string {
+	zone := obj.GetAnnotations()[ZoneAnnotationKey]
+	if zone == "" {
+		return healthy
+	}
+	var matched []string
+	for _, ip := range healthy {
+		if r.targetLabel(ip, ZoneLabel) == zone {
+			matched = append(matched, ip)
+		}
+	}
+	if len(matched) == 0 {
+		log.FromContext(ctx).Info("no healthy IP in zone; writing all healthy IPs", "object", client.ObjectKeyFromObject(obj).String(), "zone", zone, "healthy", strings.Join(healthy, ","))
+		return healthy
+	}
+	return matched
+}
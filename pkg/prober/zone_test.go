@@ -0,0 +1,75 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunner_Tick_ZoneAffinity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// zone c has a single target, and it is down
+		if strings.HasPrefix(r.Host, "10.0.3.1") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ingresses := map[string]map[string]string{
+		"unzoned":     {},
+		"zone-a":      {ZoneAnnotationKey: "eu-west-1a"},
+		"zone-b":      {ZoneAnnotationKey: "eu-west-1b"},
+		"zone-c-down": {ZoneAnnotationKey: "eu-west-1c"},
+		"zone-none":   {ZoneAnnotationKey: "us-east-1a"},
+		"zone-a-all":  {ZoneAnnotationKey: "eu-west-1a", AllTargetsAnnotationKey: "true"},
+		"zone-a-own":  {ZoneAnnotationKey: "eu-west-1a", TargetsAnnotationKey: "10.0.1.1,10.0.9.9"},
+	}
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for name, ann := range ingresses {
+		ann["kubernetes.io/ingress.class"] = "public-nginx"
+		builder = builder.WithObjects(newIngress(name, ann))
+	}
+	k8s := builder.Build()
+
+	runner, err := New(Options{
+		Client:     k8s,
+		IPs:        []string{"10.0.1.1;zone=eu-west-1a", "10.0.2.1;zone=eu-west-1b", "10.0.1.2;zone=eu-west-1a", "10.0.3.1;zone=eu-west-1c", "10.0.4.1"},
+		HTTPClient: newRoutedHTTPClient(server),
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runner.tick(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	allHealthy := "10.0.1.1,10.0.2.1,10.0.1.2,10.0.4.1"
+	expected := map[string]string{
+		"unzoned":     allHealthy,
+		"zone-a":      "10.0.1.1,10.0.1.2",
+		"zone-b":      "10.0.2.1",
+		"zone-c-down": allHealthy,
+		"zone-none":   allHealthy,
+		"zone-a-all":  "10.0.1.1,10.0.2.1,10.0.1.2,10.0.3.1,10.0.4.1",
+		"zone-a-own":  "10.0.1.1",
+	}
+	for name, want := range expected {
+		got := &networkingv1.Ingress{}
+		if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, got); err != nil {
+			t.Fatalf("failed to get Ingress: %v", err)
+		}
+		if v := got.Annotations[DefaultAnnotationKey]; v != want {
+			t.Errorf("Ingress %s: expected %q, got %q", name, want, v)
+		}
+	}
+}